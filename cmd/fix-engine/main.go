@@ -15,7 +15,11 @@ import (
 
 	"github.com/go-chi/chi/v5"
 	"github.com/kasbench/globeco-fix-engine/internal/api"
+	"github.com/kasbench/globeco-fix-engine/internal/calendar"
+	"github.com/kasbench/globeco-fix-engine/internal/clock"
 	"github.com/kasbench/globeco-fix-engine/internal/config"
+	"github.com/kasbench/globeco-fix-engine/internal/domain"
+	"github.com/kasbench/globeco-fix-engine/internal/fix"
 	"github.com/kasbench/globeco-fix-engine/internal/kafka"
 	"github.com/kasbench/globeco-fix-engine/internal/metrics"
 	"github.com/kasbench/globeco-fix-engine/internal/middleware"
@@ -73,6 +77,10 @@ func main() {
 	if err != nil {
 		logger.Fatal("failed to create consumer metrics", zap.Error(err))
 	}
+	executionMetrics, err := metrics.NewExecutionMetrics(meter)
+	if err != nil {
+		logger.Fatal("failed to create execution metrics", zap.Error(err))
+	}
 
 	// Run database migrations
 	if err := config.RunMigrations(cfg.Postgres); err != nil {
@@ -95,13 +103,23 @@ func main() {
 		logger.Fatal("failed to ensure fills topic exists", zap.Error(err))
 	}
 	ordersConsumer := kafka.NewOrdersConsumer(cfg.Kafka, cfg.Kafka.ConsumerGroup, logger)
+	cancelsConsumer := kafka.NewCancelsConsumer(cfg.Kafka, cfg.Kafka.ConsumerGroup, logger)
+	amendsConsumer := kafka.NewAmendsConsumer(cfg.Kafka, cfg.Kafka.ConsumerGroup, logger)
 	fillsProducer := kafka.NewFillsProducer(cfg.Kafka, logger)
+	rejectsProducer := kafka.NewRejectsProducer(cfg.Kafka, logger)
+	dlq := kafka.NewDeadLetterQueue(cfg.Kafka, cfg.Kafka.ConsumerGroup, logger)
 	defer ordersConsumer.Close()
+	defer cancelsConsumer.Close()
+	defer amendsConsumer.Close()
 	defer fillsProducer.Close()
+	defer rejectsProducer.Close()
+	defer dlq.Close()
 	logger.Info("Kafka consumer and producer initialized successfully",
 		zap.Strings("brokers", cfg.Kafka.Brokers),
 		zap.String("orders_topic", cfg.Kafka.OrdersTopic),
 		zap.String("fills_topic", cfg.Kafka.FillsTopic),
+		zap.String("cancels_topic", cfg.Kafka.CancelsTopic),
+		zap.String("amends_topic", cfg.Kafka.AmendsTopic),
 		zap.String("consumer_group", cfg.Kafka.ConsumerGroup),
 	)
 
@@ -125,7 +143,10 @@ func main() {
 		repo,
 		db,
 		ordersConsumer,
+		cancelsConsumer,
+		amendsConsumer,
 		fillsProducer,
+		rejectsProducer,
 		securityClient,
 		pricingClient,
 		logger,
@@ -133,19 +154,112 @@ func main() {
 		kafkaReady,
 	)
 
-	// Start order intake and fill processing loops in background goroutines
+	sessionClose, err := service.NewSessionClose(cfg.Session)
+	if err != nil {
+		logger.Fatal("invalid trading session configuration", zap.Error(err))
+	}
+	execService.SessionClose = sessionClose
+	fillModel, err := service.NewFillModel(cfg.Fill)
+	if err != nil {
+		logger.Fatal("invalid fill model configuration", zap.Error(err))
+	}
+	execService.FillModel = fillModel
+	execService.FillSeed = cfg.Fill.Seed
+	if cfg.Price.Simulate {
+		priceSimulator, err := service.NewPriceSimulator(cfg.Price, cfg.Fill.Seed)
+		if err != nil {
+			logger.Fatal("invalid price simulation configuration", zap.Error(err))
+		}
+		execService.PriceSimulator = priceSimulator
+	}
+	slippage, err := service.NewSlippageModel(cfg.Slippage)
+	if err != nil {
+		logger.Fatal("invalid slippage configuration", zap.Error(err))
+	}
+	execService.Slippage = slippage
+	var tradingCalendar *calendar.Calendar
+	if cfg.Session.CalendarFile != "" {
+		tradingCalendar, err = calendar.Load(cfg.Session)
+		if err != nil {
+			logger.Fatal("invalid trading calendar", zap.Error(err))
+		}
+		execService.Calendar = tradingCalendar
+	}
+	simClock, err := clock.New(cfg.Clock)
+	if err != nil {
+		logger.Fatal("invalid clock configuration", zap.Error(err))
+	}
+	execService.Clock = simClock
+	execService.ExecutionMetrics = executionMetrics
+	avgPriceRounding, err := domain.NewRounding(cfg.AvgPrice.Places, cfg.AvgPrice.Rounding)
+	if err != nil {
+		logger.Fatal("invalid average price rounding", zap.Error(err))
+	}
+	execService.AveragePriceRounding = &avgPriceRounding
+	venues, err := service.NewVenues(cfg.Venues, tradingCalendar)
+	if err != nil {
+		logger.Fatal("invalid venue configuration", zap.Error(err))
+	}
+	execService.Venues = venues
+	execService.DLQ = dlq
+
 	var wg sync.WaitGroup
+
+	// Start the FIX acceptor alongside the HTTP server, before the loops that report to its sessions
+	fixCtx, fixCancel := context.WithCancel(ctx)
+	if cfg.FIX.Enabled {
+		acceptor := fix.NewAcceptor(
+			fix.AcceptorConfig{
+				Port:         cfg.FIX.Port,
+				SenderCompID: cfg.FIX.SenderCompID,
+				LogonTimeout: time.Duration(cfg.FIX.LogonTimeout) * time.Second,
+			},
+			service.NewFIXOrderHandler(execService),
+			repository.NewFIXSessionStoreFactory(db),
+			logger,
+		)
+		if err := acceptor.Listen(); err != nil {
+			logger.Fatal("failed to start FIX acceptor", zap.Error(err))
+		}
+		execService.FIXSender = acceptor
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := acceptor.Serve(fixCtx); err != nil {
+				logger.Error("FIX acceptor exited", zap.Error(err))
+			}
+		}()
+	}
+
+	// Start order intake, cancel, amend and fill processing loops, the expiry sweeper and the outbox relay in background goroutines
 	orderIntakeCtx, orderIntakeCancel := context.WithCancel(ctx)
 	fillProcessingCtx, fillProcessingCancel := context.WithCancel(ctx)
-	wg.Add(2)
+	wg.Add(6)
 	go func() {
 		defer wg.Done()
 		execService.StartOrderIntakeLoop(orderIntakeCtx)
 	}()
+	go func() {
+		defer wg.Done()
+		execService.StartCancelLoop(orderIntakeCtx)
+	}()
+	go func() {
+		defer wg.Done()
+		execService.StartAmendLoop(orderIntakeCtx)
+	}()
 	go func() {
 		defer wg.Done()
 		execService.StartFillProcessingLoop(fillProcessingCtx)
 	}()
+	go func() {
+		defer wg.Done()
+		execService.StartExpirySweeper(fillProcessingCtx, time.Duration(cfg.Session.ExpirySweepInterval)*time.Second)
+	}()
+	go func() {
+		defer wg.Done()
+		execService.StartOutboxRelay(fillProcessingCtx, time.Duration(cfg.Outbox.RelayInterval)*time.Millisecond, cfg.Outbox.BatchSize,
+			time.Duration(cfg.Outbox.Retention)*time.Second)
+	}()
 
 	// Set up chi router
 	r := chi.NewRouter()
@@ -170,8 +284,9 @@ func main() {
 	r.Use(middleware.LoggingMiddleware(logger))
 
 	// Register API routes
-	execAPI := api.NewExecutionAPI(repo)
+	execAPI := api.NewExecutionAPI(repo, execService, avgPriceRounding)
 	execAPI.RegisterRoutes(r)
+	api.NewAdminAPI(dlq).RegisterRoutes(r)
 
 	// Serve OpenAPI spec
 	r.Get("/openapi.json", func(w http.ResponseWriter, r *http.Request) {
@@ -263,9 +378,10 @@ func main() {
 	if err := httpServer.Shutdown(shutdownCtx); err != nil {
 		logger.Error("HTTP server shutdown error", zap.Error(err))
 	}
-	// Stop order intake and fill processing loops and wait for them to finish
+	// Stop order intake, fill processing and FIX sessions and wait for them to finish
 	orderIntakeCancel()
 	fillProcessingCancel()
+	fixCancel()
 	wg.Wait()
 	logger.Info("Shutdown complete")
 }
@@ -0,0 +1,46 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// DLQRedriver moves dead-lettered messages back onto the topics they came from; *kafka.DeadLetterQueue implements it.
+type DLQRedriver interface {
+	Redrive(ctx context.Context, max int) (int, error)
+}
+
+// AdminAPI exposes operational endpoints.
+type AdminAPI struct {
+	DLQ DLQRedriver
+}
+
+func NewAdminAPI(dlq DLQRedriver) *AdminAPI {
+	return &AdminAPI{DLQ: dlq}
+}
+
+// RedriveDLQ re-drives dead-lettered messages; the optional max query parameter limits how many.
+func (h *AdminAPI) RedriveDLQ(w http.ResponseWriter, r *http.Request) {
+	max := 0
+	if s := r.URL.Query().Get("max"); s != "" {
+		var err error
+		max, err = strconv.Atoi(s)
+		if err != nil || max < 0 {
+			writeError(w, http.StatusBadRequest, "invalid max")
+			return
+		}
+	}
+	moved, err := h.DLQ.Redrive(r.Context(), max)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]any{"error": "failed to re-drive dead-lettered messages", "redriven": moved})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]int{"redriven": moved})
+}
+
+func (h *AdminAPI) RegisterRoutes(r chi.Router) {
+	r.Post("/api/v1/admin/dlq/redrive", h.RedriveDLQ)
+}
@@ -0,0 +1,51 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+type mockRedriver struct {
+	max   int
+	moved int
+	err   error
+}
+
+func (m *mockRedriver) Redrive(ctx context.Context, max int) (int, error) {
+	m.max = max
+	return m.moved, m.err
+}
+
+func TestRedriveDLQ(t *testing.T) {
+	dlq := &mockRedriver{moved: 3}
+	r := chi.NewRouter()
+	NewAdminAPI(dlq).RegisterRoutes(r)
+
+	req := httptest.NewRequest("POST", "/api/v1/admin/dlq/redrive?max=10", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, 10, dlq.max)
+	var body map[string]int
+	assert.NoError(t, json.NewDecoder(w.Body).Decode(&body))
+	assert.Equal(t, 3, body["redriven"])
+
+	req = httptest.NewRequest("POST", "/api/v1/admin/dlq/redrive?max=abc", nil)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	dlq.err = errors.New("broker unavailable")
+	req = httptest.NewRequest("POST", "/api/v1/admin/dlq/redrive", nil)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Equal(t, 0, dlq.max)
+}
@@ -1,21 +1,33 @@
 package api
 
 import (
+	"context"
+	"database/sql"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/kasbench/globeco-fix-engine/internal/domain"
 	"github.com/kasbench/globeco-fix-engine/internal/repository"
+	"github.com/kasbench/globeco-fix-engine/internal/service"
 )
 
+// ExecutionAmender applies amendments to open executions; *service.ExecutionService implements it.
+type ExecutionAmender interface {
+	AmendExecutionByID(ctx context.Context, id int, amend *domain.AmendDTO) (*repository.Execution, error)
+}
+
 type ExecutionAPI struct {
-	Repo repository.ExecutionRepository
+	Repo    repository.ExecutionRepository
+	Amender ExecutionAmender
+	// Rounding rounds the average prices returned, as on the fills topic.
+	Rounding domain.Rounding
 }
 
-func NewExecutionAPI(repo repository.ExecutionRepository) *ExecutionAPI {
-	return &ExecutionAPI{Repo: repo}
+func NewExecutionAPI(repo repository.ExecutionRepository, amender ExecutionAmender, rounding domain.Rounding) *ExecutionAPI {
+	return &ExecutionAPI{Repo: repo, Amender: amender, Rounding: rounding}
 }
 
 func (h *ExecutionAPI) ListExecutions(w http.ResponseWriter, r *http.Request) {
@@ -26,7 +38,7 @@ func (h *ExecutionAPI) ListExecutions(w http.ResponseWriter, r *http.Request) {
 	}
 	var dtos []*domain.ExecutionDTO
 	for _, exec := range execs {
-		dtos = append(dtos, domain.MapExecutionToDTO(exec))
+		dtos = append(dtos, domain.MapExecutionToDTO(exec, h.Rounding))
 	}
 	writeJSON(w, http.StatusOK, dtos)
 }
@@ -43,14 +55,68 @@ func (h *ExecutionAPI) GetExecutionByID(w http.ResponseWriter, r *http.Request)
 		writeError(w, http.StatusNotFound, "execution not found")
 		return
 	}
-	dto := domain.MapExecutionToDTO(exec)
+	dto := domain.MapExecutionToDTO(exec, h.Rounding)
 	writeJSON(w, http.StatusOK, dto)
 }
 
+// AmendExecution amends the quantity and/or limit price of an open execution.
+func (h *ExecutionAPI) AmendExecution(w http.ResponseWriter, r *http.Request) {
+	idStr := chi.URLParam(r, "id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	var amend domain.AmendDTO
+	if err := json.NewDecoder(r.Body).Decode(&amend); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	amended, err := h.Amender.AmendExecutionByID(r.Context(), id, &amend)
+	switch {
+	case err == nil:
+		writeJSON(w, http.StatusOK, domain.MapExecutionToDTO(amended, h.Rounding))
+	case errors.Is(err, service.ErrInvalidAmend):
+		writeError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, service.ErrExecutionClosed), errors.Is(err, domain.ErrInvalidTransition), errors.Is(err, repository.ErrVersionConflict):
+		writeError(w, http.StatusConflict, err.Error())
+	case errors.Is(err, sql.ErrNoRows):
+		writeError(w, http.StatusNotFound, "execution not found")
+	default:
+		writeError(w, http.StatusInternalServerError, "failed to amend execution")
+	}
+}
+
+// ListFills returns the individual fills of an execution in sequence.
+func (h *ExecutionAPI) ListFills(w http.ResponseWriter, r *http.Request) {
+	idStr := chi.URLParam(r, "id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+	if _, err := h.Repo.GetByID(r.Context(), id); err != nil {
+		writeError(w, http.StatusNotFound, "execution not found")
+		return
+	}
+	fills, err := h.Repo.ListFills(r.Context(), id)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to list fills")
+		return
+	}
+	dtos := make([]*domain.FillDTO, 0, len(fills))
+	for _, fill := range fills {
+		dtos = append(dtos, domain.MapFillToDTO(fill))
+	}
+	writeJSON(w, http.StatusOK, dtos)
+}
+
 func (h *ExecutionAPI) RegisterRoutes(r chi.Router) {
 	r.Get("/api/v1/executions", h.ListExecutions)
 	r.Route("/api/v1/execution", func(r chi.Router) {
 		r.Get("/{id}", h.GetExecutionByID)
+		r.Post("/{id}/amend", h.AmendExecution)
+		r.Get("/{id}/fills", h.ListFills)
 	})
 }
 
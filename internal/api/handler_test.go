@@ -2,20 +2,29 @@ package api
 
 import (
 	"context"
+	"database/sql"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/kasbench/globeco-fix-engine/internal/domain"
 	"github.com/kasbench/globeco-fix-engine/internal/repository"
+	"github.com/kasbench/globeco-fix-engine/internal/service"
+	"github.com/shopspring/decimal"
 	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
 )
 
+var testRounding = domain.Rounding{Places: 4, Mode: domain.RoundHalfEven}
+
 type mockRepo struct {
 	execs []*repository.Execution
+	fills []*repository.Fill
 }
 
 func (m *mockRepo) Create(ctx context.Context, exec *repository.Execution) error { return nil }
@@ -28,10 +37,47 @@ func (m *mockRepo) GetByID(ctx context.Context, id int) (*repository.Execution,
 	return nil, http.ErrNoLocation
 }
 func (m *mockRepo) List(ctx context.Context) ([]*repository.Execution, error) { return m.execs, nil }
-func (m *mockRepo) PollNextForFill(ctx context.Context) (*repository.Execution, error) {
+func (m *mockRepo) PollNextForFill(ctx context.Context, now time.Time) (*repository.Execution, error) {
+	return nil, nil
+}
+func (m *mockRepo) GetByExecutionServiceID(ctx context.Context, executionServiceID int) (*repository.Execution, error) {
+	return nil, nil
+}
+func (m *mockRepo) GetByClOrdID(ctx context.Context, fixSessionID, clOrdID string) (*repository.Execution, error) {
+	return nil, nil
+}
+func (m *mockRepo) PollDueForExpiry(ctx context.Context, now time.Time) ([]*repository.Execution, error) {
 	return nil, nil
 }
 func (m *mockRepo) Update(ctx context.Context, exec *repository.Execution) error { return nil }
+func (m *mockRepo) CreateFill(ctx context.Context, fill *repository.Fill) error  { return nil }
+func (m *mockRepo) ListFills(ctx context.Context, executionID int) ([]*repository.Fill, error) {
+	var fills []*repository.Fill
+	for _, f := range m.fills {
+		if f.ExecutionID == executionID {
+			fills = append(fills, f)
+		}
+	}
+	return fills, nil
+}
+func (m *mockRepo) EnqueueOutbox(ctx context.Context, msg *repository.OutboxMessage) error {
+	return nil
+}
+func (m *mockRepo) PendingOutbox(ctx context.Context, limit int) ([]*repository.OutboxMessage, error) {
+	return nil, nil
+}
+func (m *mockRepo) PendingFIXOutbox(ctx context.Context, fixSessionID string, limit int) ([]*repository.OutboxMessage, error) {
+	return nil, nil
+}
+func (m *mockRepo) MarkOutboxSent(ctx context.Context, ids []int64, sent time.Time) error {
+	return nil
+}
+func (m *mockRepo) PruneOutbox(ctx context.Context, before time.Time) (int64, error) {
+	return 0, nil
+}
+func (m *mockRepo) WithinTx(ctx context.Context, fn func(ctx context.Context, repo repository.ExecutionRepository) error) error {
+	return fn(ctx, m)
+}
 
 func TestListExecutions(t *testing.T) {
 	repo := &mockRepo{
@@ -40,7 +86,7 @@ func TestListExecutions(t *testing.T) {
 			{ID: 2, Ticker: "GOOG", ExecutionStatus: "FULL"},
 		},
 	}
-	h := NewExecutionAPI(repo)
+	h := NewExecutionAPI(repo, nil, testRounding)
 	r := chi.NewRouter()
 	h.RegisterRoutes(r)
 
@@ -63,7 +109,7 @@ func TestGetExecutionByID(t *testing.T) {
 			{ID: 1, Ticker: "AAPL", ExecutionStatus: "WORK"},
 		},
 	}
-	h := NewExecutionAPI(repo)
+	h := NewExecutionAPI(repo, nil, testRounding)
 	r := chi.NewRouter()
 	h.RegisterRoutes(r)
 
@@ -84,3 +130,130 @@ func TestGetExecutionByID(t *testing.T) {
 	assert.Equal(t, http.StatusNotFound, w.Code)
 	assert.True(t, strings.Contains(w.Body.String(), "execution not found"))
 }
+
+type mockAmender struct {
+	id    int
+	amend *domain.AmendDTO
+	err   error
+}
+
+func (m *mockAmender) AmendExecutionByID(ctx context.Context, id int, amend *domain.AmendDTO) (*repository.Execution, error) {
+	m.id = id
+	m.amend = amend
+	if m.err != nil {
+		return nil, m.err
+	}
+	return &repository.Execution{ID: id, ExecutionServiceID: 55, QuantityOrdered: amend.QuantityOrdered.Decimal, Version: 2}, nil
+}
+
+func TestAmendExecution(t *testing.T) {
+	repo := &mockRepo{
+		execs: []*repository.Execution{
+			{ID: 1, ExecutionServiceID: 55, Ticker: "AAPL", ExecutionStatus: "WORK"},
+		},
+	}
+	amender := &mockAmender{}
+	h := NewExecutionAPI(repo, amender, testRounding)
+	r := chi.NewRouter()
+	h.RegisterRoutes(r)
+
+	req := httptest.NewRequest("POST", "/api/v1/execution/1/amend", strings.NewReader(`{"quantity": 250}`))
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, 1, amender.id)
+	var dto domain.ExecutionDTO
+	assert.NoError(t, json.NewDecoder(w.Body).Decode(&dto))
+	assert.Equal(t, "250", dto.QuantityOrdered.String())
+	assert.Equal(t, 2, dto.Version)
+
+	// Amendments refused by the service map onto client errors
+	amender.err = fmt.Errorf("%w: quantity 10 is below quantity filled 20", service.ErrInvalidAmend)
+	req = httptest.NewRequest("POST", "/api/v1/execution/1/amend", strings.NewReader(`{"quantity": 10}`))
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	amender.err = service.ErrExecutionClosed
+	req = httptest.NewRequest("POST", "/api/v1/execution/1/amend", strings.NewReader(`{"quantity": 10}`))
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	amender.err = &domain.InvalidTransitionError{From: domain.StatusFilled, To: domain.StatusPendingReplace}
+	req = httptest.NewRequest("POST", "/api/v1/execution/1/amend", strings.NewReader(`{"quantity": 10}`))
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	amender.err = &repository.VersionConflictError{ID: 1, Version: 2}
+	req = httptest.NewRequest("POST", "/api/v1/execution/1/amend", strings.NewReader(`{"quantity": 10}`))
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	amender.err = sql.ErrNoRows
+	req = httptest.NewRequest("POST", "/api/v1/execution/999/amend", strings.NewReader(`{"quantity": 10}`))
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestAmendExecution_FilledExecutionConflicts(t *testing.T) {
+	repo := &mockRepo{
+		execs: []*repository.Execution{
+			{ID: 1, ExecutionServiceID: 55, Ticker: "AAPL", ExecutionStatus: string(domain.StatusFilled), QuantityOrdered: decimal.NewFromInt(100), QuantityFilled: decimal.NewFromInt(100), Version: 3},
+		},
+	}
+	h := NewExecutionAPI(repo, &service.ExecutionService{Repo: repo, Logger: zap.NewNop()}, testRounding)
+	r := chi.NewRouter()
+	h.RegisterRoutes(r)
+
+	req := httptest.NewRequest("POST", "/api/v1/execution/1/amend", strings.NewReader(`{"quantity": 250}`))
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Equal(t, "100", repo.execs[0].QuantityOrdered.String())
+}
+
+func TestListFills(t *testing.T) {
+	filled := time.Unix(1748345329, 0).UTC()
+	repo := &mockRepo{
+		execs: []*repository.Execution{{ID: 1, Ticker: "AAPL"}, {ID: 2, Ticker: "GOOG"}},
+		fills: []*repository.Fill{
+			{ID: 10, ExecutionID: 1, FillSequence: 1, Quantity: decimal.NewFromInt(40), Price: decimal.RequireFromString("101.5"), FillTimestamp: filled, Venue: "NYSE"},
+			{ID: 11, ExecutionID: 1, FillSequence: 2, Quantity: decimal.NewFromInt(60), Price: decimal.NewFromInt(102), Fee: decimal.RequireFromString("0.18"), FillTimestamp: filled, Venue: "NYSE"},
+		},
+	}
+	h := NewExecutionAPI(repo, nil, testRounding)
+	r := chi.NewRouter()
+	h.RegisterRoutes(r)
+
+	req := httptest.NewRequest("GET", "/api/v1/execution/1/fills", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var fills []domain.FillDTO
+	assert.NoError(t, json.NewDecoder(w.Body).Decode(&fills))
+	assert.Len(t, fills, 2)
+	assert.Equal(t, 2, fills[1].FillSequence)
+	assert.Equal(t, "60", fills[1].Quantity.String())
+	assert.Equal(t, "102", fills[1].Price.String())
+	assert.Equal(t, "0.18", fills[1].Fee.String())
+	assert.Equal(t, "NYSE", fills[1].Venue)
+	assert.Equal(t, domain.EpochTime(1748345329), fills[1].FillTimestamp)
+
+	// An execution without fills has an empty history
+	req = httptest.NewRequest("GET", "/api/v1/execution/2/fills", nil)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, "[]", w.Body.String())
+
+	req = httptest.NewRequest("GET", "/api/v1/execution/999/fills", nil)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
@@ -0,0 +1,258 @@
+// Package calendar models exchange trading calendars: regular sessions,
+// weekends, holidays and half days in each exchange's time zone.
+package calendar
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/kasbench/globeco-fix-engine/internal/config"
+	"github.com/spf13/viper"
+)
+
+// maxCalendarDays bounds the search for the next session.
+const maxCalendarDays = 400
+
+// File is the content of a calendar file.
+type File struct {
+	Exchanges []ExchangeFile
+}
+
+// ExchangeFile is one exchange in a calendar file.
+type ExchangeFile struct {
+	Name     string
+	TimeZone string    // IANA time zone name
+	Open     string    // Regular session open as "HH:MM"
+	Close    string    // Regular session close as "HH:MM"
+	Weekend  []string  // Non-trading weekdays; Saturday and Sunday if omitted
+	Holidays []string  // Non-trading dates as "YYYY-MM-DD"
+	HalfDays []HalfDay // Dates with an early close
+}
+
+// HalfDay is a date on which the exchange closes early.
+type HalfDay struct {
+	Date  string // "YYYY-MM-DD"
+	Close string // "HH:MM"
+}
+
+// Calendar holds the exchanges of a calendar file.
+type Calendar struct {
+	exchanges map[string]*Exchange
+	def       *Exchange
+}
+
+// Exchange is the trading calendar of one exchange.
+type Exchange struct {
+	Name     string
+	location *time.Location
+	open     int // minutes after midnight
+	close    int
+	weekend  map[time.Weekday]bool
+	holidays map[string]bool
+	halfDays map[string]int // date -> close, minutes after midnight
+}
+
+// Load reads the calendar file configured by cfg.
+func Load(cfg config.TradingSessionConfig) (*Calendar, error) {
+	v := viper.New()
+	v.SetConfigFile(cfg.CalendarFile)
+	if err := v.ReadInConfig(); err != nil {
+		return nil, fmt.Errorf("reading calendar %s: %w", cfg.CalendarFile, err)
+	}
+	var file File
+	if err := v.Unmarshal(&file); err != nil {
+		return nil, fmt.Errorf("parsing calendar %s: %w", cfg.CalendarFile, err)
+	}
+	return New(file, cfg.Exchange)
+}
+
+// New creates a Calendar from file. defaultExchange names the exchange for
+// executions without one, the first exchange if empty.
+func New(file File, defaultExchange string) (*Calendar, error) {
+	if len(file.Exchanges) == 0 {
+		return nil, fmt.Errorf("calendar has no exchanges")
+	}
+	c := &Calendar{exchanges: make(map[string]*Exchange)}
+	for _, ef := range file.Exchanges {
+		ex, err := newExchange(ef)
+		if err != nil {
+			return nil, fmt.Errorf("exchange %q: %w", ef.Name, err)
+		}
+		if _, ok := c.exchanges[ex.Name]; ok {
+			return nil, fmt.Errorf("duplicate exchange %q", ex.Name)
+		}
+		c.exchanges[ex.Name] = ex
+		if c.def == nil {
+			c.def = ex
+		}
+	}
+	if defaultExchange != "" {
+		ex, ok := c.exchanges[defaultExchange]
+		if !ok {
+			return nil, fmt.Errorf("unknown default exchange %q", defaultExchange)
+		}
+		c.def = ex
+	}
+	return c, nil
+}
+
+func newExchange(ef ExchangeFile) (*Exchange, error) {
+	if ef.Name == "" {
+		return nil, fmt.Errorf("exchange without a name")
+	}
+	loc, err := time.LoadLocation(ef.TimeZone)
+	if err != nil {
+		return nil, fmt.Errorf("invalid time zone %q: %w", ef.TimeZone, err)
+	}
+	open, err := parseClock(ef.Open)
+	if err != nil {
+		return nil, fmt.Errorf("invalid open: %w", err)
+	}
+	close, err := parseClock(ef.Close)
+	if err != nil {
+		return nil, fmt.Errorf("invalid close: %w", err)
+	}
+	if close <= open {
+		return nil, fmt.Errorf("close %s is not after open %s", ef.Close, ef.Open)
+	}
+	ex := &Exchange{
+		Name:     ef.Name,
+		location: loc,
+		open:     open,
+		close:    close,
+		weekend:  map[time.Weekday]bool{time.Saturday: true, time.Sunday: true},
+		holidays: make(map[string]bool),
+		halfDays: make(map[string]int),
+	}
+	if ef.Weekend != nil {
+		ex.weekend = make(map[time.Weekday]bool)
+		for _, name := range ef.Weekend {
+			day, err := parseWeekday(name)
+			if err != nil {
+				return nil, err
+			}
+			ex.weekend[day] = true
+		}
+		if len(ex.weekend) == 7 {
+			return nil, fmt.Errorf("no trading weekdays")
+		}
+	}
+	for _, date := range ef.Holidays {
+		if _, err := time.Parse(time.DateOnly, date); err != nil {
+			return nil, fmt.Errorf("invalid holiday %q: %w", date, err)
+		}
+		ex.holidays[date] = true
+	}
+	for _, half := range ef.HalfDays {
+		if _, err := time.Parse(time.DateOnly, half.Date); err != nil {
+			return nil, fmt.Errorf("invalid half day %q: %w", half.Date, err)
+		}
+		close, err := parseClock(half.Close)
+		if err != nil {
+			return nil, fmt.Errorf("invalid close on half day %s: %w", half.Date, err)
+		}
+		if close <= open {
+			return nil, fmt.Errorf("half day %s closes before the open", half.Date)
+		}
+		ex.halfDays[half.Date] = close
+	}
+	return ex, nil
+}
+
+// parseClock parses "HH:MM" into minutes after midnight.
+func parseClock(s string) (int, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, err
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+func parseWeekday(name string) (time.Weekday, error) {
+	for day := time.Sunday; day <= time.Saturday; day++ {
+		if strings.EqualFold(name, day.String()) {
+			return day, nil
+		}
+	}
+	return 0, fmt.Errorf("invalid weekday %q", name)
+}
+
+// Default returns the exchange for executions without one.
+func (c *Calendar) Default() *Exchange {
+	return c.def
+}
+
+// Exchange returns the named exchange.
+func (c *Calendar) Exchange(name string) (*Exchange, bool) {
+	ex, ok := c.exchanges[name]
+	return ex, ok
+}
+
+// NextOpen returns t if the exchange is trading at t, and otherwise when it next opens.
+func (e *Exchange) NextOpen(t time.Time) time.Time {
+	local := t.In(e.location)
+	for days := 0; days < maxCalendarDays; days++ {
+		day := e.day(local, days)
+		open, close, ok := e.session(day)
+		if !ok {
+			continue
+		}
+		if local.Before(open) {
+			return open.UTC()
+		}
+		if local.Before(close) {
+			return t
+		}
+	}
+	return t // unreachable: every week has a trading day
+}
+
+// NextClose returns the end of the session in progress at t, or of the next session.
+func (e *Exchange) NextClose(t time.Time) time.Time {
+	local := t.In(e.location)
+	for days := 0; days < maxCalendarDays; days++ {
+		_, close, ok := e.session(e.day(local, days))
+		if ok && local.Before(close) {
+			return close.UTC()
+		}
+	}
+	return t // unreachable: every week has a trading day
+}
+
+// Open returns the open of the session on date (YYYY-MM-DD), or false if the
+// exchange does not trade that day.
+func (e *Exchange) Open(date string) (time.Time, bool) {
+	day, err := time.ParseInLocation(time.DateOnly, date, e.location)
+	if err != nil {
+		return time.Time{}, false
+	}
+	open, _, ok := e.session(day)
+	return open.UTC(), ok
+}
+
+// IsOpen reports whether the exchange is trading at t.
+func (e *Exchange) IsOpen(t time.Time) bool {
+	return e.NextOpen(t).Equal(t)
+}
+
+// day returns local midnight days after local's date.
+func (e *Exchange) day(local time.Time, days int) time.Time {
+	return time.Date(local.Year(), local.Month(), local.Day()+days, 0, 0, 0, 0, e.location)
+}
+
+// session returns the open and close on day, or false if it is not a trading day.
+func (e *Exchange) session(day time.Time) (time.Time, time.Time, bool) {
+	date := day.Format(time.DateOnly)
+	if e.weekend[day.Weekday()] || e.holidays[date] {
+		return time.Time{}, time.Time{}, false
+	}
+	close := e.close
+	if half, ok := e.halfDays[date]; ok {
+		close = half
+	}
+	at := func(minutes int) time.Time {
+		return time.Date(day.Year(), day.Month(), day.Day(), 0, minutes, 0, 0, e.location)
+	}
+	return at(e.open), at(close), true
+}
@@ -0,0 +1,138 @@
+package calendar
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/kasbench/globeco-fix-engine/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testFile = File{Exchanges: []ExchangeFile{
+	{
+		Name:     "XNYS",
+		TimeZone: "America/New_York",
+		Open:     "09:30",
+		Close:    "16:00",
+		Holidays: []string{"2026-11-26"},
+		HalfDays: []HalfDay{{Date: "2026-11-27", Close: "13:00"}},
+	},
+	{Name: "XLON", TimeZone: "Europe/London", Open: "08:00", Close: "16:30"},
+}}
+
+func TestExchangeNextOpen(t *testing.T) {
+	cal, err := New(testFile, "")
+	require.NoError(t, err)
+	ex := cal.Default()
+	assert.Equal(t, "XNYS", ex.Name)
+	ny, _ := time.LoadLocation("America/New_York")
+	at := func(day, hour, minute int) time.Time { return time.Date(2026, 11, day, hour, minute, 0, 0, ny) }
+
+	// Wednesday 25 November: before, during and after the session
+	assert.Equal(t, at(25, 9, 30).UTC(), ex.NextOpen(at(25, 8, 0)))
+	assert.Equal(t, at(25, 12, 0), ex.NextOpen(at(25, 12, 0)))
+	assert.True(t, ex.IsOpen(at(25, 12, 0)))
+	// Thanksgiving is a holiday, so the next open is the half day after
+	assert.Equal(t, at(27, 9, 30).UTC(), ex.NextOpen(at(25, 16, 0)))
+	// The half day closes at 13:00, then the weekend follows
+	assert.False(t, ex.IsOpen(at(27, 14, 0)))
+	assert.Equal(t, at(30, 9, 30).UTC(), ex.NextOpen(at(27, 14, 0)))
+}
+
+func TestExchangeOpen(t *testing.T) {
+	cal, err := New(testFile, "")
+	require.NoError(t, err)
+	ex := cal.Default()
+	ny, _ := time.LoadLocation("America/New_York")
+
+	open, ok := ex.Open("2026-11-25")
+	assert.True(t, ok)
+	assert.Equal(t, time.Date(2026, 11, 25, 9, 30, 0, 0, ny).UTC(), open)
+	_, ok = ex.Open("2026-11-26") // Thanksgiving
+	assert.False(t, ok)
+	_, ok = ex.Open("2026-11-28") // Saturday
+	assert.False(t, ok)
+	_, ok = ex.Open("not a date")
+	assert.False(t, ok)
+}
+
+func TestExchangeNextClose(t *testing.T) {
+	cal, err := New(testFile, "")
+	require.NoError(t, err)
+	ex := cal.Default()
+	ny, _ := time.LoadLocation("America/New_York")
+	at := func(day, hour, minute int) time.Time { return time.Date(2026, 11, day, hour, minute, 0, 0, ny) }
+
+	assert.Equal(t, at(25, 16, 0).UTC(), ex.NextClose(at(25, 8, 0)))
+	assert.Equal(t, at(25, 16, 0).UTC(), ex.NextClose(at(25, 12, 0)))
+	assert.Equal(t, at(27, 13, 0).UTC(), ex.NextClose(at(25, 16, 0)))
+}
+
+func TestCalendarDefaultExchange(t *testing.T) {
+	cal, err := New(testFile, "XLON")
+	require.NoError(t, err)
+	assert.Equal(t, "XLON", cal.Default().Name)
+	_, ok := cal.Exchange("XNYS")
+	assert.True(t, ok)
+
+	_, err = New(testFile, "XPAR")
+	assert.Error(t, err)
+}
+
+func TestNewRejectsInvalidCalendars(t *testing.T) {
+	valid := testFile.Exchanges[0]
+	for _, mutate := range []func(*ExchangeFile){
+		func(e *ExchangeFile) { e.Name = "" },
+		func(e *ExchangeFile) { e.TimeZone = "Nowhere/Special" },
+		func(e *ExchangeFile) { e.Close = "09:00" },
+		func(e *ExchangeFile) { e.Holidays = []string{"26/11/2026"} },
+		func(e *ExchangeFile) { e.HalfDays = []HalfDay{{Date: "2026-11-27", Close: "08:00"}} },
+		func(e *ExchangeFile) { e.Weekend = []string{"Caturday"} },
+		func(e *ExchangeFile) {
+			e.Weekend = []string{"Sunday", "Monday", "Tuesday", "Wednesday", "Thursday", "Friday", "Saturday"}
+		},
+	} {
+		ex := valid
+		mutate(&ex)
+		_, err := New(File{Exchanges: []ExchangeFile{ex}}, "")
+		assert.Error(t, err, "%+v", ex)
+	}
+	_, err := New(File{}, "")
+	assert.Error(t, err)
+}
+
+func TestLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "calendar.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(`
+Exchanges:
+  - Name: XNYS
+    TimeZone: America/New_York
+    Open: "09:30"
+    Close: "16:00"
+    Weekend: [Saturday, Sunday]
+    Holidays: ["2026-11-26"]
+    HalfDays:
+      - Date: "2026-11-27"
+        Close: "13:00"
+`), 0o600))
+
+	cal, err := Load(config.TradingSessionConfig{CalendarFile: path, Exchange: "XNYS"})
+	require.NoError(t, err)
+	ex, ok := cal.Exchange("XNYS")
+	require.True(t, ok)
+	assert.False(t, ex.IsOpen(time.Date(2026, 11, 26, 15, 0, 0, 0, time.UTC)))
+
+	_, err = Load(config.TradingSessionConfig{CalendarFile: filepath.Join(t.TempDir(), "missing.yaml")})
+	assert.Error(t, err)
+}
+
+func TestLoadSampleCalendar(t *testing.T) {
+	cal, err := Load(config.TradingSessionConfig{CalendarFile: "../../config/calendar.yaml"})
+	require.NoError(t, err)
+	assert.Equal(t, "XNYS", cal.Default().Name)
+	_, ok := cal.Exchange("XLON")
+	assert.True(t, ok)
+}
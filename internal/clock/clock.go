@@ -0,0 +1,102 @@
+// Package clock abstracts the current time, so that executions can be
+// timestamped and scheduled on a simulated clock: accelerated for benchmarks,
+// started in the past to replay a trading day, or fixed in tests.
+package clock
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/kasbench/globeco-fix-engine/internal/config"
+)
+
+// Clock tells the current time.
+type Clock interface {
+	Now() time.Time
+}
+
+// Real returns the wall clock.
+func Real() Clock {
+	return realClock{}
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+	return time.Now().UTC()
+}
+
+// Fixed is a clock that only moves when told to, for tests.
+type Fixed struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+// NewFixed returns a Fixed clock at t.
+func NewFixed(t time.Time) *Fixed {
+	return &Fixed{now: t.UTC()}
+}
+
+func (c *Fixed) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// Set moves the clock to t.
+func (c *Fixed) Set(t time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = t.UTC()
+}
+
+// Advance moves the clock forward by d.
+func (c *Fixed) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+// Accelerated is a clock that reads start when base reads origin, and from
+// then on runs speed times as fast as base.
+type Accelerated struct {
+	base   Clock
+	origin time.Time
+	start  time.Time
+	speed  float64
+}
+
+// NewAccelerated returns an Accelerated clock.
+func NewAccelerated(base Clock, origin, start time.Time, speed float64) (*Accelerated, error) {
+	if speed <= 0 {
+		return nil, fmt.Errorf("invalid clock acceleration %v", speed)
+	}
+	return &Accelerated{base: base, origin: origin, start: start.UTC(), speed: speed}, nil
+}
+
+func (c *Accelerated) Now() time.Time {
+	return c.start.Add(time.Duration(float64(c.base.Now().Sub(c.origin)) * c.speed))
+}
+
+// New returns the clock configured by cfg: the wall clock unless it is
+// accelerated or starts at another time.
+func New(cfg config.ClockConfig) (Clock, error) {
+	if cfg.Acceleration == 1 && cfg.Start == "" {
+		return Real(), nil
+	}
+	now := time.Now().UTC()
+	origin, start := now, now
+	var err error
+	if cfg.Origin != "" {
+		if origin, err = time.Parse(time.RFC3339, cfg.Origin); err != nil {
+			return nil, fmt.Errorf("invalid clock origin %q: %w", cfg.Origin, err)
+		}
+	}
+	if cfg.Start != "" {
+		if start, err = time.Parse(time.RFC3339, cfg.Start); err != nil {
+			return nil, fmt.Errorf("invalid clock start %q: %w", cfg.Start, err)
+		}
+	}
+	return NewAccelerated(Real(), origin, start, cfg.Acceleration)
+}
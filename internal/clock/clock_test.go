@@ -0,0 +1,58 @@
+package clock
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kasbench/globeco-fix-engine/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFixed(t *testing.T) {
+	start := time.Date(2026, 11, 25, 14, 30, 0, 0, time.UTC)
+	c := NewFixed(start)
+	assert.Equal(t, start, c.Now())
+	c.Advance(time.Minute)
+	assert.Equal(t, start.Add(time.Minute), c.Now())
+	c.Set(start)
+	assert.Equal(t, start, c.Now())
+}
+
+func TestAccelerated(t *testing.T) {
+	origin := time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC)
+	replay := time.Date(2026, 11, 25, 14, 30, 0, 0, time.UTC)
+	base := NewFixed(origin)
+	c, err := NewAccelerated(base, origin, replay, 60)
+	require.NoError(t, err)
+
+	assert.Equal(t, replay, c.Now())
+	base.Advance(time.Minute)
+	assert.Equal(t, replay.Add(time.Hour), c.Now())
+
+	_, err = NewAccelerated(base, origin, replay, 0)
+	assert.Error(t, err)
+}
+
+func TestNew(t *testing.T) {
+	c, err := New(config.ClockConfig{Acceleration: 1})
+	require.NoError(t, err)
+	assert.IsType(t, realClock{}, c)
+	assert.WithinDuration(t, time.Now(), c.Now(), time.Second)
+
+	// Replaying from a past start and origin
+	c, err = New(config.ClockConfig{Acceleration: 2, Start: "2026-11-25T14:30:00Z", Origin: "2026-10-17T12:00:00Z"})
+	require.NoError(t, err)
+	elapsed := time.Since(time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC))
+	expected := time.Date(2026, 11, 25, 14, 30, 0, 0, time.UTC).Add(2 * elapsed)
+	assert.WithinDuration(t, expected, c.Now(), 2*time.Second)
+
+	for _, cfg := range []config.ClockConfig{
+		{Acceleration: 0, Start: "2026-11-25T14:30:00Z"},
+		{Acceleration: 1, Start: "yesterday"},
+		{Acceleration: 60, Origin: "today"},
+	} {
+		_, err := New(cfg)
+		assert.Error(t, err, "%+v", cfg)
+	}
+}
@@ -16,12 +16,25 @@ type Config struct {
 	SecuritySvc ServiceConfig
 	PricingSvc  ServiceConfig
 	OTEL        OTELConfig
+	FIX         FIXConfig
+	Session     TradingSessionConfig
+	Clock       ClockConfig
+	Outbox      OutboxConfig
+	Fill        FillModelConfig
+	Price       PriceSimulationConfig
+	Slippage    SlippageConfig
+	Venues      []VenueConfig
+	AvgPrice    AveragePriceConfig
 }
 
 type KafkaConfig struct {
 	Brokers       []string
 	OrdersTopic   string
 	FillsTopic    string
+	CancelsTopic  string // Cancel requests referencing executionServiceId
+	AmendsTopic   string // Quantity/limit price amendments referencing executionServiceId
+	RejectsTopic  string // Rejected orders; empty publishes them to FillsTopic
+	DLQTopic      string // Order messages that failed permanently, for re-drive
 	ConsumerGroup string
 }
 
@@ -39,6 +52,88 @@ type ServiceConfig struct {
 	Port int
 }
 
+// FIXConfig configures the FIX session-layer acceptor.
+type FIXConfig struct {
+	Enabled      bool
+	Port         int
+	SenderCompID string
+	LogonTimeout int // Seconds to wait for Logon after a connection is accepted
+}
+
+// TradingSessionConfig configures the trading day used to expire DAY orders.
+type TradingSessionConfig struct {
+	CloseTime           string // Session close as "HH:MM" in TimeZone, if there is no calendar
+	TimeZone            string // IANA time zone name
+	ExpirySweepInterval int    // Seconds between expiry sweeps
+	CalendarFile        string // Exchange trading calendar; empty trades around the clock
+	Exchange            string // Calendar exchange for destinations without one; the first if empty
+}
+
+// ClockConfig configures the clock executions are timestamped and scheduled by.
+type ClockConfig struct {
+	Acceleration float64 // Simulated seconds per wall-clock second
+	Start        string  // RFC 3339 simulated time at Origin; Origin if empty
+	Origin       string  // RFC 3339 wall-clock time the simulation starts; startup if empty
+}
+
+// OutboxConfig configures the relay that publishes the outbox to the fills topic.
+type OutboxConfig struct {
+	RelayInterval int // Milliseconds between polls for pending messages
+	BatchSize     int // Maximum messages published per transaction
+	Retention     int // Seconds on the Clock sent messages are kept; 0 keeps them forever
+}
+
+// FillModelConfig selects and parameterises the fill simulation model.
+type FillModelConfig struct {
+	Model             string  // random, fixed-ratio, always-full, poisson or volume-participation
+	MinDelay          int     // Minimum seconds between fill attempts (all but poisson)
+	MaxDelay          int     // Maximum seconds between fill attempts (all but poisson)
+	FixedRatio        float64 // Fraction of the remainder filled per attempt (fixed-ratio)
+	MeanDelay         float64 // Mean seconds between fill attempts (poisson)
+	LotSize           float64 // Quantity filled per attempt (poisson)
+	ParticipationRate float64 // Fraction of market volume taken (volume-participation)
+	IntervalVolume    float64 // Market volume traded between attempts if pricing has no volume (volume-participation)
+	TradingDayMinutes int     // Length of the trading day the daily volume trades over (volume-participation)
+	Seed              int64   // Non-zero makes fills deterministic per execution
+}
+
+// PriceSimulationConfig configures the intraday price path simulated per ticker.
+type PriceSimulationConfig struct {
+	Simulate   bool    // Move prices intraday; false fills at the Pricing Service's price
+	Volatility float64 // Standard deviation of log returns per hour
+	Step       int     // Milliseconds per random walk step
+}
+
+// SlippageConfig configures the costs applied to fill prices, in basis points.
+type SlippageConfig struct {
+	Impact                 float64 // Adverse impact per 1% of the ticker's daily volume filled
+	MaxImpact              float64 // Cap on the impact of a single fill
+	Spread                 float64 // Maximum random spread cost
+	Improvement            float64 // Maximum price improvement for limit orders
+	ImprovementProbability float64 // Probability a limit order fill is improved instead of paying costs
+}
+
+// VenueConfig is the simulated behaviour of executions for one destination.
+type VenueConfig struct {
+	Name              string           // Destination the venue is for
+	Fill              *FillModelConfig // Fill model at the venue; the global Fill model if omitted
+	MinLatency        int              // Minimum milliseconds added to each fill attempt
+	MaxLatency        int              // Maximum milliseconds added to each fill attempt
+	RejectProbability float64          // Probability that an order routed to the venue is rejected
+	OpenTime          string           // Trading hours as "HH:MM" in TimeZone; around the clock if omitted
+	CloseTime         string
+	TimeZone          string  // IANA time zone name
+	Exchange          string  // Calendar exchange the venue trades on; Session.Exchange if empty
+	FeePerShare       float64 // Fee per share filled
+	FeeBps            float64 // Fee in basis points of the value filled
+}
+
+// AveragePriceConfig configures how reported average prices are rounded.
+type AveragePriceConfig struct {
+	Places   int32  // Decimal places
+	Rounding string // half-even (banker's), half-up or down (truncate)
+}
+
 type OTELConfig struct {
 	TraceEndpoint      string
 	MetricEndpoint     string
@@ -79,6 +174,10 @@ func LoadConfig() (*Config, error) {
 	viper.SetDefault("Kafka.Brokers", []string{"globeco-execution-service-kafka:9092"})
 	viper.SetDefault("Kafka.OrdersTopic", "orders")
 	viper.SetDefault("Kafka.FillsTopic", "fills")
+	viper.SetDefault("Kafka.CancelsTopic", "cancels")
+	viper.SetDefault("Kafka.AmendsTopic", "amends")
+	viper.SetDefault("Kafka.RejectsTopic", "")
+	viper.SetDefault("Kafka.DLQTopic", "orders_dlq")
 	viper.SetDefault("Kafka.ConsumerGroup", "fix_engine")
 	viper.SetDefault("Postgres.Host", "globeco-fix-engine-postgresql")
 	viper.SetDefault("Postgres.Port", 5432)
@@ -98,6 +197,41 @@ func LoadConfig() (*Config, error) {
 	viper.SetDefault("OTEL.ServiceVersion", "1.0.0")
 	viper.SetDefault("OTEL.ServiceNamespace", "globeco")
 	viper.SetDefault("OTEL.ResourceAttributes", "")
+	viper.SetDefault("FIX.Enabled", true)
+	viper.SetDefault("FIX.Port", 9878)
+	viper.SetDefault("FIX.SenderCompID", "GLOBECO")
+	viper.SetDefault("FIX.LogonTimeout", 10)
+	viper.SetDefault("Session.CloseTime", "16:00")
+	viper.SetDefault("Session.TimeZone", "America/New_York")
+	viper.SetDefault("Session.ExpirySweepInterval", 1)
+	viper.SetDefault("Session.CalendarFile", "")
+	viper.SetDefault("Session.Exchange", "")
+	viper.SetDefault("Clock.Acceleration", 1)
+	viper.SetDefault("Clock.Start", "")
+	viper.SetDefault("Clock.Origin", "")
+	viper.SetDefault("Outbox.RelayInterval", 200)
+	viper.SetDefault("Outbox.BatchSize", 100)
+	viper.SetDefault("Outbox.Retention", 86400)
+	viper.SetDefault("Fill.Model", "random")
+	viper.SetDefault("Fill.MinDelay", 5)
+	viper.SetDefault("Fill.MaxDelay", 120)
+	viper.SetDefault("Fill.FixedRatio", 0.25)
+	viper.SetDefault("Fill.MeanDelay", 30)
+	viper.SetDefault("Fill.LotSize", 100)
+	viper.SetDefault("Fill.ParticipationRate", 0.1)
+	viper.SetDefault("Fill.IntervalVolume", 10000)
+	viper.SetDefault("Fill.TradingDayMinutes", 390)
+	viper.SetDefault("Fill.Seed", 0)
+	viper.SetDefault("Price.Simulate", false)
+	viper.SetDefault("Price.Volatility", 0.01)
+	viper.SetDefault("Price.Step", 1000)
+	viper.SetDefault("Slippage.Impact", 0)
+	viper.SetDefault("Slippage.MaxImpact", 0)
+	viper.SetDefault("Slippage.Spread", 0)
+	viper.SetDefault("Slippage.Improvement", 0)
+	viper.SetDefault("Slippage.ImprovementProbability", 0)
+	viper.SetDefault("AvgPrice.Places", 4)
+	viper.SetDefault("AvgPrice.Rounding", "half-even")
 
 	// Read config file if present
 	err := viper.ReadInConfig()
@@ -0,0 +1,86 @@
+package domain
+
+import (
+	"fmt"
+
+	"github.com/shopspring/decimal"
+)
+
+// Decimal is an exact quantity, price or amount in a DTO. Quantities, prices
+// and amounts are stored as decimal(18,8), and Decimal encodes them in JSON as
+// numbers rather than the strings decimal.Decimal produces, so no precision is
+// lost between the database, the arithmetic and the messages. It decodes
+// numbers and strings alike.
+type Decimal struct {
+	decimal.Decimal
+}
+
+// NewDecimal wraps d for a DTO.
+func NewDecimal(d decimal.Decimal) Decimal {
+	return Decimal{Decimal: d}
+}
+
+// MarshalJSON encodes d as a JSON number.
+func (d Decimal) MarshalJSON() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+// Scale is the number of decimal places stored for quantities, prices and amounts.
+const Scale = 8
+
+// RoundScale rounds d to Scale places with banker's rounding, so values
+// computed in memory match what the database stores.
+func RoundScale(d decimal.Decimal) decimal.Decimal {
+	return d.RoundBank(Scale)
+}
+
+// Rounding modes for average prices.
+const (
+	RoundHalfEven = "half-even" // Banker's rounding: ties go to the even digit
+	RoundHalfUp   = "half-up"   // Ties go away from zero
+	RoundDown     = "down"      // Truncates towards zero
+)
+
+// Rounding rounds to a number of decimal places with one of the Round* modes.
+type Rounding struct {
+	Places int32
+	Mode   string
+}
+
+// NewRounding validates a rounding to places decimal places with mode.
+func NewRounding(places int32, mode string) (Rounding, error) {
+	if places < 0 || places > Scale {
+		return Rounding{}, fmt.Errorf("invalid places %d", places)
+	}
+	switch mode {
+	case RoundHalfEven, RoundHalfUp, RoundDown:
+	default:
+		return Rounding{}, fmt.Errorf("unknown rounding mode %q", mode)
+	}
+	return Rounding{Places: places, Mode: mode}, nil
+}
+
+// Div returns x / y rounded exactly, i.e. from the true quotient rather than
+// a quotient already rounded to some division precision. y must not be zero.
+func (r Rounding) Div(x, y decimal.Decimal) decimal.Decimal {
+	// q is truncated towards zero and |rem| < |y| * 10^-Places
+	q, rem := x.QuoRem(y, r.Places)
+	if rem.IsZero() || r.Mode == RoundDown {
+		return q
+	}
+	ulp := decimal.New(1, -r.Places)
+	if x.Sign() != y.Sign() {
+		ulp = ulp.Neg()
+	}
+	switch rem.Abs().Add(rem.Abs()).Cmp(y.Abs().Shift(-r.Places)) {
+	case 1:
+		return q.Add(ulp)
+	case -1:
+		return q
+	}
+	// Exactly half way
+	if r.Mode == RoundHalfUp || q.Shift(r.Places).BigInt().Bit(0) == 1 {
+		return q.Add(ulp)
+	}
+	return q
+}
@@ -0,0 +1,118 @@
+package domain
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRounding_Div(t *testing.T) {
+	d := decimal.RequireFromString
+	cases := []struct {
+		x, y                   string
+		halfEven, halfUp, down string
+	}{
+		{"246.89", "200", "1.2344", "1.2345", "1.2344"}, // 1.23445, a tie
+		{"246.91", "200", "1.2346", "1.2346", "1.2345"}, // 1.23455, a tie
+		{"-246.89", "200", "-1.2344", "-1.2345", "-1.2344"},
+		{"100", "3", "33.3333", "33.3333", "33.3333"},
+		{"200", "3", "66.6667", "66.6667", "66.6666"},
+		{"12.5", "5", "2.5", "2.5", "2.5"},
+		// A tie between the stored scale and the rounded places
+		{"1.00005", "1", "1", "1.0001", "1"},
+	}
+	for _, c := range cases {
+		x, y := d(c.x), d(c.y)
+		assert.Equal(t, c.halfEven, Rounding{4, RoundHalfEven}.Div(x, y).String(), "%s / %s half-even", c.x, c.y)
+		assert.Equal(t, c.halfUp, Rounding{4, RoundHalfUp}.Div(x, y).String(), "%s / %s half-up", c.x, c.y)
+		assert.Equal(t, c.down, Rounding{4, RoundDown}.Div(x, y).String(), "%s / %s down", c.x, c.y)
+	}
+}
+
+func TestNewRounding(t *testing.T) {
+	r, err := NewRounding(2, RoundHalfUp)
+	require.NoError(t, err)
+	assert.Equal(t, Rounding{Places: 2, Mode: RoundHalfUp}, r)
+
+	_, err = NewRounding(9, RoundHalfEven)
+	assert.Error(t, err)
+	_, err = NewRounding(4, "ceiling")
+	assert.Error(t, err)
+}
+
+func TestAveragePrice(t *testing.T) {
+	exec := &Execution{}
+	r := Rounding{Places: 4, Mode: RoundHalfEven}
+	assert.Nil(t, AveragePrice(exec, r), "no average price before the first fill")
+
+	// 3,000,000 shares in 30,000 fills of 100 at 101.23456789: the total is
+	// exact, where summing float64 amounts drifts by a fraction of a cent.
+	price := decimal.RequireFromString("101.23456789")
+	lot := decimal.NewFromInt(100)
+	for i := 0; i < 30000; i++ {
+		exec.QuantityFilled = exec.QuantityFilled.Add(lot)
+		exec.TotalAmount = exec.TotalAmount.Add(RoundScale(lot.Mul(price)))
+	}
+	assert.Equal(t, "303703703.67", exec.TotalAmount.String())
+	assert.Equal(t, "101.2346", AveragePrice(exec, r).String())
+	assert.Equal(t, "101.23", AveragePrice(exec, Rounding{Places: 2, Mode: RoundDown}).String())
+}
+
+func TestDecimalJSON(t *testing.T) {
+	type dto struct {
+		Price Decimal  `json:"price"`
+		Fee   *Decimal `json:"fee,omitempty"`
+	}
+	b, err := json.Marshal(dto{Price: NewDecimal(decimal.RequireFromString("150.12345678"))})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"price":150.12345678}`, string(b))
+
+	// Numbers and strings both decode exactly
+	var got dto
+	require.NoError(t, json.Unmarshal([]byte(`{"price":0.1,"fee":"0.30000001"}`), &got))
+	assert.Equal(t, "0.1", got.Price.String())
+	assert.Equal(t, "0.30000001", got.Fee.String())
+
+	// decimal.Decimal itself keeps its default encoding
+	b, err = json.Marshal(decimal.RequireFromString("1.5"))
+	require.NoError(t, err)
+	assert.Equal(t, `"1.5"`, string(b))
+}
+
+func TestAmendDTOJSON(t *testing.T) {
+	cases := []struct {
+		body          string
+		quantity      string // "" if absent
+		limitPrice    string
+		hasLimitPrice bool
+	}{
+		{`{"executionServiceId": 1, "quantity": 250}`, "250", "", false},
+		{`{"executionServiceId": 1, "limitPrice": null}`, "", "", false},
+		{`{"executionServiceId": 1, "limitPrice": 0}`, "", "0", true},
+		{`{"executionServiceId": 1, "quantity": "300.5", "limitPrice": 101.12345678}`, "300.5", "101.12345678", true},
+	}
+	for _, c := range cases {
+		var amend AmendDTO
+		require.NoError(t, json.Unmarshal([]byte(c.body), &amend), c.body)
+		assert.Equal(t, 1, amend.ExecutionServiceID)
+		if c.quantity == "" {
+			assert.Nil(t, amend.QuantityOrdered, c.body)
+		} else if assert.NotNil(t, amend.QuantityOrdered, c.body) {
+			assert.Equal(t, c.quantity, amend.QuantityOrdered.String())
+		}
+		if !c.hasLimitPrice {
+			assert.Nil(t, amend.LimitPrice, c.body)
+		} else if assert.NotNil(t, amend.LimitPrice, c.body) {
+			assert.Equal(t, c.limitPrice, amend.LimitPrice.String())
+		}
+	}
+
+	// Amendments encode as numbers, omitting what they leave unchanged.
+	price := NewDecimal(decimal.RequireFromString("101.5"))
+	b, err := json.Marshal(AmendDTO{ExecutionServiceID: 1, LimitPrice: &price})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"executionServiceId": 1, "limitPrice": 101.5}`, string(b))
+}
@@ -2,11 +2,21 @@ package domain
 
 import (
 	"github.com/kasbench/globeco-fix-engine/internal/repository"
+	"github.com/shopspring/decimal"
 )
 
 // Execution is the domain model (mirrors the DB model)
 type Execution = repository.Execution
 
+// Time in force values. Orders without a time in force are GTC.
+const (
+	TimeInForceDay = "DAY" // Expires at the trading session close
+	TimeInForceIOC = "IOC" // Immediate or cancel: one fill attempt, the remainder is cancelled
+	TimeInForceFOK = "FOK" // Fill or kill: fills completely in one attempt or is cancelled
+	TimeInForceGTC = "GTC" // Good till cancelled
+	TimeInForceGTD = "GTD" // Good till date: expires at ExpireTimestamp
+)
+
 // ExecutionDTO is used for API responses and Kafka fills topic
 // Maps to the execution table and includes all fields
 // JSON tags use camelCase for API compatibility
@@ -19,17 +29,107 @@ type ExecutionDTO struct {
 	Destination             string     `json:"destination"`
 	SecurityID              string     `json:"securityId"`
 	Ticker                  string     `json:"ticker"`
-	QuantityOrdered         float64    `json:"quantity"`
-	LimitPrice              *float64   `json:"limitPrice,omitempty"`
+	QuantityOrdered         Decimal    `json:"quantity"`
+	LimitPrice              *Decimal   `json:"limitPrice,omitempty"`
 	ReceivedTimestamp       EpochTime  `json:"receivedTimestamp"`
 	SentTimestamp           EpochTime  `json:"sentTimestamp"`
 	LastFillTimestamp       *EpochTime `json:"lastFilledTimestamp,omitempty"`
-	QuantityFilled          float64    `json:"quantityFilled"`
-	AveragePrice            *float64   `json:"averagePrice,omitempty"`
+	QuantityFilled          Decimal    `json:"quantityFilled"`
+	AveragePrice            *Decimal   `json:"averagePrice,omitempty"`
 	NumberOfFills           int16      `json:"numberOfFills"`
-	TotalAmount             float64    `json:"totalAmount"`
+	TotalAmount             Decimal    `json:"totalAmount"`
 	TradeServiceExecutionID *int       `json:"tradeServiceExecutionId,omitempty"`
 	Version                 int        `json:"version"`
+	TimeInForce             string     `json:"timeInForce,omitempty"`
+	ExpireTimestamp         *EpochTime `json:"expireTimestamp,omitempty"`
+}
+
+// Event types carried by ExecutionEventDTO and RejectDTO.
+const (
+	EventTypeNew     = "NEW"     // The order was accepted
+	EventTypePartial = "PARTIAL" // A fill left part of the order open
+	EventTypeFill    = "FILL"    // A fill completed the order
+	EventTypeCancel  = "CANCEL"  // The order, or its unfilled remainder, was cancelled
+	EventTypeReject  = "REJECT"  // The order could not be accepted
+	EventTypeExpire  = "EXPIRE"  // A DAY or GTD order expired
+	EventTypeReplace = "REPLACE" // The order was amended
+)
+
+// ExecutionEventDTO is published to the Kafka fills topic for every change to
+// an execution: the event, and for PARTIAL and FILL events the individual
+// fill, alongside the cumulative snapshot after the change.
+type ExecutionEventDTO struct {
+	EventType     string     `json:"eventType"`
+	FillID        *int64     `json:"fillId,omitempty"`
+	LastQuantity  *Decimal   `json:"lastQuantity,omitempty"`
+	LastPrice     *Decimal   `json:"lastPrice,omitempty"`
+	LastFee       *Decimal   `json:"lastFee,omitempty"`
+	FillTimestamp *EpochTime `json:"fillTimestamp,omitempty"`
+	*ExecutionDTO
+}
+
+// MapExecutionEventToDTO maps a change to a DB Execution to an ExecutionEventDTO.
+// fill is nil unless the event is a fill. The average price is rounded with r.
+func MapExecutionEventToDTO(eventType string, exec *Execution, fill *Fill, r Rounding) *ExecutionEventDTO {
+	dto := &ExecutionEventDTO{EventType: eventType, ExecutionDTO: MapExecutionToDTO(exec, r)}
+	if fill != nil {
+		ts := EpochTimeFromTime(fill.FillTimestamp)
+		dto.FillID = &fill.ID
+		lastQuantity, lastPrice, lastFee := NewDecimal(fill.Quantity), NewDecimal(fill.Price), NewDecimal(fill.Fee)
+		dto.LastQuantity = &lastQuantity
+		dto.LastPrice = &lastPrice
+		dto.LastFee = &lastFee
+		dto.FillTimestamp = &ts
+	}
+	return dto
+}
+
+// Reject reason codes carried by RejectDTO. Only the first three are final and
+// published as rejects; the others classify transient intake failures.
+const (
+	RejectReasonInvalidMessage  = "INVALID_MESSAGE"  // The order message is not valid JSON
+	RejectReasonInvalidOrder    = "INVALID_ORDER"    // The order is well formed but its fields are invalid
+	RejectReasonUnknownSecurity = "UNKNOWN_SECURITY" // The Security Service does not know the security ID
+	RejectReasonSecurityLookup  = "SECURITY_LOOKUP"  // The Security Service could not be reached or failed
+	RejectReasonPersistence     = "PERSISTENCE"      // The execution could not be stored
+)
+
+// Reason codes for cancel and amend requests that are dead-lettered. Requests
+// that are not valid JSON are dead-lettered with RejectReasonInvalidMessage.
+const (
+	DeadLetterReasonUnknownExecution = "UNKNOWN_EXECUTION" // No execution with the executionServiceId arrived in time
+	DeadLetterReasonExecutionClosed  = "EXECUTION_CLOSED"  // The amended execution is no longer open
+	DeadLetterReasonInvalidAmend     = "INVALID_AMEND"     // The amendment is refused, e.g. below the quantity filled
+)
+
+// RejectDTO reports an order that could not be accepted. It is published to
+// the rejects topic (the fills topic by default) with executionStatus REJ and
+// eventType REJECT. ExecutionServiceID is 0 if the order message could not be parsed.
+type RejectDTO struct {
+	EventType          string    `json:"eventType"`
+	ExecutionServiceID int       `json:"executionServiceId"`
+	IsOpen             bool      `json:"isOpen"`
+	ExecutionStatus    string    `json:"executionStatus"`
+	ReasonCode         string    `json:"reasonCode"`
+	Reason             string    `json:"reason"`
+	TradeType          string    `json:"tradeType,omitempty"`
+	SecurityID         string    `json:"securityId,omitempty"`
+	QuantityOrdered    *Decimal  `json:"quantity,omitempty"`
+	RejectedTimestamp  EpochTime `json:"rejectedTimestamp"`
+}
+
+// CancelDTO is a cancel request consumed from the Kafka cancels topic.
+type CancelDTO struct {
+	ExecutionServiceID int `json:"executionServiceId"`
+}
+
+// AmendDTO amends an open execution; it is consumed from the Kafka amends topic
+// and accepted by the REST API. Omitted fields are left unchanged, and a
+// limit price of 0 turns the order into a market order.
+type AmendDTO struct {
+	ExecutionServiceID int      `json:"executionServiceId"`
+	QuantityOrdered    *Decimal `json:"quantity,omitempty"`
+	LimitPrice         *Decimal `json:"limitPrice,omitempty"`
 }
 
 // ExecutionPostDTO is used for creating new executions (API or Kafka orders topic)
@@ -44,22 +144,27 @@ type ExecutionDTO struct {
 // 	Version                 int      `json:"version"`
 // }
 
-// MapExecutionToDTO maps a DB Execution to an ExecutionDTO
-func MapExecutionToDTO(exec *Execution) *ExecutionDTO {
-	var limitPrice *float64
+// MapExecutionToDTO maps a DB Execution to an ExecutionDTO, rounding the average price with r
+func MapExecutionToDTO(exec *Execution, r Rounding) *ExecutionDTO {
+	var limitPrice *Decimal
 	if exec.LimitPrice.Valid {
-		limitPrice = &exec.LimitPrice.Float64
+		price := NewDecimal(exec.LimitPrice.Decimal)
+		limitPrice = &price
+	}
+	var averagePrice *Decimal
+	if avg := AveragePrice(exec, r); avg != nil {
+		price := NewDecimal(*avg)
+		averagePrice = &price
 	}
 	var lastFill *EpochTime
 	if exec.LastFillTimestamp.Valid {
 		t := EpochTimeFromTime(exec.LastFillTimestamp.Time)
 		lastFill = &t
 	}
-	var avgPrice *float64
-	if exec.QuantityFilled > 0 {
-		tmp := exec.TotalAmount / exec.QuantityFilled
-		val := float64(int64(tmp*10000)) / 10000 // round to 4 decimal places
-		avgPrice = &val
+	var expire *EpochTime
+	if exec.ExpireTimestamp.Valid {
+		t := EpochTimeFromTime(exec.ExpireTimestamp.Time)
+		expire = &t
 	}
 	return &ExecutionDTO{
 		ID:                 exec.ID,
@@ -70,15 +175,15 @@ func MapExecutionToDTO(exec *Execution) *ExecutionDTO {
 		Destination:        exec.Destination,
 		SecurityID:         exec.SecurityID,
 		Ticker:             exec.Ticker,
-		QuantityOrdered:    exec.QuantityOrdered,
+		QuantityOrdered:    NewDecimal(exec.QuantityOrdered),
 		LimitPrice:         limitPrice,
 		ReceivedTimestamp:  EpochTimeFromTime(exec.ReceivedTimestamp),
 		SentTimestamp:      EpochTimeFromTime(exec.SentTimestamp),
 		LastFillTimestamp:  lastFill,
-		QuantityFilled:     exec.QuantityFilled,
-		AveragePrice:       avgPrice,
+		QuantityFilled:     NewDecimal(exec.QuantityFilled),
+		AveragePrice:       averagePrice,
 		NumberOfFills:      exec.NumberOfFills,
-		TotalAmount:        exec.TotalAmount,
+		TotalAmount:        NewDecimal(exec.TotalAmount),
 		TradeServiceExecutionID: func() *int {
 			if exec.TradeServiceExecutionID.Valid {
 				val := int(exec.TradeServiceExecutionID.Int64)
@@ -86,6 +191,18 @@ func MapExecutionToDTO(exec *Execution) *ExecutionDTO {
 			}
 			return nil
 		}(),
-		Version: exec.Version,
+		Version:         exec.Version,
+		TimeInForce:     exec.TimeInForce,
+		ExpireTimestamp: expire,
+	}
+}
+
+// AveragePrice returns the average fill price of exec rounded with r, or nil
+// if nothing has filled.
+func AveragePrice(exec *Execution, r Rounding) *decimal.Decimal {
+	if !exec.QuantityFilled.IsPositive() {
+		return nil
 	}
+	avg := r.Div(exec.TotalAmount, exec.QuantityFilled)
+	return &avg
 }
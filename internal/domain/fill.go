@@ -0,0 +1,34 @@
+package domain
+
+import (
+	"github.com/kasbench/globeco-fix-engine/internal/repository"
+)
+
+// Fill is the domain model of a single fill (mirrors the DB model)
+type Fill = repository.Fill
+
+// FillDTO is used for the fill history API
+type FillDTO struct {
+	ID            int64     `json:"id"`
+	ExecutionID   int       `json:"executionId"`
+	FillSequence  int       `json:"fillSequence"`
+	Quantity      Decimal   `json:"quantity"`
+	Price         Decimal   `json:"price"`
+	Fee           Decimal   `json:"fee"`
+	FillTimestamp EpochTime `json:"fillTimestamp"`
+	Venue         string    `json:"venue"`
+}
+
+// MapFillToDTO maps a DB Fill to a FillDTO
+func MapFillToDTO(fill *Fill) *FillDTO {
+	return &FillDTO{
+		ID:            fill.ID,
+		ExecutionID:   fill.ExecutionID,
+		FillSequence:  fill.FillSequence,
+		Quantity:      NewDecimal(fill.Quantity),
+		Price:         NewDecimal(fill.Price),
+		Fee:           NewDecimal(fill.Fee),
+		FillTimestamp: EpochTimeFromTime(fill.FillTimestamp),
+		Venue:         fill.Venue,
+	}
+}
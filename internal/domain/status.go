@@ -0,0 +1,93 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ExecutionStatus is the state of an execution in the order state machine.
+type ExecutionStatus string
+
+// Execution statuses. NEW, WORK, PART and the pending states are open and can
+// still fill; the others are terminal.
+const (
+	StatusNew            ExecutionStatus = "NEW"  // Received, not yet accepted by the venue
+	StatusWorking        ExecutionStatus = "WORK" // Accepted, nothing filled
+	StatusPartial        ExecutionStatus = "PART" // Partially filled
+	StatusFilled         ExecutionStatus = "FULL" // Completely filled
+	StatusCancelled      ExecutionStatus = "CANC" // Cancelled, possibly after partial fills
+	StatusRejected       ExecutionStatus = "REJ"  // Rejected by the venue
+	StatusExpired        ExecutionStatus = "EXPD" // Expired by its time in force
+	StatusPendingCancel  ExecutionStatus = "PCAN" // Cancel requested, not yet confirmed
+	StatusPendingReplace ExecutionStatus = "PREP" // Replace requested, not yet confirmed
+)
+
+// transitions lists the statuses each status may move to. A status that does
+// not change is not a transition; terminal statuses have none.
+var transitions = map[ExecutionStatus][]ExecutionStatus{
+	StatusNew:            {StatusWorking, StatusRejected, StatusCancelled, StatusExpired},
+	StatusWorking:        {StatusPartial, StatusFilled, StatusCancelled, StatusExpired, StatusPendingCancel, StatusPendingReplace},
+	StatusPartial:        {StatusFilled, StatusCancelled, StatusExpired, StatusPendingCancel, StatusPendingReplace},
+	StatusPendingCancel:  {StatusCancelled, StatusWorking, StatusPartial, StatusFilled, StatusExpired},
+	StatusPendingReplace: {StatusWorking, StatusPartial, StatusFilled, StatusCancelled, StatusExpired, StatusPendingCancel},
+	StatusFilled:         nil,
+	StatusCancelled:      nil,
+	StatusRejected:       nil,
+	StatusExpired:        nil,
+}
+
+// OpenStatuses are the statuses in which an execution is open.
+var OpenStatuses = []ExecutionStatus{StatusNew, StatusWorking, StatusPartial, StatusPendingCancel, StatusPendingReplace}
+
+// Valid reports whether s is a known status.
+func (s ExecutionStatus) Valid() bool {
+	_, ok := transitions[s]
+	return ok
+}
+
+// IsOpen reports whether an execution in status s is open.
+func (s ExecutionStatus) IsOpen() bool {
+	for _, open := range OpenStatuses {
+		if s == open {
+			return true
+		}
+	}
+	return false
+}
+
+// CanTransition reports whether an execution may move from s to to.
+func (s ExecutionStatus) CanTransition(to ExecutionStatus) bool {
+	for _, allowed := range transitions[s] {
+		if to == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+// ErrInvalidTransition matches any *InvalidTransitionError with errors.Is.
+var ErrInvalidTransition = errors.New("invalid execution status transition")
+
+// InvalidTransitionError is returned by Transition for a move the state
+// machine does not allow.
+type InvalidTransitionError struct {
+	From ExecutionStatus
+	To   ExecutionStatus
+}
+
+func (e *InvalidTransitionError) Error() string {
+	return fmt.Sprintf("%v: %s to %s", ErrInvalidTransition, e.From, e.To)
+}
+
+func (e *InvalidTransitionError) Is(target error) bool {
+	return target == ErrInvalidTransition
+}
+
+// Transition checks a move from status from to status to, returning
+// *InvalidTransitionError if the state machine does not allow it.
+func Transition(from, to ExecutionStatus) error {
+	if !from.CanTransition(to) {
+		return &InvalidTransitionError{From: from, To: to}
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExecutionStatus_IsOpen(t *testing.T) {
+	for _, s := range []ExecutionStatus{StatusNew, StatusWorking, StatusPartial, StatusPendingCancel, StatusPendingReplace} {
+		assert.True(t, s.IsOpen(), s)
+		assert.True(t, s.Valid(), s)
+	}
+	for _, s := range []ExecutionStatus{StatusFilled, StatusCancelled, StatusRejected, StatusExpired} {
+		assert.False(t, s.IsOpen(), s)
+		assert.True(t, s.Valid(), s)
+	}
+	assert.False(t, ExecutionStatus("DONE").Valid())
+	assert.False(t, ExecutionStatus("DONE").IsOpen())
+}
+
+func TestTransition(t *testing.T) {
+	valid := [][2]ExecutionStatus{
+		{StatusNew, StatusWorking},
+		{StatusNew, StatusRejected},
+		{StatusWorking, StatusPartial},
+		{StatusWorking, StatusFilled},
+		{StatusPartial, StatusFilled},
+		{StatusPartial, StatusCancelled},
+		{StatusWorking, StatusExpired},
+		{StatusPartial, StatusPendingCancel},
+		{StatusPendingCancel, StatusCancelled},
+		{StatusPendingReplace, StatusWorking},
+	}
+	for _, tr := range valid {
+		assert.NoError(t, Transition(tr[0], tr[1]), "%s to %s", tr[0], tr[1])
+	}
+
+	invalid := [][2]ExecutionStatus{
+		{StatusNew, StatusFilled},
+		{StatusPartial, StatusWorking},
+		{StatusPartial, StatusPartial},
+		{StatusFilled, StatusPartial},
+		{StatusCancelled, StatusWorking},
+		{StatusRejected, StatusWorking},
+		{StatusExpired, StatusCancelled},
+		{ExecutionStatus("DONE"), StatusWorking},
+	}
+	for _, tr := range invalid {
+		err := Transition(tr[0], tr[1])
+		assert.ErrorIs(t, err, ErrInvalidTransition, "%s to %s", tr[0], tr[1])
+		var invalidErr *InvalidTransitionError
+		if assert.ErrorAs(t, err, &invalidErr) {
+			assert.Equal(t, tr[0], invalidErr.From)
+			assert.Equal(t, tr[1], invalidErr.To)
+		}
+	}
+}
@@ -0,0 +1,260 @@
+package fix
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// ErrSessionNotFound is returned by Acceptor.Send when the session is not logged on.
+var ErrSessionNotFound = errors.New("fix: session not logged on")
+
+// AcceptorConfig configures the TCP acceptor.
+type AcceptorConfig struct {
+	Port         int
+	SenderCompID string
+	LogonTimeout time.Duration
+}
+
+// Acceptor accepts counterparty connections and runs one Session per logged-on CompID.
+type Acceptor struct {
+	cfg    AcceptorConfig
+	app    Application
+	stores MessageStoreFactory
+	logger *zap.Logger
+
+	mu       sync.Mutex
+	listener net.Listener
+	// sessions holds the logged-on sessions; a nil entry reserves the id of a
+	// session whose Logon is still in progress.
+	sessions map[SessionID]*Session
+	conns    sync.WaitGroup
+}
+
+// NewAcceptor constructs a new Acceptor.
+func NewAcceptor(cfg AcceptorConfig, app Application, stores MessageStoreFactory, logger *zap.Logger) *Acceptor {
+	if cfg.LogonTimeout <= 0 {
+		cfg.LogonTimeout = 10 * time.Second
+	}
+	return &Acceptor{
+		cfg:      cfg,
+		app:      app,
+		stores:   stores,
+		logger:   logger,
+		sessions: make(map[SessionID]*Session),
+	}
+}
+
+// Listen opens the TCP listener.
+func (a *Acceptor) Listen() error {
+	ln, err := net.Listen("tcp", fmt.Sprintf(":%d", a.cfg.Port))
+	if err != nil {
+		return fmt.Errorf("fix acceptor listen: %w", err)
+	}
+	a.mu.Lock()
+	a.listener = ln
+	a.mu.Unlock()
+	return nil
+}
+
+// Addr returns the listener address, or nil before Listen.
+func (a *Acceptor) Addr() net.Addr {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.listener == nil {
+		return nil
+	}
+	return a.listener.Addr()
+}
+
+// Serve accepts connections until ctx is cancelled, then logs out every
+// session and waits for their connections to close.
+func (a *Acceptor) Serve(ctx context.Context) error {
+	a.mu.Lock()
+	ln := a.listener
+	a.mu.Unlock()
+	if ln == nil {
+		return errors.New("fix acceptor: Serve called before Listen")
+	}
+	go func() {
+		<-ctx.Done()
+		ln.Close()
+	}()
+	a.logger.Info("FIX acceptor listening", zap.String("addr", ln.Addr().String()), zap.String("sender_comp_id", a.cfg.SenderCompID))
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				a.conns.Wait()
+				return nil
+			}
+			a.logger.Warn("FIX accept error", zap.Error(err))
+			continue
+		}
+		a.conns.Add(1)
+		go func() {
+			defer a.conns.Done()
+			a.handleConn(ctx, conn)
+		}()
+	}
+}
+
+// ListenAndServe is Listen followed by Serve.
+func (a *Acceptor) ListenAndServe(ctx context.Context) error {
+	if err := a.Listen(); err != nil {
+		return err
+	}
+	return a.Serve(ctx)
+}
+
+// Send delivers an application message to a logged-on session.
+func (a *Acceptor) Send(ctx context.Context, sessionID SessionID, m *Message) error {
+	a.mu.Lock()
+	s := a.sessions[sessionID]
+	a.mu.Unlock()
+	if s == nil {
+		return fmt.Errorf("%w: %s", ErrSessionNotFound, sessionID)
+	}
+	return s.Send(ctx, m)
+}
+
+// Sessions returns the sessions currently logged on.
+func (a *Acceptor) Sessions() []SessionID {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	ids := make([]SessionID, 0, len(a.sessions))
+	for id, s := range a.sessions {
+		if s != nil {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+func (a *Acceptor) handleConn(ctx context.Context, conn net.Conn) {
+	defer conn.Close()
+	remote := conn.RemoteAddr().String()
+
+	inbound := make(chan *Message)
+	readErr := make(chan error, 1)
+	done := make(chan struct{})
+	defer close(done)
+	go readLoop(conn, a.logger, inbound, readErr, done)
+
+	var logon *Message
+	select {
+	case logon = <-inbound:
+	case err := <-readErr:
+		a.logger.Info("FIX connection closed before Logon", zap.String("remote", remote), zap.Error(err))
+		return
+	case <-time.After(a.cfg.LogonTimeout):
+		a.logger.Warn("FIX Logon timeout", zap.String("remote", remote))
+		return
+	case <-ctx.Done():
+		return
+	}
+
+	s, err := a.logon(ctx, conn, logon)
+	if err != nil {
+		a.logger.Warn("FIX Logon refused", zap.String("remote", remote), zap.Error(err))
+		return
+	}
+	defer a.unregister(s.id)
+	s.logger.Info("FIX session logged on", zap.String("remote", remote))
+	a.app.OnLogon(s.id)
+	defer a.app.OnLogout(s.id)
+
+	s.run(ctx, inbound, readErr)
+}
+
+// logon validates the counterparty's Logon, answers with a Logon of our own
+// and registers the session. Until the Logon (and any ResendRequest) is on the
+// wire the id is only reserved, so nothing can be sent on the session ahead of
+// it.
+func (a *Acceptor) logon(ctx context.Context, conn net.Conn, m *Message) (*Session, error) {
+	if m.MsgType() != MsgTypeLogon {
+		return nil, fmt.Errorf("first message must be Logon, got MsgType %q", m.MsgType())
+	}
+	if target, _ := m.Header.Get(TagTargetCompID); target != a.cfg.SenderCompID {
+		return nil, fmt.Errorf("unknown TargetCompID %q", target)
+	}
+	sender, err := m.Header.GetString(TagSenderCompID)
+	if err != nil {
+		return nil, err
+	}
+	heartBtInt, err := m.Body.GetInt(TagHeartBtInt)
+	if err != nil {
+		return nil, err
+	}
+
+	id := SessionID{BeginString: BeginString, SenderCompID: a.cfg.SenderCompID, TargetCompID: sender}
+	store, err := a.stores.Create(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("loading session store: %w", err)
+	}
+	s := newSession(id, conn, store, a.app, heartBtInt, a.logger)
+
+	a.mu.Lock()
+	if _, exists := a.sessions[id]; exists {
+		a.mu.Unlock()
+		return nil, fmt.Errorf("session %s already logged on", id)
+	}
+	a.sessions[id] = nil
+	a.mu.Unlock()
+
+	registered := false
+	defer func() {
+		if !registered {
+			a.unregister(id)
+		}
+	}()
+
+	reset := m.Body.GetBool(TagResetSeqNumFlag)
+	if reset {
+		if err := store.Reset(ctx); err != nil {
+			return nil, err
+		}
+	}
+	seq := m.MsgSeqNum()
+	expected := store.NextTargetMsgSeqNum()
+	if seq < expected {
+		s.initiateLogout(ctx, fmt.Sprintf("MsgSeqNum too low, expecting %d but received %d", expected, seq))
+		return nil, fmt.Errorf("logon MsgSeqNum %d below expected %d", seq, expected)
+	}
+
+	resp := NewMessage(MsgTypeLogon)
+	resp.Body.SetInt(TagEncryptMethod, 0)
+	resp.Body.SetInt(TagHeartBtInt, heartBtInt)
+	if reset {
+		resp.Body.SetBool(TagResetSeqNumFlag, true)
+	}
+	if err := s.Send(ctx, resp); err != nil {
+		return nil, err
+	}
+
+	if seq > expected {
+		s.resendTarget = seq
+		if err := s.sendResendRequest(ctx, expected); err != nil {
+			return nil, err
+		}
+	} else if err := store.IncrNextTargetMsgSeqNum(ctx); err != nil {
+		return nil, err
+	}
+
+	a.mu.Lock()
+	a.sessions[id] = s
+	a.mu.Unlock()
+	registered = true
+	return s, nil
+}
+
+func (a *Acceptor) unregister(id SessionID) {
+	a.mu.Lock()
+	delete(a.sessions, id)
+	a.mu.Unlock()
+}
@@ -0,0 +1,318 @@
+package fix
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+type recordingApp struct {
+	mu       sync.Mutex
+	received []*Message
+	logons   int
+}
+
+func (a *recordingApp) OnLogon(sessionID SessionID) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.logons++
+}
+
+func (a *recordingApp) OnLogout(sessionID SessionID) {}
+
+func (a *recordingApp) FromApp(ctx context.Context, sessionID SessionID, msg *Message) ([]*Message, error) {
+	a.mu.Lock()
+	a.received = append(a.received, msg)
+	a.mu.Unlock()
+	if msg.MsgType() != MsgTypeNewOrderSingle {
+		return nil, ErrUnsupportedMsgType
+	}
+	clOrdID, _ := msg.Body.Get(TagClOrdID)
+	ack := &ExecutionReport{OrderID: "1", ClOrdID: clOrdID, ExecID: "1-0", ExecType: ExecTypeNew, OrdStatus: OrdStatusNew, Side: SideBuy}
+	return []*Message{ack.ToMessage()}, nil
+}
+
+// testClient is a minimal initiator used to drive the acceptor.
+type testClient struct {
+	t    *testing.T
+	conn net.Conn
+	r    *bufio.Reader
+	seq  int
+}
+
+func startAcceptor(t *testing.T, app Application, stores MessageStoreFactory) (*Acceptor, context.CancelFunc) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	a := NewAcceptor(AcceptorConfig{Port: 0, SenderCompID: "FIXENGINE", LogonTimeout: time.Second}, app, stores, zap.NewNop())
+	require.NoError(t, a.Listen())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		a.Serve(ctx)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+	return a, cancel
+}
+
+func dial(t *testing.T, a *Acceptor) *testClient {
+	t.Helper()
+	conn, err := net.Dial("tcp", a.Addr().String())
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return &testClient{t: t, conn: conn, r: bufio.NewReader(conn), seq: 1}
+}
+
+func (c *testClient) send(m *Message) {
+	c.sendSeq(m, c.seq)
+	c.seq++
+}
+
+func (c *testClient) sendSeq(m *Message, seq int) {
+	m.Header.Set(TagSenderCompID, "CLIENT")
+	m.Header.Set(TagTargetCompID, "FIXENGINE")
+	m.Header.SetInt(TagMsgSeqNum, seq)
+	m.Header.SetTime(TagSendingTime, time.Now())
+	b, err := Encode(m)
+	require.NoError(c.t, err)
+	_, err = c.conn.Write(b)
+	require.NoError(c.t, err)
+}
+
+func (c *testClient) read() *Message {
+	c.conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	raw, err := ReadMessage(c.r)
+	require.NoError(c.t, err)
+	m, err := Decode(raw)
+	require.NoError(c.t, err)
+	return m
+}
+
+func (c *testClient) logon() *Message {
+	m := NewMessage(MsgTypeLogon)
+	m.Body.SetInt(TagEncryptMethod, 0)
+	m.Body.SetInt(TagHeartBtInt, 30)
+	c.send(m)
+	return c.read()
+}
+
+func TestAcceptor_LogonAndApplicationMessage(t *testing.T) {
+	app := &recordingApp{}
+	a, _ := startAcceptor(t, app, NewMemoryStoreFactory())
+	c := dial(t, a)
+	assert.Empty(t, a.Sessions())
+
+	resp := c.logon()
+	assert.Equal(t, MsgTypeLogon, resp.MsgType())
+	assert.Equal(t, 1, resp.MsgSeqNum())
+	target, _ := resp.Header.Get(TagTargetCompID)
+	assert.Equal(t, "CLIENT", target)
+	assert.Equal(t, []SessionID{{BeginString: BeginString, SenderCompID: "FIXENGINE", TargetCompID: "CLIENT"}}, a.Sessions())
+
+	order := &NewOrderSingle{ClOrdID: "42", Symbol: "IBM", Side: SideBuy, OrderQty: decimal.NewFromInt(100), OrdType: OrdTypeMarket, TransactTime: time.Now()}
+	c.send(order.ToMessage())
+	ack := c.read()
+	assert.Equal(t, MsgTypeExecutionReport, ack.MsgType())
+	assert.Equal(t, 2, ack.MsgSeqNum())
+	clOrdID, _ := ack.Body.Get(TagClOrdID)
+	assert.Equal(t, "42", clOrdID)
+
+	tr := NewMessage(MsgTypeTestRequest)
+	tr.Body.Set(TagTestReqID, "ping")
+	c.send(tr)
+	hb := c.read()
+	assert.Equal(t, MsgTypeHeartbeat, hb.MsgType())
+	id, _ := hb.Body.Get(TagTestReqID)
+	assert.Equal(t, "ping", id)
+
+	// Unsupported application messages are rejected at the session level.
+	c.send(NewMessage("Z"))
+	rej := c.read()
+	assert.Equal(t, MsgTypeReject, rej.MsgType())
+	reason, _ := rej.Body.GetInt(TagSessionRejectReason)
+	assert.Equal(t, rejectReasonInvalidMsgType, reason)
+
+	c.send(NewMessage(MsgTypeLogout))
+	assert.Equal(t, MsgTypeLogout, c.read().MsgType())
+}
+
+func TestAcceptor_AdminMessagesAreGapFilled(t *testing.T) {
+	a, _ := startAcceptor(t, &recordingApp{}, NewMemoryStoreFactory())
+	c := dial(t, a)
+	c.logon()
+
+	rr := NewMessage(MsgTypeResendRequest)
+	rr.Body.SetInt(TagBeginSeqNo, 1)
+	rr.Body.SetInt(TagEndSeqNo, 0)
+	c.send(rr)
+
+	gf := c.read()
+	assert.Equal(t, MsgTypeSequenceReset, gf.MsgType())
+	assert.Equal(t, 1, gf.MsgSeqNum())
+	assert.True(t, gf.Body.GetBool(TagGapFillFlag))
+	assert.True(t, gf.Header.GetBool(TagPossDupFlag))
+	newSeqNo, _ := gf.Body.GetInt(TagNewSeqNo)
+	assert.Equal(t, 2, newSeqNo)
+}
+
+func TestAcceptor_SequenceGapTriggersResendRequest(t *testing.T) {
+	a, _ := startAcceptor(t, &recordingApp{}, NewMemoryStoreFactory())
+	c := dial(t, a)
+	c.logon()
+
+	c.sendSeq(NewMessage(MsgTypeHeartbeat), 5)
+	rr := c.read()
+	assert.Equal(t, MsgTypeResendRequest, rr.MsgType())
+	begin, _ := rr.Body.GetInt(TagBeginSeqNo)
+	assert.Equal(t, 2, begin)
+
+	// Gap fill our own messages 2..4; the acceptor should now expect 5.
+	sr := NewMessage(MsgTypeSequenceReset)
+	sr.Header.SetBool(TagPossDupFlag, true)
+	sr.Body.SetBool(TagGapFillFlag, true)
+	sr.Body.SetInt(TagNewSeqNo, 5)
+	c.sendSeq(sr, 2)
+
+	tr := NewMessage(MsgTypeTestRequest)
+	tr.Body.Set(TagTestReqID, "after-gap")
+	c.sendSeq(tr, 5)
+	hb := c.read()
+	assert.Equal(t, MsgTypeHeartbeat, hb.MsgType())
+}
+
+func TestAcceptor_RejectsUnknownTargetCompID(t *testing.T) {
+	a, _ := startAcceptor(t, &recordingApp{}, NewMemoryStoreFactory())
+	c := dial(t, a)
+
+	m := NewMessage(MsgTypeLogon)
+	m.Body.SetInt(TagHeartBtInt, 30)
+	m.Header.Set(TagSenderCompID, "CLIENT")
+	m.Header.Set(TagTargetCompID, "SOMEONE-ELSE")
+	m.Header.SetInt(TagMsgSeqNum, 1)
+	m.Header.SetTime(TagSendingTime, time.Now())
+	b, err := Encode(m)
+	require.NoError(t, err)
+	_, err = c.conn.Write(b)
+	require.NoError(t, err)
+
+	c.conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	_, err = ReadMessage(c.r)
+	assert.Error(t, err, "connection should be closed without a Logon response")
+}
+
+func TestAcceptor_SequenceNumbersSurviveReconnect(t *testing.T) {
+	a, _ := startAcceptor(t, &recordingApp{}, NewMemoryStoreFactory())
+	c := dial(t, a)
+	c.logon()
+	c.send(NewMessage(MsgTypeLogout))
+	c.read()
+
+	c2 := dial(t, a)
+	c2.seq = c.seq
+	resp := c2.logon()
+	assert.Equal(t, MsgTypeLogon, resp.MsgType())
+	assert.Equal(t, 3, resp.MsgSeqNum())
+}
+
+func TestAcceptor_ResendRequestReplaysJournal(t *testing.T) {
+	a, _ := startAcceptor(t, &recordingApp{}, NewMemoryStoreFactory())
+	c := dial(t, a)
+	c.logon()
+
+	order := &NewOrderSingle{ClOrdID: "7", Symbol: "IBM", Side: SideBuy, OrderQty: decimal.NewFromInt(100), OrdType: OrdTypeMarket, TransactTime: time.Now()}
+	c.send(order.ToMessage())
+	original := c.read()
+	tr := NewMessage(MsgTypeTestRequest)
+	tr.Body.Set(TagTestReqID, "x")
+	c.send(tr)
+	c.read()
+
+	rr := NewMessage(MsgTypeResendRequest)
+	rr.Body.SetInt(TagBeginSeqNo, 1)
+	rr.Body.SetInt(TagEndSeqNo, 0)
+	c.send(rr)
+
+	// Seq 1 (Logon) is administrative and is gap filled up to the ExecutionReport.
+	gf := c.read()
+	assert.Equal(t, MsgTypeSequenceReset, gf.MsgType())
+	assert.Equal(t, 1, gf.MsgSeqNum())
+	newSeqNo, _ := gf.Body.GetInt(TagNewSeqNo)
+	assert.Equal(t, 2, newSeqNo)
+
+	resent := c.read()
+	assert.Equal(t, MsgTypeExecutionReport, resent.MsgType())
+	assert.Equal(t, 2, resent.MsgSeqNum())
+	assert.True(t, resent.Header.GetBool(TagPossDupFlag))
+	origSent, _ := original.Header.Get(TagSendingTime)
+	origSendingTime, _ := resent.Header.Get(TagOrigSendingTime)
+	assert.Equal(t, origSent, origSendingTime)
+	assert.Equal(t, original.Body, resent.Body)
+
+	// Seq 3 (the Heartbeat answering the TestRequest) is gap filled to the next sequence number.
+	gf = c.read()
+	assert.Equal(t, MsgTypeSequenceReset, gf.MsgType())
+	assert.Equal(t, 3, gf.MsgSeqNum())
+	newSeqNo, _ = gf.Body.GetInt(TagNewSeqNo)
+	assert.Equal(t, 4, newSeqNo)
+}
+
+// blockingResetStores hands out memory stores whose Reset waits for release,
+// holding a ResetSeqNumFlag Logon part way through.
+type blockingResetStores struct {
+	*MemoryStoreFactory
+	resetting chan struct{}
+	release   chan struct{}
+}
+
+type blockingResetStore struct {
+	MessageStore
+	stores *blockingResetStores
+}
+
+func (f *blockingResetStores) Create(ctx context.Context, sessionID SessionID) (MessageStore, error) {
+	store, err := f.MemoryStoreFactory.Create(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	return &blockingResetStore{MessageStore: store, stores: f}, nil
+}
+
+func (s *blockingResetStore) Reset(ctx context.Context) error {
+	close(s.stores.resetting)
+	<-s.stores.release
+	return s.MessageStore.Reset(ctx)
+}
+
+func TestAcceptor_SessionNotAvailableUntilLogonAnswered(t *testing.T) {
+	stores := &blockingResetStores{MemoryStoreFactory: NewMemoryStoreFactory(), resetting: make(chan struct{}), release: make(chan struct{})}
+	a, _ := startAcceptor(t, &recordingApp{}, stores)
+	c := dial(t, a)
+	id := SessionID{BeginString: BeginString, SenderCompID: "FIXENGINE", TargetCompID: "CLIENT"}
+
+	m := NewMessage(MsgTypeLogon)
+	m.Body.SetInt(TagEncryptMethod, 0)
+	m.Body.SetInt(TagHeartBtInt, 30)
+	m.Body.SetBool(TagResetSeqNumFlag, true)
+	c.send(m)
+	<-stores.resetting
+
+	assert.Empty(t, a.Sessions())
+	err := a.Send(context.Background(), id, NewMessage(MsgTypeHeartbeat))
+	assert.ErrorIs(t, err, ErrSessionNotFound)
+
+	close(stores.release)
+	resp := c.read()
+	assert.Equal(t, MsgTypeLogon, resp.MsgType())
+	assert.Equal(t, 1, resp.MsgSeqNum())
+	assert.Eventually(t, func() bool { return len(a.Sessions()) == 1 }, time.Second, 10*time.Millisecond)
+}
@@ -0,0 +1,167 @@
+package fix
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+var (
+	// ErrGarbled is returned when a message cannot be framed or tokenized.
+	ErrGarbled = errors.New("fix: garbled message")
+	// ErrInvalidBeginString is returned when tag 8 is missing, misplaced or not FIX.4.4.
+	ErrInvalidBeginString = errors.New("fix: invalid BeginString")
+	// ErrBodyLengthMismatch is returned when tag 9 disagrees with the actual body length.
+	ErrBodyLengthMismatch = errors.New("fix: BodyLength mismatch")
+	// ErrChecksumMismatch is returned when tag 10 disagrees with the computed checksum.
+	ErrChecksumMismatch = errors.New("fix: CheckSum mismatch")
+	// ErrRequiredTagMissing is returned when a required field is absent.
+	ErrRequiredTagMissing = errors.New("fix: required tag missing")
+	// ErrIncorrectDataFormat is returned when a field value cannot be parsed.
+	ErrIncorrectDataFormat = errors.New("fix: incorrect data format")
+	// ErrIncorrectNumInGroup is returned when a repeating group is malformed.
+	ErrIncorrectNumInGroup = errors.New("fix: incorrect NumInGroup")
+	// ErrUnexpectedMsgType is returned when a typed parser receives the wrong message type.
+	ErrUnexpectedMsgType = errors.New("fix: unexpected MsgType")
+)
+
+// Encode serializes a message to tag=value form. BeginString, BodyLength and
+// MsgType are written first, then the rest of the header, the body, the trailer
+// and finally a freshly computed CheckSum.
+func Encode(m *Message) ([]byte, error) {
+	msgType := m.MsgType()
+	if msgType == "" {
+		return nil, fmt.Errorf("%w: tag %d", ErrRequiredTagMissing, TagMsgType)
+	}
+	beginString, ok := m.Header.Get(TagBeginString)
+	if !ok {
+		beginString = BeginString
+	}
+
+	var body bytes.Buffer
+	writeField(&body, TagMsgType, msgType)
+	for _, f := range m.Header {
+		switch f.Tag {
+		case TagBeginString, TagBodyLength, TagMsgType:
+			continue
+		}
+		writeField(&body, f.Tag, f.Value)
+	}
+	for _, f := range m.Body {
+		writeField(&body, f.Tag, f.Value)
+	}
+	for _, f := range m.Trailer {
+		if f.Tag == TagCheckSum {
+			continue
+		}
+		writeField(&body, f.Tag, f.Value)
+	}
+
+	var out bytes.Buffer
+	writeField(&out, TagBeginString, beginString)
+	writeField(&out, TagBodyLength, strconv.Itoa(body.Len()))
+	out.Write(body.Bytes())
+	writeField(&out, TagCheckSum, formatChecksum(checksum(out.Bytes())))
+	return out.Bytes(), nil
+}
+
+// Decode parses and validates a single tag=value message. It checks that
+// BeginString, BodyLength and MsgType are the first three fields, that
+// BodyLength matches, and that CheckSum is last and correct.
+func Decode(raw []byte) (*Message, error) {
+	fields, err := tokenize(raw)
+	if err != nil {
+		return nil, err
+	}
+	if len(fields) < 4 {
+		return nil, fmt.Errorf("%w: too few fields", ErrGarbled)
+	}
+	if fields[0].Tag != TagBeginString || fields[0].Value != BeginString {
+		return nil, ErrInvalidBeginString
+	}
+	if fields[1].Tag != TagBodyLength {
+		return nil, fmt.Errorf("%w: tag %d must be second", ErrGarbled, TagBodyLength)
+	}
+	if fields[2].Tag != TagMsgType {
+		return nil, fmt.Errorf("%w: tag %d must be third", ErrGarbled, TagMsgType)
+	}
+	last := fields[len(fields)-1]
+	if last.Tag != TagCheckSum {
+		return nil, fmt.Errorf("%w: tag %d must be last", ErrGarbled, TagCheckSum)
+	}
+
+	bodyLength, err := strconv.Atoi(fields[1].Value)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %q", ErrBodyLengthMismatch, fields[1].Value)
+	}
+	// The body runs from the byte after the BodyLength field's SOH up to, and
+	// including, the SOH that precedes the CheckSum field.
+	bodyStart := len(fmt.Sprintf("8=%s\x019=%s\x01", fields[0].Value, fields[1].Value))
+	trailerStart := bytes.LastIndex(raw, []byte("\x0110="))
+	if trailerStart < 0 {
+		return nil, fmt.Errorf("%w: missing CheckSum", ErrGarbled)
+	}
+	trailerStart++ // keep the SOH inside the body
+	if trailerStart-bodyStart != bodyLength {
+		return nil, fmt.Errorf("%w: declared %d, actual %d", ErrBodyLengthMismatch, bodyLength, trailerStart-bodyStart)
+	}
+	want, err := strconv.Atoi(last.Value)
+	if err != nil || len(last.Value) != 3 {
+		return nil, fmt.Errorf("%w: %q", ErrChecksumMismatch, last.Value)
+	}
+	if got := checksum(raw[:trailerStart]); got != want {
+		return nil, fmt.Errorf("%w: declared %03d, computed %03d", ErrChecksumMismatch, want, got)
+	}
+
+	m := &Message{}
+	for _, f := range fields {
+		switch {
+		case f.Tag == TagCheckSum:
+			m.Trailer = append(m.Trailer, f)
+		case headerTags[f.Tag]:
+			m.Header = append(m.Header, f)
+		default:
+			m.Body = append(m.Body, f)
+		}
+	}
+	return m, nil
+}
+
+func tokenize(raw []byte) ([]Field, error) {
+	if len(raw) == 0 || raw[len(raw)-1] != SOH {
+		return nil, fmt.Errorf("%w: message must end with SOH", ErrGarbled)
+	}
+	var fields []Field
+	for _, token := range bytes.Split(raw[:len(raw)-1], []byte{SOH}) {
+		eq := bytes.IndexByte(token, '=')
+		if eq <= 0 {
+			return nil, fmt.Errorf("%w: bad field %q", ErrGarbled, token)
+		}
+		tag, err := strconv.Atoi(string(token[:eq]))
+		if err != nil || tag <= 0 {
+			return nil, fmt.Errorf("%w: bad tag %q", ErrGarbled, token[:eq])
+		}
+		fields = append(fields, Field{Tag: tag, Value: string(token[eq+1:])})
+	}
+	return fields, nil
+}
+
+func writeField(buf *bytes.Buffer, tag int, value string) {
+	buf.WriteString(strconv.Itoa(tag))
+	buf.WriteByte('=')
+	buf.WriteString(value)
+	buf.WriteByte(SOH)
+}
+
+func checksum(b []byte) int {
+	sum := 0
+	for _, c := range b {
+		sum += int(c)
+	}
+	return sum % 256
+}
+
+func formatChecksum(sum int) string {
+	return fmt.Sprintf("%03d", sum)
+}
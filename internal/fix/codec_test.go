@@ -0,0 +1,192 @@
+package fix
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// raw converts a pipe-delimited message into SOH-delimited bytes.
+func raw(s string) []byte {
+	return []byte(strings.ReplaceAll(s, "|", "\x01"))
+}
+
+func TestEncode_ComputesBodyLengthAndChecksum(t *testing.T) {
+	m := NewMessage(MsgTypeHeartbeat)
+	m.Header.Set(TagSenderCompID, "FIXENGINE")
+	m.Header.Set(TagTargetCompID, "CLIENT")
+	m.Header.SetInt(TagMsgSeqNum, 2)
+	m.Header.Set(TagSendingTime, "20240102-10:00:00.000")
+
+	b, err := Encode(m)
+	require.NoError(t, err)
+
+	want := "8=FIX.4.4|9=58|35=0|49=FIXENGINE|56=CLIENT|34=2|52=20240102-10:00:00.000|10="
+	assert.True(t, bytes.HasPrefix(b, raw(want)), "got %q", strings.ReplaceAll(string(b), "\x01", "|"))
+
+	decoded, err := Decode(b)
+	require.NoError(t, err)
+	assert.Equal(t, MsgTypeHeartbeat, decoded.MsgType())
+	assert.Equal(t, 2, decoded.MsgSeqNum())
+}
+
+func TestDecode_ValidatesFraming(t *testing.T) {
+	m := NewMessage(MsgTypeTestRequest)
+	m.Body.Set(TagTestReqID, "abc")
+	good, err := Encode(m)
+	require.NoError(t, err)
+
+	t.Run("checksum", func(t *testing.T) {
+		bad := append([]byte{}, good...)
+		copy(bad[len(bad)-4:], "000")
+		_, err := Decode(bad)
+		assert.ErrorIs(t, err, ErrChecksumMismatch)
+	})
+	t.Run("body length", func(t *testing.T) {
+		bad := bytes.Replace(good, raw("9=13|"), raw("9=14|"), 1)
+		_, err := Decode(bad)
+		assert.ErrorIs(t, err, ErrBodyLengthMismatch)
+	})
+	t.Run("begin string", func(t *testing.T) {
+		bad := bytes.Replace(good, []byte("FIX.4.4"), []byte("FIX.4.2"), 1)
+		_, err := Decode(bad)
+		assert.ErrorIs(t, err, ErrInvalidBeginString)
+	})
+	t.Run("missing trailing SOH", func(t *testing.T) {
+		_, err := Decode(good[:len(good)-1])
+		assert.ErrorIs(t, err, ErrGarbled)
+	})
+}
+
+func TestRepeatingGroups(t *testing.T) {
+	var fm FieldMap
+	fm.Set(TagClOrdID, "1")
+	fm.SetGroup(PartiesGroup, []FieldMap{
+		{{Tag: TagPartyRole, Value: "1"}, {Tag: TagPartyID, Value: "BROKER"}},
+		{{Tag: TagPartyID, Value: "DESK"}, {Tag: TagPartyIDSource, Value: "D"}},
+	})
+	fm.Set(TagSide, SideBuy)
+
+	// The delimiter tag is always written first within each entry.
+	assert.Equal(t, []int{TagClOrdID, TagNoPartyIDs, TagPartyID, TagPartyRole, TagPartyID, TagPartyIDSource, TagSide}, tagsOf(fm))
+
+	entries, err := fm.GetGroup(PartiesGroup)
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	v, _ := entries[1].Get(TagPartyIDSource)
+	assert.Equal(t, "D", v)
+
+	bad := FieldMap{{Tag: TagNoPartyIDs, Value: "3"}, {Tag: TagPartyID, Value: "X"}}
+	_, err = bad.GetGroup(PartiesGroup)
+	assert.ErrorIs(t, err, ErrIncorrectNumInGroup)
+}
+
+func TestNewOrderSingle_RoundTrip(t *testing.T) {
+	price := decimal.RequireFromString("101.12345679") // not exact as a float64
+	expire := time.Date(2024, 1, 2, 21, 0, 0, 0, time.UTC)
+	order := &NewOrderSingle{
+		ClOrdID:       "12345",
+		Symbol:        "IBM",
+		SecurityID:    "68309ec47ac5fd2e7c3a6d6d",
+		Side:          SideBuy,
+		TransactTime:  time.Date(2024, 1, 2, 15, 4, 5, 123e6, time.UTC),
+		OrderQty:      decimal.NewFromInt(1500),
+		OrdType:       OrdTypeLimit,
+		Price:         &price,
+		TimeInForce:   TimeInForceGoodTillDate,
+		ExpireTime:    &expire,
+		ExDestination: "NYSE",
+		Parties:       []Party{{PartyID: "PM1", PartyIDSource: "D", PartyRole: 11}},
+	}
+	b, err := Encode(order.ToMessage())
+	require.NoError(t, err)
+	m, err := Decode(b)
+	require.NoError(t, err)
+	got, err := ParseNewOrderSingle(m)
+	require.NoError(t, err)
+	assert.Equal(t, order, got)
+}
+
+func TestParseNewOrderSingle_RequiresPriceForLimit(t *testing.T) {
+	order := &NewOrderSingle{ClOrdID: "1", Symbol: "IBM", Side: SideSell, OrderQty: decimal.NewFromInt(10), OrdType: OrdTypeLimit}
+	_, err := ParseNewOrderSingle(order.ToMessage())
+	assert.ErrorIs(t, err, ErrRequiredTagMissing)
+}
+
+func TestExecutionReport_RoundTrip(t *testing.T) {
+	report := &ExecutionReport{
+		OrderID:      "7",
+		ClOrdID:      "12345",
+		ExecID:       "7-3",
+		ExecType:     ExecTypeTrade,
+		OrdStatus:    OrdStatusPartiallyFilled,
+		Symbol:       "IBM",
+		Side:         SideBuy,
+		OrderQty:     decimal.NewFromInt(1500),
+		LastQty:      decimal.NewFromInt(300),
+		LastPx:       decimal.RequireFromString("100.5"),
+		LeavesQty:    decimal.NewFromInt(1200),
+		CumQty:       decimal.NewFromInt(300),
+		AvgPx:        decimal.RequireFromString("100.5"),
+		TransactTime: time.Date(2024, 1, 2, 15, 4, 5, 0, time.UTC),
+	}
+	b, err := Encode(report.ToMessage())
+	require.NoError(t, err)
+	m, err := Decode(b)
+	require.NoError(t, err)
+	got, err := ParseExecutionReport(m)
+	require.NoError(t, err)
+	assert.Equal(t, report, got)
+}
+
+func TestCancelMessages_RoundTrip(t *testing.T) {
+	cancel := &OrderCancelRequest{
+		OrigClOrdID:  "12345",
+		ClOrdID:      "12346",
+		Symbol:       "IBM",
+		Side:         SideBuy,
+		TransactTime: time.Date(2024, 1, 2, 15, 4, 5, 0, time.UTC),
+		OrderQty:     decimal.NewFromInt(1500),
+	}
+	b, err := Encode(cancel.ToMessage())
+	require.NoError(t, err)
+	m, err := Decode(b)
+	require.NoError(t, err)
+	gotCancel, err := ParseOrderCancelRequest(m)
+	require.NoError(t, err)
+	assert.Equal(t, cancel, gotCancel)
+
+	reason := 1
+	reject := &OrderCancelReject{
+		OrderID:          "NONE",
+		ClOrdID:          "12346",
+		OrigClOrdID:      "12345",
+		OrdStatus:        OrdStatusRejected,
+		CxlRejResponseTo: CxlRejResponseToCancel,
+		CxlRejReason:     &reason,
+		Text:             "unknown order",
+	}
+	b, err = Encode(reject.ToMessage())
+	require.NoError(t, err)
+	m, err = Decode(b)
+	require.NoError(t, err)
+	gotReject, err := ParseOrderCancelReject(m)
+	require.NoError(t, err)
+	assert.Equal(t, reject, gotReject)
+
+	_, err = ParseOrderCancelReject(cancel.ToMessage())
+	assert.ErrorIs(t, err, ErrUnexpectedMsgType)
+}
+
+func tagsOf(fm FieldMap) []int {
+	tags := make([]int, 0, len(fm))
+	for _, f := range fm {
+		tags = append(tags, f.Tag)
+	}
+	return tags
+}
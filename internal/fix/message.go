@@ -0,0 +1,283 @@
+package fix
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// UTCTimestampFormat is the FIX UTCTimestamp layout with millisecond precision.
+const UTCTimestampFormat = "20060102-15:04:05.000"
+
+// Field is a single tag=value pair.
+type Field struct {
+	Tag   int
+	Value string
+}
+
+// FieldMap is an ordered list of fields. Order matters in FIX because repeating
+// groups are delimited by position, so fields are kept in insertion order.
+type FieldMap []Field
+
+// Get returns the value of the first field with the given tag.
+func (fm FieldMap) Get(tag int) (string, bool) {
+	for _, f := range fm {
+		if f.Tag == tag {
+			return f.Value, true
+		}
+	}
+	return "", false
+}
+
+// Has reports whether the tag is present.
+func (fm FieldMap) Has(tag int) bool {
+	_, ok := fm.Get(tag)
+	return ok
+}
+
+// GetString returns the value of a required field.
+func (fm FieldMap) GetString(tag int) (string, error) {
+	v, ok := fm.Get(tag)
+	if !ok || v == "" {
+		return "", fmt.Errorf("%w: tag %d", ErrRequiredTagMissing, tag)
+	}
+	return v, nil
+}
+
+// GetInt returns the value of a required integer field.
+func (fm FieldMap) GetInt(tag int) (int, error) {
+	v, err := fm.GetString(tag)
+	if err != nil {
+		return 0, err
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return 0, fmt.Errorf("%w: tag %d value %q", ErrIncorrectDataFormat, tag, v)
+	}
+	return n, nil
+}
+
+// GetDecimal returns the value of a required decimal field, exactly as sent.
+func (fm FieldMap) GetDecimal(tag int) (decimal.Decimal, error) {
+	v, err := fm.GetString(tag)
+	if err != nil {
+		return decimal.Zero, err
+	}
+	d, err := decimal.NewFromString(v)
+	if err != nil {
+		return decimal.Zero, fmt.Errorf("%w: tag %d value %q", ErrIncorrectDataFormat, tag, v)
+	}
+	return d, nil
+}
+
+// GetTime returns the value of a required UTCTimestamp field.
+func (fm FieldMap) GetTime(tag int) (time.Time, error) {
+	v, err := fm.GetString(tag)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return parseUTCTimestamp(tag, v)
+}
+
+// GetBool returns the value of a Boolean (Y/N) field, defaulting to false when absent.
+func (fm FieldMap) GetBool(tag int) bool {
+	v, _ := fm.Get(tag)
+	return v == "Y"
+}
+
+// Set replaces the first field with the given tag, or appends it if absent.
+func (fm *FieldMap) Set(tag int, value string) {
+	for i := range *fm {
+		if (*fm)[i].Tag == tag {
+			(*fm)[i].Value = value
+			return
+		}
+	}
+	*fm = append(*fm, Field{Tag: tag, Value: value})
+}
+
+// SetInt sets an integer field.
+func (fm *FieldMap) SetInt(tag int, v int) {
+	fm.Set(tag, strconv.Itoa(v))
+}
+
+// SetDecimal sets a decimal field without trailing zeros.
+func (fm *FieldMap) SetDecimal(tag int, v decimal.Decimal) {
+	fm.Set(tag, v.String())
+}
+
+// SetTime sets a UTCTimestamp field.
+func (fm *FieldMap) SetTime(tag int, t time.Time) {
+	fm.Set(tag, t.UTC().Format(UTCTimestampFormat))
+}
+
+// SetBool sets a Boolean (Y/N) field.
+func (fm *FieldMap) SetBool(tag int, v bool) {
+	if v {
+		fm.Set(tag, "Y")
+	} else {
+		fm.Set(tag, "N")
+	}
+}
+
+// Remove deletes every field with the given tag.
+func (fm *FieldMap) Remove(tag int) {
+	out := (*fm)[:0]
+	for _, f := range *fm {
+		if f.Tag != tag {
+			out = append(out, f)
+		}
+	}
+	*fm = out
+}
+
+// GroupSpec describes a repeating group: the NoXXX count tag, the delimiter
+// tag that must start every entry, and the full set of member tags.
+type GroupSpec struct {
+	CountTag     int
+	DelimiterTag int
+	MemberTags   []int
+}
+
+func (g GroupSpec) isMember(tag int) bool {
+	for _, t := range g.MemberTags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// PartiesGroup is the standard Parties component (NoPartyIDs).
+var PartiesGroup = GroupSpec{
+	CountTag:     TagNoPartyIDs,
+	DelimiterTag: TagPartyID,
+	MemberTags:   []int{TagPartyID, TagPartyIDSource, TagPartyRole},
+}
+
+// SetGroup writes a repeating group, replacing any existing instance. Each entry
+// is written with the delimiter tag first, as the spec requires.
+func (fm *FieldMap) SetGroup(spec GroupSpec, entries []FieldMap) {
+	fm.removeGroup(spec)
+	if len(entries) == 0 {
+		return
+	}
+	*fm = append(*fm, Field{Tag: spec.CountTag, Value: strconv.Itoa(len(entries))})
+	for _, entry := range entries {
+		if v, ok := entry.Get(spec.DelimiterTag); ok {
+			*fm = append(*fm, Field{Tag: spec.DelimiterTag, Value: v})
+		}
+		for _, f := range entry {
+			if f.Tag != spec.DelimiterTag {
+				*fm = append(*fm, f)
+			}
+		}
+	}
+}
+
+// GetGroup reads a repeating group. It returns nil when the count tag is absent
+// and an error when the number of entries disagrees with the declared count.
+func (fm FieldMap) GetGroup(spec GroupSpec) ([]FieldMap, error) {
+	start := -1
+	for i, f := range fm {
+		if f.Tag == spec.CountTag {
+			start = i
+			break
+		}
+	}
+	if start < 0 {
+		return nil, nil
+	}
+	count, err := strconv.Atoi(fm[start].Value)
+	if err != nil || count < 0 {
+		return nil, fmt.Errorf("%w: tag %d value %q", ErrIncorrectDataFormat, spec.CountTag, fm[start].Value)
+	}
+
+	var entries []FieldMap
+	for i := start + 1; i < len(fm) && spec.isMember(fm[i].Tag); i++ {
+		if fm[i].Tag == spec.DelimiterTag {
+			entries = append(entries, FieldMap{})
+		} else if len(entries) == 0 {
+			return nil, fmt.Errorf("%w: group %d must start with tag %d", ErrIncorrectNumInGroup, spec.CountTag, spec.DelimiterTag)
+		}
+		entries[len(entries)-1] = append(entries[len(entries)-1], fm[i])
+	}
+	if len(entries) != count {
+		return nil, fmt.Errorf("%w: group %d declared %d entries, found %d", ErrIncorrectNumInGroup, spec.CountTag, count, len(entries))
+	}
+	return entries, nil
+}
+
+func (fm *FieldMap) removeGroup(spec GroupSpec) {
+	out := (*fm)[:0]
+	inGroup := false
+	for _, f := range *fm {
+		if f.Tag == spec.CountTag {
+			inGroup = true
+			continue
+		}
+		if inGroup && spec.isMember(f.Tag) {
+			continue
+		}
+		inGroup = false
+		out = append(out, f)
+	}
+	*fm = out
+}
+
+// Message is a FIX message split into its standard header, body and trailer.
+// BodyLength and CheckSum are computed by Encode and need not be set by callers.
+type Message struct {
+	Header  FieldMap
+	Body    FieldMap
+	Trailer FieldMap
+}
+
+// NewMessage creates a message with BeginString and MsgType set.
+func NewMessage(msgType string) *Message {
+	m := &Message{}
+	m.Header.Set(TagBeginString, BeginString)
+	m.Header.Set(TagMsgType, msgType)
+	return m
+}
+
+// MsgType returns the message type (tag 35).
+func (m *Message) MsgType() string {
+	v, _ := m.Header.Get(TagMsgType)
+	return v
+}
+
+// MsgSeqNum returns the sequence number (tag 34), or 0 if absent or invalid.
+func (m *Message) MsgSeqNum() int {
+	n, err := m.Header.GetInt(TagMsgSeqNum)
+	if err != nil {
+		return 0
+	}
+	return n
+}
+
+// IsAdmin reports whether the message belongs to the session layer.
+func (m *Message) IsAdmin() bool {
+	return IsAdminMsgType(m.MsgType())
+}
+
+// IsAdminMsgType reports whether msgType is a session-level message type.
+func IsAdminMsgType(msgType string) bool {
+	switch msgType {
+	case MsgTypeHeartbeat, MsgTypeTestRequest, MsgTypeResendRequest, MsgTypeReject,
+		MsgTypeSequenceReset, MsgTypeLogout, MsgTypeLogon:
+		return true
+	}
+	return false
+}
+
+func parseUTCTimestamp(tag int, v string) (time.Time, error) {
+	for _, layout := range []string{UTCTimestampFormat, "20060102-15:04:05", "20060102-15:04:05.000000"} {
+		if t, err := time.Parse(layout, v); err == nil {
+			return t.UTC(), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("%w: tag %d value %q", ErrIncorrectDataFormat, tag, v)
+}
@@ -0,0 +1,425 @@
+package fix
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// Party is an entry of the Parties repeating group.
+type Party struct {
+	PartyID       string
+	PartyIDSource string
+	PartyRole     int
+}
+
+// NewOrderSingle (35=D) submits a new order.
+type NewOrderSingle struct {
+	ClOrdID          string
+	Account          string
+	Symbol           string
+	SecurityID       string
+	SecurityIDSource string
+	Side             string
+	PositionEffect   string
+	TransactTime     time.Time
+	OrderQty         decimal.Decimal
+	OrdType          string
+	Price            *decimal.Decimal
+	TimeInForce      string
+	ExpireTime       *time.Time
+	ExDestination    string
+	Parties          []Party
+}
+
+// ToMessage converts the order to a generic message.
+func (o *NewOrderSingle) ToMessage() *Message {
+	m := NewMessage(MsgTypeNewOrderSingle)
+	m.Body.Set(TagClOrdID, o.ClOrdID)
+	setOptional(&m.Body, TagAccount, o.Account)
+	setOptional(&m.Body, TagSymbol, o.Symbol)
+	setOptional(&m.Body, TagSecurityID, o.SecurityID)
+	setOptional(&m.Body, TagSecurityIDSource, o.SecurityIDSource)
+	m.Body.Set(TagSide, o.Side)
+	setOptional(&m.Body, TagPositionEffect, o.PositionEffect)
+	m.Body.SetTime(TagTransactTime, o.TransactTime)
+	m.Body.SetDecimal(TagOrderQty, o.OrderQty)
+	m.Body.Set(TagOrdType, o.OrdType)
+	if o.Price != nil {
+		m.Body.SetDecimal(TagPrice, *o.Price)
+	}
+	setOptional(&m.Body, TagTimeInForce, o.TimeInForce)
+	if o.ExpireTime != nil {
+		m.Body.SetTime(TagExpireTime, *o.ExpireTime)
+	}
+	setOptional(&m.Body, TagExDestination, o.ExDestination)
+	m.Body.SetGroup(PartiesGroup, partiesToGroup(o.Parties))
+	return m
+}
+
+// ParseNewOrderSingle extracts a NewOrderSingle, validating required fields.
+func ParseNewOrderSingle(m *Message) (*NewOrderSingle, error) {
+	if err := expectMsgType(m, MsgTypeNewOrderSingle); err != nil {
+		return nil, err
+	}
+	var (
+		o   NewOrderSingle
+		err error
+	)
+	if o.ClOrdID, err = m.Body.GetString(TagClOrdID); err != nil {
+		return nil, err
+	}
+	if o.Side, err = m.Body.GetString(TagSide); err != nil {
+		return nil, err
+	}
+	if o.TransactTime, err = m.Body.GetTime(TagTransactTime); err != nil {
+		return nil, err
+	}
+	if o.OrderQty, err = m.Body.GetDecimal(TagOrderQty); err != nil {
+		return nil, err
+	}
+	if o.OrdType, err = m.Body.GetString(TagOrdType); err != nil {
+		return nil, err
+	}
+	o.Account, _ = m.Body.Get(TagAccount)
+	o.Symbol, _ = m.Body.Get(TagSymbol)
+	o.SecurityID, _ = m.Body.Get(TagSecurityID)
+	o.SecurityIDSource, _ = m.Body.Get(TagSecurityIDSource)
+	o.PositionEffect, _ = m.Body.Get(TagPositionEffect)
+	if o.Symbol == "" && o.SecurityID == "" {
+		return nil, fmt.Errorf("%w: tag %d or %d", ErrRequiredTagMissing, TagSymbol, TagSecurityID)
+	}
+	if m.Body.Has(TagPrice) {
+		price, err := m.Body.GetDecimal(TagPrice)
+		if err != nil {
+			return nil, err
+		}
+		o.Price = &price
+	}
+	if o.OrdType == OrdTypeLimit && o.Price == nil {
+		return nil, fmt.Errorf("%w: tag %d required for limit orders", ErrRequiredTagMissing, TagPrice)
+	}
+	o.TimeInForce, _ = m.Body.Get(TagTimeInForce)
+	if m.Body.Has(TagExpireTime) {
+		t, err := m.Body.GetTime(TagExpireTime)
+		if err != nil {
+			return nil, err
+		}
+		o.ExpireTime = &t
+	}
+	o.ExDestination, _ = m.Body.Get(TagExDestination)
+	if o.Parties, err = groupToParties(m.Body); err != nil {
+		return nil, err
+	}
+	return &o, nil
+}
+
+// ExecutionReport (35=8) reports order state changes and fills.
+type ExecutionReport struct {
+	OrderID      string
+	ClOrdID      string
+	OrigClOrdID  string
+	ExecID       string
+	ExecType     string
+	OrdStatus    string
+	OrdRejReason *int
+	Account      string
+	Symbol       string
+	SecurityID   string
+	Side         string
+	OrderQty     decimal.Decimal
+	OrdType      string
+	Price        *decimal.Decimal
+	TimeInForce  string
+	LastQty      decimal.Decimal
+	LastPx       decimal.Decimal
+	LeavesQty    decimal.Decimal
+	CumQty       decimal.Decimal
+	AvgPx        decimal.Decimal
+	TransactTime time.Time
+	Text         string
+	Parties      []Party
+}
+
+// ToMessage converts the report to a generic message.
+func (r *ExecutionReport) ToMessage() *Message {
+	m := NewMessage(MsgTypeExecutionReport)
+	m.Body.Set(TagOrderID, r.OrderID)
+	setOptional(&m.Body, TagClOrdID, r.ClOrdID)
+	setOptional(&m.Body, TagOrigClOrdID, r.OrigClOrdID)
+	m.Body.Set(TagExecID, r.ExecID)
+	m.Body.Set(TagExecType, r.ExecType)
+	m.Body.Set(TagOrdStatus, r.OrdStatus)
+	if r.OrdRejReason != nil {
+		m.Body.SetInt(TagOrdRejReason, *r.OrdRejReason)
+	}
+	setOptional(&m.Body, TagAccount, r.Account)
+	setOptional(&m.Body, TagSymbol, r.Symbol)
+	setOptional(&m.Body, TagSecurityID, r.SecurityID)
+	m.Body.Set(TagSide, r.Side)
+	m.Body.SetDecimal(TagOrderQty, r.OrderQty)
+	setOptional(&m.Body, TagOrdType, r.OrdType)
+	if r.Price != nil {
+		m.Body.SetDecimal(TagPrice, *r.Price)
+	}
+	setOptional(&m.Body, TagTimeInForce, r.TimeInForce)
+	m.Body.SetDecimal(TagLastQty, r.LastQty)
+	m.Body.SetDecimal(TagLastPx, r.LastPx)
+	m.Body.SetDecimal(TagLeavesQty, r.LeavesQty)
+	m.Body.SetDecimal(TagCumQty, r.CumQty)
+	m.Body.SetDecimal(TagAvgPx, r.AvgPx)
+	m.Body.SetTime(TagTransactTime, r.TransactTime)
+	setOptional(&m.Body, TagText, r.Text)
+	m.Body.SetGroup(PartiesGroup, partiesToGroup(r.Parties))
+	return m
+}
+
+// ParseExecutionReport extracts an ExecutionReport, validating required fields.
+func ParseExecutionReport(m *Message) (*ExecutionReport, error) {
+	if err := expectMsgType(m, MsgTypeExecutionReport); err != nil {
+		return nil, err
+	}
+	var (
+		r   ExecutionReport
+		err error
+	)
+	if r.OrderID, err = m.Body.GetString(TagOrderID); err != nil {
+		return nil, err
+	}
+	if r.ExecID, err = m.Body.GetString(TagExecID); err != nil {
+		return nil, err
+	}
+	if r.ExecType, err = m.Body.GetString(TagExecType); err != nil {
+		return nil, err
+	}
+	if r.OrdStatus, err = m.Body.GetString(TagOrdStatus); err != nil {
+		return nil, err
+	}
+	if r.Side, err = m.Body.GetString(TagSide); err != nil {
+		return nil, err
+	}
+	if r.LeavesQty, err = m.Body.GetDecimal(TagLeavesQty); err != nil {
+		return nil, err
+	}
+	if r.CumQty, err = m.Body.GetDecimal(TagCumQty); err != nil {
+		return nil, err
+	}
+	if r.AvgPx, err = m.Body.GetDecimal(TagAvgPx); err != nil {
+		return nil, err
+	}
+	r.ClOrdID, _ = m.Body.Get(TagClOrdID)
+	r.OrigClOrdID, _ = m.Body.Get(TagOrigClOrdID)
+	r.Account, _ = m.Body.Get(TagAccount)
+	r.Symbol, _ = m.Body.Get(TagSymbol)
+	r.SecurityID, _ = m.Body.Get(TagSecurityID)
+	r.OrdType, _ = m.Body.Get(TagOrdType)
+	r.TimeInForce, _ = m.Body.Get(TagTimeInForce)
+	r.Text, _ = m.Body.Get(TagText)
+	if m.Body.Has(TagOrdRejReason) {
+		reason, err := m.Body.GetInt(TagOrdRejReason)
+		if err != nil {
+			return nil, err
+		}
+		r.OrdRejReason = &reason
+	}
+	if m.Body.Has(TagOrderQty) {
+		if r.OrderQty, err = m.Body.GetDecimal(TagOrderQty); err != nil {
+			return nil, err
+		}
+	}
+	if m.Body.Has(TagPrice) {
+		price, err := m.Body.GetDecimal(TagPrice)
+		if err != nil {
+			return nil, err
+		}
+		r.Price = &price
+	}
+	if m.Body.Has(TagLastQty) {
+		if r.LastQty, err = m.Body.GetDecimal(TagLastQty); err != nil {
+			return nil, err
+		}
+	}
+	if m.Body.Has(TagLastPx) {
+		if r.LastPx, err = m.Body.GetDecimal(TagLastPx); err != nil {
+			return nil, err
+		}
+	}
+	if m.Body.Has(TagTransactTime) {
+		if r.TransactTime, err = m.Body.GetTime(TagTransactTime); err != nil {
+			return nil, err
+		}
+	}
+	if r.Parties, err = groupToParties(m.Body); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// OrderCancelRequest (35=F) requests cancellation of the remaining quantity of an order.
+type OrderCancelRequest struct {
+	OrigClOrdID  string
+	ClOrdID      string
+	OrderID      string
+	Symbol       string
+	SecurityID   string
+	Side         string
+	TransactTime time.Time
+	OrderQty     decimal.Decimal
+}
+
+// ToMessage converts the request to a generic message.
+func (c *OrderCancelRequest) ToMessage() *Message {
+	m := NewMessage(MsgTypeOrderCancelRequest)
+	m.Body.Set(TagOrigClOrdID, c.OrigClOrdID)
+	m.Body.Set(TagClOrdID, c.ClOrdID)
+	setOptional(&m.Body, TagOrderID, c.OrderID)
+	setOptional(&m.Body, TagSymbol, c.Symbol)
+	setOptional(&m.Body, TagSecurityID, c.SecurityID)
+	m.Body.Set(TagSide, c.Side)
+	m.Body.SetTime(TagTransactTime, c.TransactTime)
+	if c.OrderQty.IsPositive() {
+		m.Body.SetDecimal(TagOrderQty, c.OrderQty)
+	}
+	return m
+}
+
+// ParseOrderCancelRequest extracts an OrderCancelRequest, validating required fields.
+func ParseOrderCancelRequest(m *Message) (*OrderCancelRequest, error) {
+	if err := expectMsgType(m, MsgTypeOrderCancelRequest); err != nil {
+		return nil, err
+	}
+	var (
+		c   OrderCancelRequest
+		err error
+	)
+	if c.OrigClOrdID, err = m.Body.GetString(TagOrigClOrdID); err != nil {
+		return nil, err
+	}
+	if c.ClOrdID, err = m.Body.GetString(TagClOrdID); err != nil {
+		return nil, err
+	}
+	if c.Side, err = m.Body.GetString(TagSide); err != nil {
+		return nil, err
+	}
+	if c.TransactTime, err = m.Body.GetTime(TagTransactTime); err != nil {
+		return nil, err
+	}
+	c.OrderID, _ = m.Body.Get(TagOrderID)
+	c.Symbol, _ = m.Body.Get(TagSymbol)
+	c.SecurityID, _ = m.Body.Get(TagSecurityID)
+	if m.Body.Has(TagOrderQty) {
+		if c.OrderQty, err = m.Body.GetDecimal(TagOrderQty); err != nil {
+			return nil, err
+		}
+	}
+	return &c, nil
+}
+
+// OrderCancelReject (35=9) rejects a cancel or cancel/replace request.
+type OrderCancelReject struct {
+	OrderID          string
+	ClOrdID          string
+	OrigClOrdID      string
+	OrdStatus        string
+	CxlRejResponseTo string
+	CxlRejReason     *int
+	Text             string
+}
+
+// ToMessage converts the reject to a generic message.
+func (r *OrderCancelReject) ToMessage() *Message {
+	m := NewMessage(MsgTypeOrderCancelReject)
+	m.Body.Set(TagOrderID, r.OrderID)
+	m.Body.Set(TagClOrdID, r.ClOrdID)
+	m.Body.Set(TagOrigClOrdID, r.OrigClOrdID)
+	m.Body.Set(TagOrdStatus, r.OrdStatus)
+	m.Body.Set(TagCxlRejResponseTo, r.CxlRejResponseTo)
+	if r.CxlRejReason != nil {
+		m.Body.SetInt(TagCxlRejReason, *r.CxlRejReason)
+	}
+	setOptional(&m.Body, TagText, r.Text)
+	return m
+}
+
+// ParseOrderCancelReject extracts an OrderCancelReject, validating required fields.
+func ParseOrderCancelReject(m *Message) (*OrderCancelReject, error) {
+	if err := expectMsgType(m, MsgTypeOrderCancelReject); err != nil {
+		return nil, err
+	}
+	var (
+		r   OrderCancelReject
+		err error
+	)
+	if r.OrderID, err = m.Body.GetString(TagOrderID); err != nil {
+		return nil, err
+	}
+	if r.ClOrdID, err = m.Body.GetString(TagClOrdID); err != nil {
+		return nil, err
+	}
+	if r.OrigClOrdID, err = m.Body.GetString(TagOrigClOrdID); err != nil {
+		return nil, err
+	}
+	if r.OrdStatus, err = m.Body.GetString(TagOrdStatus); err != nil {
+		return nil, err
+	}
+	if r.CxlRejResponseTo, err = m.Body.GetString(TagCxlRejResponseTo); err != nil {
+		return nil, err
+	}
+	if m.Body.Has(TagCxlRejReason) {
+		reason, err := m.Body.GetInt(TagCxlRejReason)
+		if err != nil {
+			return nil, err
+		}
+		r.CxlRejReason = &reason
+	}
+	r.Text, _ = m.Body.Get(TagText)
+	return &r, nil
+}
+
+func expectMsgType(m *Message, msgType string) error {
+	if got := m.MsgType(); got != msgType {
+		return fmt.Errorf("%w: want %s, got %s", ErrUnexpectedMsgType, msgType, got)
+	}
+	return nil
+}
+
+func setOptional(fm *FieldMap, tag int, value string) {
+	if value != "" {
+		fm.Set(tag, value)
+	}
+}
+
+func partiesToGroup(parties []Party) []FieldMap {
+	entries := make([]FieldMap, 0, len(parties))
+	for _, p := range parties {
+		var entry FieldMap
+		entry.Set(TagPartyID, p.PartyID)
+		setOptional(&entry, TagPartyIDSource, p.PartyIDSource)
+		if p.PartyRole != 0 {
+			entry.SetInt(TagPartyRole, p.PartyRole)
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+func groupToParties(fm FieldMap) ([]Party, error) {
+	entries, err := fm.GetGroup(PartiesGroup)
+	if err != nil {
+		return nil, err
+	}
+	var parties []Party
+	for _, entry := range entries {
+		p := Party{}
+		p.PartyID, _ = entry.Get(TagPartyID)
+		p.PartyIDSource, _ = entry.Get(TagPartyIDSource)
+		if entry.Has(TagPartyRole) {
+			if p.PartyRole, err = entry.GetInt(TagPartyRole); err != nil {
+				return nil, err
+			}
+		}
+		parties = append(parties, p)
+	}
+	return parties, nil
+}
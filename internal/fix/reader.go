@@ -0,0 +1,48 @@
+package fix
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// maxBodyLength bounds the allocation made for a single inbound message.
+const maxBodyLength = 1 << 20
+
+// trailerLength is the size of "10=NNN<SOH>".
+const trailerLength = 7
+
+// ReadMessage reads exactly one framed message from a stream, using BodyLength
+// to find the end of the message. The returned bytes still need to be passed
+// to Decode for checksum validation.
+func ReadMessage(r *bufio.Reader) ([]byte, error) {
+	begin, err := r.ReadBytes(SOH)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.HasPrefix(begin, []byte("8=")) {
+		return nil, fmt.Errorf("%w: expected BeginString, got %q", ErrGarbled, begin)
+	}
+	length, err := r.ReadBytes(SOH)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.HasPrefix(length, []byte("9=")) {
+		return nil, fmt.Errorf("%w: expected BodyLength, got %q", ErrGarbled, length)
+	}
+	n, err := strconv.Atoi(string(length[2 : len(length)-1]))
+	if err != nil || n <= 0 || n > maxBodyLength {
+		return nil, fmt.Errorf("%w: invalid BodyLength %q", ErrGarbled, length)
+	}
+
+	out := make([]byte, 0, len(begin)+len(length)+n+trailerLength)
+	out = append(out, begin...)
+	out = append(out, length...)
+	rest := make([]byte, n+trailerLength)
+	if _, err := io.ReadFull(r, rest); err != nil {
+		return nil, err
+	}
+	return append(out, rest...), nil
+}
@@ -0,0 +1,501 @@
+package fix
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// SessionID identifies a FIX session from the engine's point of view:
+// SenderCompID is the engine and TargetCompID is the counterparty.
+type SessionID struct {
+	BeginString  string
+	SenderCompID string
+	TargetCompID string
+}
+
+func (id SessionID) String() string {
+	return fmt.Sprintf("%s:%s->%s", id.BeginString, id.SenderCompID, id.TargetCompID)
+}
+
+// ParseSessionID is the inverse of SessionID.String.
+func ParseSessionID(s string) (SessionID, error) {
+	begin, comps, ok := strings.Cut(s, ":")
+	if !ok {
+		return SessionID{}, fmt.Errorf("invalid session id %q", s)
+	}
+	sender, target, ok := strings.Cut(comps, "->")
+	if !ok || sender == "" || target == "" {
+		return SessionID{}, fmt.Errorf("invalid session id %q", s)
+	}
+	return SessionID{BeginString: begin, SenderCompID: sender, TargetCompID: target}, nil
+}
+
+// Application receives session events and application-level messages.
+type Application interface {
+	// OnLogon is called once a counterparty has completed the Logon handshake.
+	OnLogon(sessionID SessionID)
+	// OnLogout is called when a logged-on session ends for any reason.
+	OnLogout(sessionID SessionID)
+	// FromApp handles an in-sequence application message and returns the
+	// messages to send back. Returning an error causes a session-level Reject.
+	FromApp(ctx context.Context, sessionID SessionID, msg *Message) ([]*Message, error)
+}
+
+// ErrUnsupportedMsgType may be returned by Application.FromApp for message
+// types the application does not handle.
+var ErrUnsupportedMsgType = errors.New("fix: unsupported MsgType")
+
+// errDisconnect signals the session loop to close the connection.
+var errDisconnect = errors.New("fix: disconnect")
+
+// SessionRejectReason values (tag 373).
+const (
+	rejectReasonRequiredTagMissing  = 1
+	rejectReasonIncorrectDataFormat = 6
+	rejectReasonInvalidMsgType      = 11
+	rejectReasonIncorrectNumInGroup = 16
+	rejectReasonOther               = 99
+)
+
+const (
+	defaultHeartBtInt = 30
+	// A TestRequest is sent after 1.2 heartbeat intervals of silence, and the
+	// connection is dropped if nothing arrives within 2.4 intervals.
+	testRequestThreshold = 1.2
+	disconnectThreshold  = 2.4
+	sessionTimerInterval = 200 * time.Millisecond
+	logoutAckGracePeriod = 2 * time.Second
+)
+
+// Session runs the FIX session protocol over a single accepted connection.
+type Session struct {
+	id         SessionID
+	conn       net.Conn
+	store      MessageStore
+	app        Application
+	logger     *zap.Logger
+	heartBtInt time.Duration
+
+	sendMu   sync.Mutex
+	lastSent time.Time
+
+	lastReceived    time.Time
+	testRequestSent bool
+	resendTarget    int
+	logoutSent      bool
+}
+
+func newSession(id SessionID, conn net.Conn, store MessageStore, app Application, heartBtInt int, logger *zap.Logger) *Session {
+	if heartBtInt <= 0 {
+		heartBtInt = defaultHeartBtInt
+	}
+	return &Session{
+		id:           id,
+		conn:         conn,
+		store:        store,
+		app:          app,
+		logger:       logger.With(zap.String("fix_session", id.String())),
+		heartBtInt:   time.Duration(heartBtInt) * time.Second,
+		lastReceived: time.Now(),
+	}
+}
+
+// ID returns the session identifier.
+func (s *Session) ID() SessionID {
+	return s.id
+}
+
+// Send stamps the header with the next outbound sequence number, journals the
+// message and writes it. The message is journaled and the sequence number
+// consumed before the write, so a failed write is recovered by a later resend.
+func (s *Session) Send(ctx context.Context, m *Message) error {
+	s.sendMu.Lock()
+	defer s.sendMu.Unlock()
+	seq := s.store.NextSenderMsgSeqNum()
+	b, err := s.stamp(m, seq, time.Now())
+	if err != nil {
+		return err
+	}
+	if err := s.store.SaveMessage(ctx, seq, b); err != nil {
+		return fmt.Errorf("journaling message %d: %w", seq, err)
+	}
+	if err := s.store.IncrNextSenderMsgSeqNum(ctx); err != nil {
+		return err
+	}
+	return s.writeRaw(b)
+}
+
+// stamp sets the standard header fields and encodes the message.
+func (s *Session) stamp(m *Message, seq int, sendingTime time.Time) ([]byte, error) {
+	m.Header.Set(TagBeginString, s.id.BeginString)
+	m.Header.Set(TagSenderCompID, s.id.SenderCompID)
+	m.Header.Set(TagTargetCompID, s.id.TargetCompID)
+	m.Header.SetInt(TagMsgSeqNum, seq)
+	m.Header.SetTime(TagSendingTime, sendingTime)
+	return Encode(m)
+}
+
+// write stamps and writes one message without touching the store.
+// Callers must hold sendMu.
+func (s *Session) write(m *Message, seq int, sendingTime time.Time) error {
+	b, err := s.stamp(m, seq, sendingTime)
+	if err != nil {
+		return err
+	}
+	return s.writeRaw(b)
+}
+
+// writeRaw writes encoded bytes to the connection. Callers must hold sendMu.
+func (s *Session) writeRaw(b []byte) error {
+	if _, err := s.conn.Write(b); err != nil {
+		return err
+	}
+	s.lastSent = time.Now()
+	return nil
+}
+
+// run processes inbound messages and heartbeats until the connection ends or
+// ctx is cancelled. The Logon handshake must already have completed.
+func (s *Session) run(ctx context.Context, inbound <-chan *Message, readErr <-chan error) {
+	timer := time.NewTicker(sessionTimerInterval)
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			s.initiateLogout(context.Background(), "engine shutting down")
+			s.awaitLogoutAck(inbound)
+			return
+		case err := <-readErr:
+			s.logger.Info("FIX connection closed", zap.Error(err))
+			return
+		case m := <-inbound:
+			s.lastReceived = time.Now()
+			s.testRequestSent = false
+			if err := s.handle(ctx, m); err != nil {
+				if !errors.Is(err, errDisconnect) {
+					s.logger.Error("FIX session error", zap.Error(err))
+				}
+				return
+			}
+		case <-timer.C:
+			if err := s.checkHeartbeat(ctx); err != nil {
+				s.logger.Warn("FIX heartbeat failure, disconnecting", zap.Error(err))
+				return
+			}
+		}
+	}
+}
+
+func (s *Session) checkHeartbeat(ctx context.Context) error {
+	sinceReceived := time.Since(s.lastReceived)
+	if s.testRequestSent && sinceReceived >= time.Duration(float64(s.heartBtInt)*disconnectThreshold) {
+		return fmt.Errorf("no response to TestRequest after %s", sinceReceived)
+	}
+	if !s.testRequestSent && sinceReceived >= time.Duration(float64(s.heartBtInt)*testRequestThreshold) {
+		m := NewMessage(MsgTypeTestRequest)
+		m.Body.Set(TagTestReqID, fmt.Sprintf("TEST-%d", time.Now().UnixNano()))
+		s.testRequestSent = true
+		return s.Send(ctx, m)
+	}
+	s.sendMu.Lock()
+	idle := time.Since(s.lastSent)
+	s.sendMu.Unlock()
+	if idle >= s.heartBtInt {
+		return s.Send(ctx, NewMessage(MsgTypeHeartbeat))
+	}
+	return nil
+}
+
+// handle applies sequence number rules and dispatches one inbound message.
+func (s *Session) handle(ctx context.Context, m *Message) error {
+	msgType := m.MsgType()
+
+	// SequenceReset in Reset mode ignores MsgSeqNum entirely.
+	if msgType == MsgTypeSequenceReset && !m.Body.GetBool(TagGapFillFlag) {
+		return s.handleSequenceReset(ctx, m)
+	}
+
+	seq := m.MsgSeqNum()
+	expected := s.store.NextTargetMsgSeqNum()
+	switch {
+	case seq == 0:
+		s.initiateLogout(ctx, "MsgSeqNum missing")
+		return errDisconnect
+	case seq < expected:
+		if m.Header.GetBool(TagPossDupFlag) {
+			return nil
+		}
+		s.initiateLogout(ctx, fmt.Sprintf("MsgSeqNum too low, expecting %d but received %d", expected, seq))
+		return errDisconnect
+	case seq > expected:
+		switch msgType {
+		case MsgTypeLogout:
+			return s.handleLogout(ctx)
+		case MsgTypeResendRequest:
+			if err := s.handleResendRequest(ctx, m); err != nil {
+				return err
+			}
+		}
+		if s.resendTarget == 0 {
+			s.resendTarget = seq
+			return s.sendResendRequest(ctx, expected)
+		}
+		return nil
+	}
+
+	if s.resendTarget != 0 && seq >= s.resendTarget {
+		s.resendTarget = 0
+	}
+
+	switch msgType {
+	case MsgTypeHeartbeat, MsgTypeReject:
+	case MsgTypeTestRequest:
+		hb := NewMessage(MsgTypeHeartbeat)
+		if id, ok := m.Body.Get(TagTestReqID); ok {
+			hb.Body.Set(TagTestReqID, id)
+		}
+		if err := s.Send(ctx, hb); err != nil {
+			return err
+		}
+	case MsgTypeResendRequest:
+		if err := s.handleResendRequest(ctx, m); err != nil {
+			return err
+		}
+	case MsgTypeSequenceReset:
+		return s.handleSequenceReset(ctx, m)
+	case MsgTypeLogout:
+		if err := s.store.IncrNextTargetMsgSeqNum(ctx); err != nil {
+			return err
+		}
+		return s.handleLogout(ctx)
+	case MsgTypeLogon:
+		if err := s.sendReject(ctx, m, rejectReasonOther, "already logged on"); err != nil {
+			return err
+		}
+	default:
+		if err := s.store.IncrNextTargetMsgSeqNum(ctx); err != nil {
+			return err
+		}
+		return s.fromApp(ctx, m)
+	}
+	return s.store.IncrNextTargetMsgSeqNum(ctx)
+}
+
+func (s *Session) fromApp(ctx context.Context, m *Message) error {
+	responses, err := s.app.FromApp(ctx, s.id, m)
+	if err != nil {
+		s.logger.Warn("rejecting FIX application message", zap.String("msg_type", m.MsgType()), zap.Error(err))
+		return s.sendReject(ctx, m, rejectReasonFor(err), err.Error())
+	}
+	for _, resp := range responses {
+		if err := s.Send(ctx, resp); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// handleResendRequest replays the requested range from the journal.
+// Application messages are resent with PossDupFlag=Y and their original
+// SendingTime in OrigSendingTime; administrative messages, and any sequence
+// numbers missing from the journal, are skipped with SequenceReset-GapFill.
+func (s *Session) handleResendRequest(ctx context.Context, m *Message) error {
+	begin, err := m.Body.GetInt(TagBeginSeqNo)
+	if err != nil {
+		return s.sendReject(ctx, m, rejectReasonFor(err), err.Error())
+	}
+	end, err := m.Body.GetInt(TagEndSeqNo)
+	if err != nil {
+		end = 0
+	}
+
+	s.sendMu.Lock()
+	defer s.sendMu.Unlock()
+	next := s.store.NextSenderMsgSeqNum()
+	if end == 0 || end >= next {
+		end = next - 1
+	}
+	if begin > end {
+		return nil
+	}
+	s.logger.Info("FIX resend requested", zap.Int("begin", begin), zap.Int("end", end))
+
+	journal, err := s.store.GetMessages(ctx, begin, end)
+	if err != nil {
+		return fmt.Errorf("reading journal: %w", err)
+	}
+
+	gapStart := 0
+	expected := begin
+	for _, raw := range journal {
+		orig, err := Decode(raw)
+		if err != nil {
+			s.logger.Warn("skipping unreadable journal entry", zap.Error(err))
+			continue
+		}
+		seq := orig.MsgSeqNum()
+		if seq < expected || seq > end {
+			continue
+		}
+		if gapStart == 0 && seq > expected {
+			gapStart = expected
+		}
+		if orig.IsAdmin() {
+			if gapStart == 0 {
+				gapStart = seq
+			}
+			expected = seq + 1
+			continue
+		}
+		if gapStart != 0 {
+			if err := s.writeGapFill(gapStart, seq); err != nil {
+				return err
+			}
+			gapStart = 0
+		}
+		if err := s.writeResend(orig, seq); err != nil {
+			return err
+		}
+		expected = seq + 1
+	}
+	if gapStart == 0 && expected <= end {
+		gapStart = expected
+	}
+	if gapStart != 0 {
+		return s.writeGapFill(gapStart, end+1)
+	}
+	return nil
+}
+
+// writeResend writes a journaled message again as a possible duplicate.
+// Callers must hold sendMu.
+func (s *Session) writeResend(orig *Message, seq int) error {
+	if sent, ok := orig.Header.Get(TagSendingTime); ok {
+		orig.Header.Set(TagOrigSendingTime, sent)
+	}
+	orig.Header.SetBool(TagPossDupFlag, true)
+	return s.write(orig, seq, time.Now())
+}
+
+// writeGapFill sends a SequenceReset-GapFill numbered seq that moves the
+// counterparty's expected sequence number to newSeqNo. Callers must hold sendMu.
+func (s *Session) writeGapFill(seq, newSeqNo int) error {
+	gf := NewMessage(MsgTypeSequenceReset)
+	gf.Header.SetBool(TagPossDupFlag, true)
+	gf.Body.SetBool(TagGapFillFlag, true)
+	gf.Body.SetInt(TagNewSeqNo, newSeqNo)
+	return s.write(gf, seq, time.Now())
+}
+
+func (s *Session) handleSequenceReset(ctx context.Context, m *Message) error {
+	newSeqNo, err := m.Body.GetInt(TagNewSeqNo)
+	if err != nil {
+		return s.sendReject(ctx, m, rejectReasonFor(err), err.Error())
+	}
+	expected := s.store.NextTargetMsgSeqNum()
+	if newSeqNo < expected {
+		return s.sendReject(ctx, m, rejectReasonOther, fmt.Sprintf("NewSeqNo %d lower than expected %d", newSeqNo, expected))
+	}
+	s.logger.Info("FIX sequence reset", zap.Int("from", expected), zap.Int("to", newSeqNo))
+	return s.store.SetNextTargetMsgSeqNum(ctx, newSeqNo)
+}
+
+func (s *Session) handleLogout(ctx context.Context) error {
+	if !s.logoutSent {
+		if err := s.Send(ctx, NewMessage(MsgTypeLogout)); err != nil {
+			return err
+		}
+	}
+	s.logger.Info("FIX logout completed")
+	return errDisconnect
+}
+
+func (s *Session) initiateLogout(ctx context.Context, text string) {
+	m := NewMessage(MsgTypeLogout)
+	m.Body.Set(TagText, text)
+	if err := s.Send(ctx, m); err != nil {
+		s.logger.Warn("failed to send FIX Logout", zap.Error(err))
+		return
+	}
+	s.logoutSent = true
+	s.logger.Info("FIX logout initiated", zap.String("reason", text))
+}
+
+// awaitLogoutAck waits briefly for the counterparty's Logout before the
+// connection is closed.
+func (s *Session) awaitLogoutAck(inbound <-chan *Message) {
+	deadline := time.After(logoutAckGracePeriod)
+	for {
+		select {
+		case m := <-inbound:
+			if m.MsgType() == MsgTypeLogout {
+				return
+			}
+		case <-deadline:
+			return
+		}
+	}
+}
+
+func (s *Session) sendResendRequest(ctx context.Context, begin int) error {
+	s.logger.Info("FIX sequence gap detected, requesting resend", zap.Int("begin", begin))
+	m := NewMessage(MsgTypeResendRequest)
+	m.Body.SetInt(TagBeginSeqNo, begin)
+	m.Body.SetInt(TagEndSeqNo, 0)
+	return s.Send(ctx, m)
+}
+
+func (s *Session) sendReject(ctx context.Context, ref *Message, reason int, text string) error {
+	m := NewMessage(MsgTypeReject)
+	m.Body.SetInt(TagRefSeqNum, ref.MsgSeqNum())
+	m.Body.Set(TagRefMsgType, ref.MsgType())
+	m.Body.SetInt(TagSessionRejectReason, reason)
+	m.Body.Set(TagText, text)
+	return s.Send(ctx, m)
+}
+
+func rejectReasonFor(err error) int {
+	switch {
+	case errors.Is(err, ErrRequiredTagMissing):
+		return rejectReasonRequiredTagMissing
+	case errors.Is(err, ErrIncorrectDataFormat):
+		return rejectReasonIncorrectDataFormat
+	case errors.Is(err, ErrIncorrectNumInGroup):
+		return rejectReasonIncorrectNumInGroup
+	case errors.Is(err, ErrUnsupportedMsgType):
+		return rejectReasonInvalidMsgType
+	default:
+		return rejectReasonOther
+	}
+}
+
+// readLoop decodes messages from the connection until it fails or done is
+// closed. Garbled messages are dropped, as the session protocol requires.
+func readLoop(conn net.Conn, logger *zap.Logger, inbound chan<- *Message, readErr chan<- error, done <-chan struct{}) {
+	r := bufio.NewReader(conn)
+	for {
+		raw, err := ReadMessage(r)
+		if err != nil {
+			readErr <- err
+			return
+		}
+		m, err := Decode(raw)
+		if err != nil {
+			logger.Warn("dropping garbled FIX message", zap.Error(err))
+			continue
+		}
+		select {
+		case inbound <- m:
+		case <-done:
+			return
+		}
+	}
+}
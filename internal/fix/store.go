@@ -0,0 +1,133 @@
+package fix
+
+import (
+	"context"
+	"sync"
+)
+
+// MessageStore holds the sequence number state and outbound message journal
+// of a single FIX session. Getters return cached values; mutators persist the
+// change before returning.
+type MessageStore interface {
+	NextSenderMsgSeqNum() int
+	NextTargetMsgSeqNum() int
+	SetNextSenderMsgSeqNum(ctx context.Context, next int) error
+	SetNextTargetMsgSeqNum(ctx context.Context, next int) error
+	IncrNextSenderMsgSeqNum(ctx context.Context) error
+	IncrNextTargetMsgSeqNum(ctx context.Context) error
+	// SaveMessage journals an encoded outbound message under its sequence number.
+	SaveMessage(ctx context.Context, seqNum int, msg []byte) error
+	// GetMessages returns the journaled messages in [beginSeqNo, endSeqNo], in order.
+	GetMessages(ctx context.Context, beginSeqNo, endSeqNo int) ([][]byte, error)
+	// Reset sets both sequence numbers back to 1 and clears the journal.
+	Reset(ctx context.Context) error
+}
+
+// MessageStoreFactory creates (or reloads) the store for a session.
+type MessageStoreFactory interface {
+	Create(ctx context.Context, sessionID SessionID) (MessageStore, error)
+}
+
+// memoryStore is a MessageStore that lives only as long as the process.
+type memoryStore struct {
+	mu         sync.Mutex
+	nextSender int
+	nextTarget int
+	journal    map[int][]byte
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{nextSender: 1, nextTarget: 1, journal: make(map[int][]byte)}
+}
+
+func (s *memoryStore) NextSenderMsgSeqNum() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.nextSender
+}
+
+func (s *memoryStore) NextTargetMsgSeqNum() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.nextTarget
+}
+
+func (s *memoryStore) SetNextSenderMsgSeqNum(ctx context.Context, next int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.nextSender = next
+	return nil
+}
+
+func (s *memoryStore) SetNextTargetMsgSeqNum(ctx context.Context, next int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.nextTarget = next
+	return nil
+}
+
+func (s *memoryStore) IncrNextSenderMsgSeqNum(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.nextSender++
+	return nil
+}
+
+func (s *memoryStore) IncrNextTargetMsgSeqNum(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.nextTarget++
+	return nil
+}
+
+func (s *memoryStore) SaveMessage(ctx context.Context, seqNum int, msg []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.journal[seqNum] = append([]byte(nil), msg...)
+	return nil
+}
+
+func (s *memoryStore) GetMessages(ctx context.Context, beginSeqNo, endSeqNo int) ([][]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var msgs [][]byte
+	for seq := beginSeqNo; seq <= endSeqNo; seq++ {
+		if msg, ok := s.journal[seq]; ok {
+			msgs = append(msgs, msg)
+		}
+	}
+	return msgs, nil
+}
+
+func (s *memoryStore) Reset(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.nextSender = 1
+	s.nextTarget = 1
+	s.journal = make(map[int][]byte)
+	return nil
+}
+
+// MemoryStoreFactory hands out in-memory stores, one per session, so sequence
+// numbers survive reconnects but not process restarts.
+type MemoryStoreFactory struct {
+	mu     sync.Mutex
+	stores map[SessionID]*memoryStore
+}
+
+// NewMemoryStoreFactory creates a new MemoryStoreFactory.
+func NewMemoryStoreFactory() *MemoryStoreFactory {
+	return &MemoryStoreFactory{stores: make(map[SessionID]*memoryStore)}
+}
+
+// Create returns the store for sessionID, creating it on first use.
+func (f *MemoryStoreFactory) Create(ctx context.Context, sessionID SessionID) (MessageStore, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	store, ok := f.stores[sessionID]
+	if !ok {
+		store = newMemoryStore()
+		f.stores[sessionID] = store
+	}
+	return store, nil
+}
@@ -0,0 +1,155 @@
+package fix
+
+// BeginString is the only protocol version spoken by this engine.
+const BeginString = "FIX.4.4"
+
+// SOH is the field delimiter of the FIX tag=value encoding.
+const SOH = '\x01'
+
+// Standard header, trailer and application tags used by the engine.
+const (
+	TagAccount             = 1
+	TagAvgPx               = 6
+	TagBeginSeqNo          = 7
+	TagBeginString         = 8
+	TagBodyLength          = 9
+	TagCheckSum            = 10
+	TagClOrdID             = 11
+	TagCumQty              = 14
+	TagEndSeqNo            = 16
+	TagExecID              = 17
+	TagSecurityIDSource    = 22
+	TagLastPx              = 31
+	TagLastQty             = 32
+	TagMsgSeqNum           = 34
+	TagMsgType             = 35
+	TagNewSeqNo            = 36
+	TagOrderID             = 37
+	TagOrderQty            = 38
+	TagOrdStatus           = 39
+	TagOrdType             = 40
+	TagOrigClOrdID         = 41
+	TagPossDupFlag         = 43
+	TagPrice               = 44
+	TagRefSeqNum           = 45
+	TagSecurityID          = 48
+	TagSenderCompID        = 49
+	TagSendingTime         = 52
+	TagSide                = 54
+	TagSymbol              = 55
+	TagTargetCompID        = 56
+	TagText                = 58
+	TagTimeInForce         = 59
+	TagTransactTime        = 60
+	TagPositionEffect      = 77
+	TagPossResend          = 97
+	TagEncryptMethod       = 98
+	TagExDestination       = 100
+	TagCxlRejReason        = 102
+	TagOrdRejReason        = 103
+	TagHeartBtInt          = 108
+	TagTestReqID           = 112
+	TagOrigSendingTime     = 122
+	TagGapFillFlag         = 123
+	TagExpireTime          = 126
+	TagResetSeqNumFlag     = 141
+	TagExecType            = 150
+	TagLeavesQty           = 151
+	TagRefTagID            = 371
+	TagRefMsgType          = 372
+	TagSessionRejectReason = 373
+	TagCxlRejResponseTo    = 434
+	TagPartyIDSource       = 447
+	TagPartyID             = 448
+	TagPartyRole           = 452
+	TagNoPartyIDs          = 453
+)
+
+// MsgType values (tag 35).
+const (
+	MsgTypeHeartbeat          = "0"
+	MsgTypeTestRequest        = "1"
+	MsgTypeResendRequest      = "2"
+	MsgTypeReject             = "3"
+	MsgTypeSequenceReset      = "4"
+	MsgTypeLogout             = "5"
+	MsgTypeExecutionReport    = "8"
+	MsgTypeOrderCancelReject  = "9"
+	MsgTypeLogon              = "A"
+	MsgTypeNewOrderSingle     = "D"
+	MsgTypeOrderCancelRequest = "F"
+)
+
+// Side values (tag 54).
+const (
+	SideBuy             = "1"
+	SideSell            = "2"
+	SideSellShort       = "5"
+	SideSellShortExempt = "6"
+)
+
+// PositionEffect values (tag 77). A Buy that closes a short position is a cover.
+const (
+	PositionEffectOpen  = "O"
+	PositionEffectClose = "C"
+)
+
+// OrdType values (tag 40).
+const (
+	OrdTypeMarket = "1"
+	OrdTypeLimit  = "2"
+)
+
+// TimeInForce values (tag 59).
+const (
+	TimeInForceDay               = "0"
+	TimeInForceGoodTillCancel    = "1"
+	TimeInForceImmediateOrCancel = "3"
+	TimeInForceFillOrKill        = "4"
+	TimeInForceGoodTillDate      = "6"
+)
+
+// OrdStatus values (tag 39).
+const (
+	OrdStatusNew             = "0"
+	OrdStatusPartiallyFilled = "1"
+	OrdStatusFilled          = "2"
+	OrdStatusCanceled        = "4"
+	OrdStatusPendingCancel   = "6"
+	OrdStatusRejected        = "8"
+	OrdStatusExpired         = "C"
+	OrdStatusPendingReplace  = "E"
+)
+
+// ExecType values (tag 150).
+const (
+	ExecTypeNew            = "0"
+	ExecTypeCanceled       = "4"
+	ExecTypeReplaced       = "5"
+	ExecTypePendingCancel  = "6"
+	ExecTypeRejected       = "8"
+	ExecTypeExpired        = "C"
+	ExecTypePendingReplace = "E"
+	ExecTypeTrade          = "F"
+)
+
+// CxlRejResponseTo values (tag 434).
+const (
+	CxlRejResponseToCancel  = "1"
+	CxlRejResponseToReplace = "2"
+)
+
+// headerTags lists the tags that belong to the standard header. Decode uses it
+// to split fields between Header and Body.
+var headerTags = map[int]bool{
+	TagBeginString:     true,
+	TagBodyLength:      true,
+	TagMsgType:         true,
+	TagSenderCompID:    true,
+	TagTargetCompID:    true,
+	TagMsgSeqNum:       true,
+	TagPossDupFlag:     true,
+	TagPossResend:      true,
+	TagSendingTime:     true,
+	TagOrigSendingTime: true,
+}
@@ -0,0 +1,256 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kasbench/globeco-fix-engine/internal/config"
+	"github.com/segmentio/kafka-go"
+	"go.uber.org/zap"
+)
+
+// Headers added to dead-lettered messages. They are stripped again on re-drive.
+const (
+	dlqHeaderPrefix            = "dlq-"
+	DLQHeaderOriginalTopic     = dlqHeaderPrefix + "original-topic"
+	DLQHeaderOriginalPartition = dlqHeaderPrefix + "original-partition"
+	DLQHeaderOriginalOffset    = dlqHeaderPrefix + "original-offset"
+	DLQHeaderReasonCode        = dlqHeaderPrefix + "reason-code"
+	DLQHeaderReason            = dlqHeaderPrefix + "reason"
+	DLQHeaderFailedAt          = dlqHeaderPrefix + "failed-at"
+)
+
+const (
+	// redriveJoinTimeout bounds the first fetch of a re-drive, which includes joining the group.
+	redriveJoinTimeout = 30 * time.Second
+	// redriveIdleTimeout ends a re-drive once the DLQ has been drained.
+	redriveIdleTimeout = 5 * time.Second
+)
+
+// DeadLetterQueue writes order, cancel and amend messages that failed
+// permanently to the DLQ topic, keeping the original key, value and headers,
+// and re-drives them back onto the topic they came from.
+type DeadLetterQueue struct {
+	cfg     config.KafkaConfig
+	groupID string
+	writer  *kafka.Writer
+	logger  *zap.Logger
+	mu      sync.Mutex // serialises re-drives
+}
+
+// NewDeadLetterQueue creates a DeadLetterQueue for cfg.DLQTopic. Re-drives
+// consume the DLQ with their own consumer group derived from groupID.
+func NewDeadLetterQueue(cfg config.KafkaConfig, groupID string, logger *zap.Logger) *DeadLetterQueue {
+	logger.Info("Creating Kafka dead-letter producer", zap.String("topic", cfg.DLQTopic))
+	return &DeadLetterQueue{
+		cfg:     cfg,
+		groupID: groupID + "_dlq_redrive",
+		writer: kafka.NewWriter(kafka.WriterConfig{
+			Brokers:  cfg.Brokers,
+			Topic:    cfg.DLQTopic,
+			Balancer: &kafka.Hash{},
+		}),
+		logger: logger,
+	}
+}
+
+// Publish dead-letters m with the reason it could not be processed.
+func (q *DeadLetterQueue) Publish(ctx context.Context, m kafka.Message, reasonCode, reason string) error {
+	headers := make([]kafka.Header, 0, len(m.Headers)+6)
+	headers = append(headers, m.Headers...)
+	headers = append(headers,
+		kafka.Header{Key: DLQHeaderOriginalTopic, Value: []byte(m.Topic)},
+		kafka.Header{Key: DLQHeaderOriginalPartition, Value: []byte(strconv.Itoa(m.Partition))},
+		kafka.Header{Key: DLQHeaderOriginalOffset, Value: []byte(strconv.FormatInt(m.Offset, 10))},
+		kafka.Header{Key: DLQHeaderReasonCode, Value: []byte(reasonCode)},
+		kafka.Header{Key: DLQHeaderReason, Value: []byte(reason)},
+		kafka.Header{Key: DLQHeaderFailedAt, Value: []byte(time.Now().UTC().Format(time.RFC3339Nano))},
+	)
+	return q.writer.WriteMessages(ctx, kafka.Message{Key: m.Key, Value: m.Value, Headers: headers})
+}
+
+// Redrive moves up to max dead-lettered messages (all of them if max <= 0)
+// back onto the topic they came from and returns how many were moved. Only
+// messages already in the DLQ when it starts are moved: a message that fails
+// again is dead-lettered behind them and left for the next re-drive. It also
+// stops once the DLQ has been idle for a few seconds.
+func (q *DeadLetterQueue) Redrive(ctx context.Context, max int) (int, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	bound, err := q.readRedriveBound(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("reading DLQ offsets: %w", err)
+	}
+
+	reader := kafka.NewReader(kafka.ReaderConfig{
+		Brokers:     q.cfg.Brokers,
+		GroupID:     q.groupID,
+		Topic:       q.cfg.DLQTopic,
+		MinBytes:    1,
+		MaxBytes:    10e6, // 10MB
+		MaxWait:     500 * time.Millisecond,
+		StartOffset: kafka.FirstOffset,
+	})
+	defer reader.Close()
+	writer := kafka.NewWriter(kafka.WriterConfig{
+		Brokers:  q.cfg.Brokers,
+		Balancer: &kafka.Hash{},
+	})
+	defer writer.Close()
+
+	moved := 0
+	timeout := redriveJoinTimeout
+	for !bound.reached() && (max <= 0 || moved < max) {
+		fetchCtx, cancel := context.WithTimeout(ctx, timeout)
+		m, err := reader.FetchMessage(fetchCtx)
+		cancel()
+		if err != nil {
+			if errors.Is(err, context.DeadlineExceeded) && ctx.Err() == nil {
+				break // drained
+			}
+			return moved, err
+		}
+		timeout = redriveIdleTimeout
+		if !bound.admit(m) {
+			continue
+		}
+
+		redriven := RedriveMessage(m)
+		redriven.Topic = q.redriveTopic(m)
+		if err := writer.WriteMessages(ctx, redriven); err != nil {
+			return moved, err
+		}
+		if err := reader.CommitMessages(ctx, m); err != nil {
+			return moved, err
+		}
+		moved++
+	}
+	q.logger.Info("Re-drove dead-lettered messages",
+		zap.String("dlq_topic", q.cfg.DLQTopic),
+		zap.Int("count", moved),
+	)
+	return moved, nil
+}
+
+// readRedriveBound reads the DLQ's high-water marks and the re-drive group's
+// committed offsets.
+func (q *DeadLetterQueue) readRedriveBound(ctx context.Context) (*redriveBound, error) {
+	client := &kafka.Client{Addr: kafka.TCP(q.cfg.Brokers...)}
+	meta, err := client.Metadata(ctx, &kafka.MetadataRequest{Topics: []string{q.cfg.DLQTopic}})
+	if err != nil {
+		return nil, err
+	}
+	var offsetReqs []kafka.OffsetRequest
+	var partitions []int
+	for _, topic := range meta.Topics {
+		if topic.Name != q.cfg.DLQTopic {
+			continue
+		}
+		if topic.Error != nil {
+			return nil, topic.Error
+		}
+		for _, p := range topic.Partitions {
+			offsetReqs = append(offsetReqs, kafka.FirstOffsetOf(p.ID), kafka.LastOffsetOf(p.ID))
+			partitions = append(partitions, p.ID)
+		}
+	}
+
+	offsets, err := client.ListOffsets(ctx, &kafka.ListOffsetsRequest{Topics: map[string][]kafka.OffsetRequest{q.cfg.DLQTopic: offsetReqs}})
+	if err != nil {
+		return nil, err
+	}
+	highWater := make(map[int]int64, len(partitions))
+	start := make(map[int]int64, len(partitions))
+	for _, p := range offsets.Topics[q.cfg.DLQTopic] {
+		if p.Error != nil {
+			return nil, p.Error
+		}
+		highWater[p.Partition] = p.LastOffset
+		start[p.Partition] = p.FirstOffset
+	}
+
+	committed, err := client.OffsetFetch(ctx, &kafka.OffsetFetchRequest{GroupID: q.groupID, Topics: map[string][]int{q.cfg.DLQTopic: partitions}})
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range committed.Topics[q.cfg.DLQTopic] {
+		if p.Error != nil {
+			return nil, p.Error
+		}
+		start[p.Partition] = max(start[p.Partition], p.CommittedOffset)
+	}
+	return newRedriveBound(highWater, start), nil
+}
+
+// redriveBound holds a re-drive to the messages that were in the DLQ when it
+// started.
+type redriveBound struct {
+	highWater map[int]int64 // per partition, the offset after the last message at start
+	done      map[int]bool
+}
+
+// newRedriveBound bounds a re-drive at highWater, given the offset each
+// partition will be read from.
+func newRedriveBound(highWater, start map[int]int64) *redriveBound {
+	b := &redriveBound{highWater: highWater, done: make(map[int]bool)}
+	for p, hw := range highWater {
+		if start[p] >= hw {
+			b.done[p] = true
+		}
+	}
+	return b
+}
+
+// admit reports whether m was in the DLQ when the re-drive started.
+func (b *redriveBound) admit(m kafka.Message) bool {
+	hw, ok := b.highWater[m.Partition]
+	if !ok {
+		return false
+	}
+	if m.Offset+1 >= hw {
+		b.done[m.Partition] = true
+	}
+	return m.Offset < hw
+}
+
+// reached reports whether every partition has been read up to its high-water mark.
+func (b *redriveBound) reached() bool {
+	return len(b.done) == len(b.highWater)
+}
+
+// redriveTopic returns the topic a dead-lettered message came from. Messages
+// without a recognised original topic go to the orders topic.
+func (q *DeadLetterQueue) redriveTopic(m kafka.Message) string {
+	for _, h := range m.Headers {
+		if h.Key != DLQHeaderOriginalTopic {
+			continue
+		}
+		switch topic := string(h.Value); topic {
+		case q.cfg.OrdersTopic, q.cfg.CancelsTopic, q.cfg.AmendsTopic:
+			return topic
+		}
+	}
+	return q.cfg.OrdersTopic
+}
+
+// Close flushes and closes the DLQ producer.
+func (q *DeadLetterQueue) Close() error {
+	return q.writer.Close()
+}
+
+// RedriveMessage rebuilds the original message from a dead-lettered one.
+func RedriveMessage(m kafka.Message) kafka.Message {
+	var headers []kafka.Header
+	for _, h := range m.Headers {
+		if !strings.HasPrefix(h.Key, dlqHeaderPrefix) {
+			headers = append(headers, h)
+		}
+	}
+	return kafka.Message{Key: m.Key, Value: m.Value, Headers: headers}
+}
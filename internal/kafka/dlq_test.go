@@ -0,0 +1,59 @@
+package kafka
+
+import (
+	"testing"
+
+	"github.com/kasbench/globeco-fix-engine/internal/config"
+	segmentio_kafka "github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedriveMessage_StripsDLQHeaders(t *testing.T) {
+	dead := segmentio_kafka.Message{
+		Topic: "orders_dlq",
+		Key:   []byte("42"),
+		Value: []byte(`{"id":42}`),
+		Headers: []segmentio_kafka.Header{
+			{Key: "traceparent", Value: []byte("00-abc-def-01")},
+			{Key: DLQHeaderOriginalTopic, Value: []byte("orders")},
+			{Key: DLQHeaderReasonCode, Value: []byte("SECURITY_LOOKUP")},
+		},
+	}
+	m := RedriveMessage(dead)
+	assert.Equal(t, dead.Key, m.Key)
+	assert.Equal(t, dead.Value, m.Value)
+	assert.Empty(t, m.Topic)
+	assert.Equal(t, []segmentio_kafka.Header{{Key: "traceparent", Value: []byte("00-abc-def-01")}}, m.Headers)
+}
+
+func TestRedriveTopic_ReturnsOriginalTopic(t *testing.T) {
+	q := &DeadLetterQueue{cfg: config.KafkaConfig{OrdersTopic: "orders", CancelsTopic: "cancels", AmendsTopic: "amends"}}
+	dead := func(headers ...segmentio_kafka.Header) segmentio_kafka.Message {
+		return segmentio_kafka.Message{Topic: "orders_dlq", Headers: headers}
+	}
+	assert.Equal(t, "cancels", q.redriveTopic(dead(segmentio_kafka.Header{Key: DLQHeaderOriginalTopic, Value: []byte("cancels")})))
+	assert.Equal(t, "amends", q.redriveTopic(dead(segmentio_kafka.Header{Key: DLQHeaderOriginalTopic, Value: []byte("amends")})))
+	assert.Equal(t, "orders", q.redriveTopic(dead(segmentio_kafka.Header{Key: DLQHeaderOriginalTopic, Value: []byte("orders")})))
+	// Unknown or missing original topics fall back to the orders topic
+	assert.Equal(t, "orders", q.redriveTopic(dead(segmentio_kafka.Header{Key: DLQHeaderOriginalTopic, Value: []byte("fills")})))
+	assert.Equal(t, "orders", q.redriveTopic(dead()))
+}
+
+func TestRedriveBound_StopsAtHighWaterMarks(t *testing.T) {
+	// Partition 0 holds offsets 3..5 to re-drive, partition 1 was drained by an earlier re-drive.
+	b := newRedriveBound(map[int]int64{0: 6, 1: 4}, map[int]int64{0: 3, 1: 4})
+	at := func(partition int, offset int64) segmentio_kafka.Message {
+		return segmentio_kafka.Message{Partition: partition, Offset: offset}
+	}
+	assert.False(t, b.reached())
+	assert.True(t, b.admit(at(0, 3)))
+	assert.True(t, b.admit(at(0, 4)))
+	assert.False(t, b.reached())
+	assert.True(t, b.admit(at(0, 5)))
+	assert.True(t, b.reached())
+
+	// Messages dead-lettered again during the re-drive wait for the next one.
+	assert.False(t, b.admit(at(0, 6)))
+	assert.False(t, b.admit(at(1, 4)))
+	assert.False(t, b.admit(at(2, 0)))
+}
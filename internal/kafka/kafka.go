@@ -54,18 +54,35 @@ func CreateFillsTopicIfNotExists(ctx context.Context, cfg config.KafkaConfig, lo
 // NewOrdersConsumer creates a Kafka reader for the orders topic.
 // Uses extended session and rebalance timeouts to tolerate slow broker stabilization at startup.
 func NewOrdersConsumer(cfg config.KafkaConfig, groupID string, logger *zap.Logger) *kafka.Reader {
-	logger.Info("Creating Kafka orders consumer",
-		zap.Strings("brokers", cfg.Brokers),
-		zap.String("topic", cfg.OrdersTopic),
+	return newConsumer(cfg.Brokers, cfg.OrdersTopic, groupID, "orders", logger)
+}
+
+// NewCancelsConsumer creates a Kafka reader for the cancels topic. It consumes
+// in its own group derived from groupID, so its offsets are committed
+// independently of the orders consumer's.
+func NewCancelsConsumer(cfg config.KafkaConfig, groupID string, logger *zap.Logger) *kafka.Reader {
+	return newConsumer(cfg.Brokers, cfg.CancelsTopic, groupID+"_cancels", "cancels", logger)
+}
+
+// NewAmendsConsumer creates a Kafka reader for the amends topic, in its own
+// consumer group derived from groupID like NewCancelsConsumer.
+func NewAmendsConsumer(cfg config.KafkaConfig, groupID string, logger *zap.Logger) *kafka.Reader {
+	return newConsumer(cfg.Brokers, cfg.AmendsTopic, groupID+"_amends", "amends", logger)
+}
+
+func newConsumer(brokers []string, topic, groupID, name string, logger *zap.Logger) *kafka.Reader {
+	logger.Info("Creating Kafka "+name+" consumer",
+		zap.Strings("brokers", brokers),
+		zap.String("topic", topic),
 		zap.String("groupID", groupID),
 		zap.Duration("sessionTimeout", 60*time.Second),
 		zap.Duration("rebalanceTimeout", 90*time.Second),
 		zap.Duration("heartbeatInterval", 10*time.Second),
 	)
 	reader := kafka.NewReader(kafka.ReaderConfig{
-		Brokers:           cfg.Brokers,
+		Brokers:           brokers,
 		GroupID:           groupID,
-		Topic:             cfg.OrdersTopic,
+		Topic:             topic,
 		MinBytes:          10e3, // 10KB
 		MaxBytes:          10e6, // 10MB
 		MaxWait:           500 * time.Millisecond,
@@ -74,8 +91,8 @@ func NewOrdersConsumer(cfg config.KafkaConfig, groupID string, logger *zap.Logge
 		RebalanceTimeout:  90 * time.Second,  // give broker extra time to complete rebalances
 		HeartbeatInterval: 10 * time.Second,  // more frequent heartbeats to maintain session
 	})
-	logger.Info("Kafka orders consumer created successfully",
-		zap.String("topic", cfg.OrdersTopic),
+	logger.Info("Kafka "+name+" consumer created successfully",
+		zap.String("topic", topic),
 		zap.String("groupID", groupID),
 	)
 	return reader
@@ -131,6 +148,21 @@ func WaitForPartitionAssignment(ctx context.Context, reader *kafka.Reader, logge
 	}
 }
 
+// NewRejectsProducer creates a Kafka writer for rejected orders. They go to the
+// rejects topic if one is configured and to the fills topic otherwise.
+func NewRejectsProducer(cfg config.KafkaConfig, logger *zap.Logger) *kafka.Writer {
+	topic := cfg.RejectsTopic
+	if topic == "" {
+		topic = cfg.FillsTopic
+	}
+	logger.Info("Creating Kafka rejects producer", zap.String("topic", topic))
+	return kafka.NewWriter(kafka.WriterConfig{
+		Brokers:  cfg.Brokers,
+		Topic:    topic,
+		Balancer: &kafka.Hash{},
+	})
+}
+
 // NewFillsProducer creates a Kafka writer for the fills topic.
 func NewFillsProducer(cfg config.KafkaConfig, logger *zap.Logger) *kafka.Writer {
 	logger.Info("Creating Kafka fills producer",
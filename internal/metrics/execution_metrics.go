@@ -0,0 +1,42 @@
+package metrics
+
+import (
+	"context"
+	"fmt"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// ExecutionMetrics holds the execution state machine metric instruments.
+type ExecutionMetrics struct {
+	invalidTransitions metric.Float64Counter
+
+	commonAttrs []attribute.KeyValue
+}
+
+// NewExecutionMetrics creates and registers the execution metric instruments.
+func NewExecutionMetrics(meter metric.Meter) (*ExecutionMetrics, error) {
+	invalidTransitions, err := meter.Float64Counter(
+		"execution_invalid_transitions_total",
+		metric.WithUnit("{transition}"),
+		metric.WithDescription("Total number of execution status transitions refused by the order state machine"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("creating invalid_transitions counter: %w", err)
+	}
+	return &ExecutionMetrics{
+		invalidTransitions: invalidTransitions,
+		commonAttrs:        []attribute.KeyValue{attribute.String("service", "globeco-fix-engine")},
+	}, nil
+}
+
+// RecordInvalidTransition counts a refused transition from status from to status to.
+func (m *ExecutionMetrics) RecordInvalidTransition(ctx context.Context, from, to string) {
+	defer func() { recover() }()
+
+	attrs := make([]attribute.KeyValue, 0, len(m.commonAttrs)+2)
+	attrs = append(attrs, m.commonAttrs...)
+	attrs = append(attrs, attribute.String("from", from), attribute.String("to", to))
+	m.invalidTransitions.Add(ctx, 1, metric.WithAttributes(attrs...))
+}
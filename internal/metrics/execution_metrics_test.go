@@ -0,0 +1,36 @@
+package metrics
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+func TestExecutionMetrics_RecordInvalidTransition(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	provider := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+	defer provider.Shutdown(context.Background())
+
+	em, err := NewExecutionMetrics(provider.Meter("test"))
+	require.NoError(t, err)
+	em.RecordInvalidTransition(context.Background(), "FULL", "PART")
+	em.RecordInvalidTransition(context.Background(), "FULL", "PART")
+
+	var rm metricdata.ResourceMetrics
+	require.NoError(t, reader.Collect(context.Background(), &rm))
+	require.Len(t, rm.ScopeMetrics, 1)
+	require.Len(t, rm.ScopeMetrics[0].Metrics, 1)
+	m := rm.ScopeMetrics[0].Metrics[0]
+	assert.Equal(t, "execution_invalid_transitions_total", m.Name)
+	sum, ok := m.Data.(metricdata.Sum[float64])
+	require.True(t, ok)
+	require.Len(t, sum.DataPoints, 1)
+	assert.Equal(t, 2.0, sum.DataPoints[0].Value)
+	from, _ := sum.DataPoints[0].Attributes.Value(attribute.Key("from"))
+	assert.Equal(t, "FULL", from.AsString())
+}
@@ -3,62 +3,128 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"errors"
+	"fmt"
 	"time"
 
 	"github.com/jmoiron/sqlx"
+	"github.com/shopspring/decimal"
 )
 
+// ErrDuplicateExecution is returned by Create when an execution already exists
+// for the execution_service_id, or for a FIX order the session and ClOrdID;
+// the existing row is left untouched.
+var ErrDuplicateExecution = errors.New("duplicate execution")
+
+// ErrVersionConflict matches any *VersionConflictError with errors.Is.
+var ErrVersionConflict = errors.New("execution version conflict")
+
+// VersionConflictError is returned by Update when the execution was modified
+// by another writer after it was read.
+type VersionConflictError struct {
+	ID      int // execution id
+	Version int // the version the caller expected
+}
+
+func (e *VersionConflictError) Error() string {
+	return fmt.Sprintf("%v: execution %d is no longer at version %d", ErrVersionConflict, e.ID, e.Version)
+}
+
+func (e *VersionConflictError) Is(target error) bool {
+	return target == ErrVersionConflict
+}
+
 // Execution represents a row in the execution table.
 type Execution struct {
-	ID                      int             `db:"id"`
-	ExecutionServiceID      int             `db:"execution_service_id"`
-	IsOpen                  bool            `db:"is_open"`
-	ExecutionStatus         string          `db:"execution_status"`
-	TradeType               string          `db:"trade_type"`
-	Destination             string          `db:"destination"`
-	SecurityID              string          `db:"security_id"`
-	Ticker                  string          `db:"ticker"`
-	QuantityOrdered         float64         `db:"quantity_ordered"`
-	LimitPrice              sql.NullFloat64 `db:"limit_price"`
-	ReceivedTimestamp       time.Time       `db:"received_timestamp"`
-	SentTimestamp           time.Time       `db:"sent_timestamp"`
-	LastFillTimestamp       sql.NullTime    `db:"last_fill_timestamp"`
-	QuantityFilled          float64         `db:"quantity_filled"`
-	NextFillTimestamp       sql.NullTime    `db:"next_fill_timestamp"`
-	NumberOfFills           int16           `db:"number_of_fills"`
-	TotalAmount             float64         `db:"total_amount"`
-	TradeServiceExecutionID sql.NullInt64   `db:"trade_service_execution_id"`
-	Version                 int             `db:"version"`
+	ID                      int                 `db:"id"`
+	ExecutionServiceID      int                 `db:"execution_service_id"`
+	IsOpen                  bool                `db:"is_open"`
+	ExecutionStatus         string              `db:"execution_status"`
+	TradeType               string              `db:"trade_type"`
+	Destination             string              `db:"destination"`
+	SecurityID              string              `db:"security_id"`
+	Ticker                  string              `db:"ticker"`
+	QuantityOrdered         decimal.Decimal     `db:"quantity_ordered"`
+	LimitPrice              decimal.NullDecimal `db:"limit_price"`
+	ReceivedTimestamp       time.Time           `db:"received_timestamp"`
+	SentTimestamp           time.Time           `db:"sent_timestamp"`
+	LastFillTimestamp       sql.NullTime        `db:"last_fill_timestamp"`
+	QuantityFilled          decimal.Decimal     `db:"quantity_filled"`
+	NextFillTimestamp       sql.NullTime        `db:"next_fill_timestamp"`
+	NumberOfFills           int16               `db:"number_of_fills"`
+	TotalAmount             decimal.Decimal     `db:"total_amount"`
+	TradeServiceExecutionID sql.NullInt64       `db:"trade_service_execution_id"`
+	Version                 int                 `db:"version"`
+	FIXSessionID            sql.NullString      `db:"fix_session_id"`
+	ClOrdID                 sql.NullString      `db:"cl_ord_id"`
+	TimeInForce             string              `db:"time_in_force"`
+	ExpireTimestamp         sql.NullTime        `db:"expire_timestamp"`
 }
 
 // ExecutionRepository defines methods for interacting with the execution table.
 type ExecutionRepository interface {
 	Create(ctx context.Context, exec *Execution) error
 	GetByID(ctx context.Context, id int) (*Execution, error)
+	GetByExecutionServiceID(ctx context.Context, executionServiceID int) (*Execution, error)
+	GetByClOrdID(ctx context.Context, fixSessionID, clOrdID string) (*Execution, error)
 	List(ctx context.Context) ([]*Execution, error)
-	PollNextForFill(ctx context.Context) (*Execution, error)
+	PollNextForFill(ctx context.Context, now time.Time) (*Execution, error)
+	PollDueForExpiry(ctx context.Context, now time.Time) ([]*Execution, error)
 	Update(ctx context.Context, exec *Execution) error
+	CreateFill(ctx context.Context, fill *Fill) error
+	ListFills(ctx context.Context, executionID int) ([]*Fill, error)
+	// EnqueueOutbox adds msg to the outbox; inside WithinTx it is only relayed
+	// if the transaction commits.
+	EnqueueOutbox(ctx context.Context, msg *OutboxMessage) error
+	PendingOutbox(ctx context.Context, limit int) ([]*OutboxMessage, error)
+	PendingFIXOutbox(ctx context.Context, fixSessionID string, limit int) ([]*OutboxMessage, error)
+	MarkOutboxSent(ctx context.Context, ids []int64, sent time.Time) error
+	PruneOutbox(ctx context.Context, before time.Time) (int64, error)
+	// WithinTx runs fn with a repository bound to a single transaction, which is
+	// committed if fn returns nil and rolled back otherwise. Row locks taken by
+	// PollNextForFill and PollDueForExpiry are held until fn returns. Nested calls join the outer transaction.
+	WithinTx(ctx context.Context, fn func(ctx context.Context, repo ExecutionRepository) error) error
 }
 
 type executionRepository struct {
-	db *sqlx.DB
+	db sqlx.ExtContext // *sqlx.DB, or *sqlx.Tx inside WithinTx
 }
 
 func NewExecutionRepository(db *sqlx.DB) ExecutionRepository {
 	return &executionRepository{db: db}
 }
 
+func (r *executionRepository) WithinTx(ctx context.Context, fn func(ctx context.Context, repo ExecutionRepository) error) error {
+	db, ok := r.db.(*sqlx.DB)
+	if !ok {
+		return fn(ctx, r) // already in a transaction
+	}
+	tx, err := db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if err := fn(ctx, &executionRepository{db: tx}); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
 func (r *executionRepository) Create(ctx context.Context, exec *Execution) error {
 	query := `INSERT INTO execution (
 		execution_service_id, is_open, execution_status, trade_type, destination, security_id, ticker,
 		quantity_ordered, limit_price, received_timestamp, sent_timestamp, last_fill_timestamp,
-		quantity_filled, next_fill_timestamp, number_of_fills, total_amount, trade_service_execution_id, version
+		quantity_filled, next_fill_timestamp, number_of_fills, total_amount, trade_service_execution_id, version,
+		fix_session_id, cl_ord_id, time_in_force, expire_timestamp
 	) VALUES (
 		:execution_service_id, :is_open, :execution_status, :trade_type, :destination, :security_id, :ticker,
 		:quantity_ordered, :limit_price, :received_timestamp, :sent_timestamp, :last_fill_timestamp,
-		:quantity_filled, :next_fill_timestamp, :number_of_fills, :total_amount, :trade_service_execution_id, :version
-	) RETURNING id`
-	rows, err := r.db.NamedQueryContext(ctx, query, exec)
+		:quantity_filled, :next_fill_timestamp, :number_of_fills, :total_amount, :trade_service_execution_id, :version,
+		:fix_session_id, :cl_ord_id, :time_in_force, :expire_timestamp
+	)
+	ON CONFLICT DO NOTHING
+	RETURNING id`
+	rows, err := sqlx.NamedQueryContext(ctx, r.db, query, exec)
 	if err != nil {
 		return err
 	}
@@ -66,13 +132,39 @@ func (r *executionRepository) Create(ctx context.Context, exec *Execution) error
 	if rows.Next() {
 		return rows.Scan(&exec.ID)
 	}
-	return sql.ErrNoRows
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	return ErrDuplicateExecution // ON CONFLICT DO NOTHING inserted no row
 }
 
 func (r *executionRepository) GetByID(ctx context.Context, id int) (*Execution, error) {
 	var exec Execution
 	query := `SELECT * FROM execution WHERE id = $1`
-	err := r.db.GetContext(ctx, &exec, query, id)
+	err := sqlx.GetContext(ctx, r.db, &exec, query, id)
+	if err != nil {
+		return nil, err
+	}
+	return &exec, nil
+}
+
+// GetByExecutionServiceID returns the Kafka order for executionServiceID.
+// FIX orders have no execution_service_id and are found with GetByClOrdID.
+func (r *executionRepository) GetByExecutionServiceID(ctx context.Context, executionServiceID int) (*Execution, error) {
+	var exec Execution
+	query := `SELECT * FROM execution WHERE execution_service_id = $1 AND fix_session_id IS NULL`
+	err := sqlx.GetContext(ctx, r.db, &exec, query, executionServiceID)
+	if err != nil {
+		return nil, err
+	}
+	return &exec, nil
+}
+
+// GetByClOrdID returns the order placed with clOrdID on the FIX session fixSessionID.
+func (r *executionRepository) GetByClOrdID(ctx context.Context, fixSessionID, clOrdID string) (*Execution, error) {
+	var exec Execution
+	query := `SELECT * FROM execution WHERE fix_session_id = $1 AND cl_ord_id = $2`
+	err := sqlx.GetContext(ctx, r.db, &exec, query, fixSessionID, clOrdID)
 	if err != nil {
 		return nil, err
 	}
@@ -82,28 +174,34 @@ func (r *executionRepository) GetByID(ctx context.Context, id int) (*Execution,
 func (r *executionRepository) List(ctx context.Context) ([]*Execution, error) {
 	var execs []*Execution
 	query := `SELECT * FROM execution`
-	err := r.db.SelectContext(ctx, &execs, query)
+	err := sqlx.SelectContext(ctx, r.db, &execs, query)
 	if err != nil {
 		return nil, err
 	}
 	return execs, nil
 }
 
-// PollNextForFill selects the next eligible execution for fill processing using FOR UPDATE SKIP LOCKED.
-func (r *executionRepository) PollNextForFill(ctx context.Context) (*Execution, error) {
+// PollNextForFill selects the execution that has been due for fill processing
+// longest at now using FOR UPDATE SKIP LOCKED.
+func (r *executionRepository) PollNextForFill(ctx context.Context, now time.Time) (*Execution, error) {
 	var exec Execution
 	query := `SELECT * FROM execution
-	WHERE next_fill_timestamp <= NOW()
+	WHERE next_fill_timestamp <= $1
 	  AND is_open
-	FOR UPDATE SKIP LOCKED
-	LIMIT 1`
-	err := r.db.GetContext(ctx, &exec, query)
+	  AND (expire_timestamp IS NULL OR expire_timestamp > $1)
+	ORDER BY next_fill_timestamp, id
+	LIMIT 1
+	FOR UPDATE SKIP LOCKED`
+	err := sqlx.GetContext(ctx, r.db, &exec, query, now)
 	if err != nil {
 		return nil, err
 	}
 	return &exec, nil
 }
 
+// Update writes exec back if its version is still exec.Version, and increments
+// the version in the database and on exec. Returns a *VersionConflictError if
+// the execution has been changed (or removed) since exec was read.
 func (r *executionRepository) Update(ctx context.Context, exec *Execution) error {
 	query := `UPDATE execution SET
 		execution_service_id = :execution_service_id,
@@ -123,8 +221,41 @@ func (r *executionRepository) Update(ctx context.Context, exec *Execution) error
 		number_of_fills = :number_of_fills,
 		total_amount = :total_amount,
 		trade_service_execution_id = :trade_service_execution_id,
-		version = :version
-	WHERE id = :id`
-	_, err := r.db.NamedExecContext(ctx, query, exec)
-	return err
+		version = version + 1,
+		fix_session_id = :fix_session_id,
+		cl_ord_id = :cl_ord_id,
+		time_in_force = :time_in_force,
+		expire_timestamp = :expire_timestamp
+	WHERE id = :id
+	  AND version = :version
+	RETURNING version`
+	rows, err := sqlx.NamedQueryContext(ctx, r.db, query, exec)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		return &VersionConflictError{ID: exec.ID, Version: exec.Version}
+	}
+	return rows.Scan(&exec.Version)
+}
+
+// PollDueForExpiry selects the open executions whose expire_timestamp is at or
+// before now using FOR UPDATE SKIP LOCKED. Executions locked by another
+// transaction are left for a later poll.
+func (r *executionRepository) PollDueForExpiry(ctx context.Context, now time.Time) ([]*Execution, error) {
+	var execs []*Execution
+	query := `SELECT * FROM execution
+	WHERE is_open
+	  AND expire_timestamp <= $1
+	ORDER BY expire_timestamp, id
+	FOR UPDATE SKIP LOCKED`
+	err := sqlx.SelectContext(ctx, r.db, &execs, query, now)
+	if err != nil {
+		return nil, err
+	}
+	return execs, nil
 }
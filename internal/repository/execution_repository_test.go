@@ -3,13 +3,18 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
+	"sort"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/jmoiron/sqlx"
 	_ "github.com/lib/pq"
+	"github.com/shopspring/decimal"
 	"github.com/stretchr/testify/assert"
 	"github.com/testcontainers/testcontainers-go"
 	"github.com/testcontainers/testcontainers-go/wait"
@@ -62,15 +67,23 @@ func setupTestDBWithContainer(t *testing.T) (*sqlx.DB, func()) {
 	}
 
 	// Apply schema
-	schema, err := os.ReadFile("../../migrations/0001_init.up.sql")
-	if err != nil {
+	migrations, err := filepath.Glob("../../migrations/*.up.sql")
+	if err != nil || len(migrations) == 0 {
 		container.Terminate(ctx)
-		t.Fatalf("failed to read schema: %v", err)
+		t.Fatalf("failed to find migrations: %v", err)
 	}
-	_, err = db.Exec(string(schema))
-	if err != nil {
-		container.Terminate(ctx)
-		t.Fatalf("failed to apply schema: %v", err)
+	sort.Strings(migrations)
+	for _, migration := range migrations {
+		schema, err := os.ReadFile(migration)
+		if err != nil {
+			container.Terminate(ctx)
+			t.Fatalf("failed to read schema: %v", err)
+		}
+		_, err = db.Exec(string(schema))
+		if err != nil {
+			container.Terminate(ctx)
+			t.Fatalf("failed to apply schema %s: %v", migration, err)
+		}
 	}
 
 	cleanup := func() {
@@ -94,15 +107,15 @@ func TestExecutionRepository_CreateAndGetByID(t *testing.T) {
 		Destination:        "DEST",
 		SecurityID:         "SECID123",
 		Ticker:             "AAPL",
-		QuantityOrdered:    100,
-		LimitPrice:         sql.NullFloat64{Float64: 150.0, Valid: true},
+		QuantityOrdered:    decimal.NewFromInt(100),
+		LimitPrice:         decimal.NewNullDecimal(decimal.RequireFromString("150.12345678")),
 		ReceivedTimestamp:  time.Now().UTC(),
 		SentTimestamp:      time.Now().UTC(),
 		LastFillTimestamp:  sql.NullTime{Valid: false},
-		QuantityFilled:     0,
+		QuantityFilled:     decimal.Zero,
 		NextFillTimestamp:  sql.NullTime{Valid: false},
 		NumberOfFills:      0,
-		TotalAmount:        0,
+		TotalAmount:        decimal.Zero,
 		Version:            1,
 	}
 	err := repo.Create(ctx, exec)
@@ -113,4 +126,393 @@ func TestExecutionRepository_CreateAndGetByID(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, exec.ExecutionServiceID, fetched.ExecutionServiceID)
 	assert.Equal(t, exec.Ticker, fetched.Ticker)
+	assert.Equal(t, "100", fetched.QuantityOrdered.String())
+	assert.Equal(t, "150.12345678", fetched.LimitPrice.Decimal.String(), "decimals round-trip exactly")
+}
+
+func TestExecutionRepository_PollDueForExpiry(t *testing.T) {
+	db, cleanup := setupTestDBWithContainer(t)
+	defer cleanup()
+	repo := NewExecutionRepository(db)
+	ctx := context.Background()
+	now := time.Now().UTC()
+	newExec := func(id int, expire sql.NullTime) *Execution {
+		return &Execution{
+			ExecutionServiceID: id,
+			IsOpen:             true,
+			ExecutionStatus:    "WORK",
+			TradeType:          "BUY",
+			Destination:        "DEST",
+			SecurityID:         "SECID123",
+			Ticker:             "AAPL",
+			QuantityOrdered:    decimal.NewFromInt(100),
+			ReceivedTimestamp:  now,
+			SentTimestamp:      now,
+			NextFillTimestamp:  sql.NullTime{Time: now.Add(-time.Second), Valid: true},
+			TimeInForce:        "GTD",
+			ExpireTimestamp:    expire,
+			Version:            1,
+		}
+	}
+	assert.NoError(t, repo.Create(ctx, newExec(1, sql.NullTime{Time: now.Add(-time.Minute), Valid: true})))
+	assert.NoError(t, repo.Create(ctx, newExec(2, sql.NullTime{Time: now.Add(time.Hour), Valid: true})))
+
+	due, err := repo.PollDueForExpiry(ctx, now)
+	assert.NoError(t, err)
+	assert.Len(t, due, 1)
+	assert.Equal(t, 1, due[0].ExecutionServiceID)
+
+	due[0].IsOpen = false
+	due[0].ExecutionStatus = "EXPD"
+	assert.NoError(t, repo.Update(ctx, due[0]))
+	due, err = repo.PollDueForExpiry(ctx, now)
+	assert.NoError(t, err)
+	assert.Empty(t, due)
+
+	next, err := repo.PollNextForFill(ctx, time.Now())
+	assert.NoError(t, err)
+	assert.Equal(t, 2, next.ExecutionServiceID)
+}
+
+func TestExecutionRepository_CreateDuplicate(t *testing.T) {
+	db, cleanup := setupTestDBWithContainer(t)
+	defer cleanup()
+	repo := NewExecutionRepository(db)
+	ctx := context.Background()
+	now := time.Now().UTC()
+	newExec := func() *Execution {
+		return &Execution{
+			ExecutionServiceID: 4242,
+			IsOpen:             true,
+			ExecutionStatus:    "WORK",
+			TradeType:          "BUY",
+			Destination:        "DEST",
+			SecurityID:         "SECID123",
+			Ticker:             "AAPL",
+			QuantityOrdered:    decimal.NewFromInt(100),
+			ReceivedTimestamp:  now,
+			SentTimestamp:      now,
+			TimeInForce:        "GTC",
+			Version:            1,
+		}
+	}
+	assert.NoError(t, repo.Create(ctx, newExec()))
+	assert.ErrorIs(t, repo.Create(ctx, newExec()), ErrDuplicateExecution)
+
+	// FIX orders are keyed on session and ClOrdID, apart from Kafka orders.
+	newFIXExec := func(session, clOrdID string) *Execution {
+		exec := newExec()
+		exec.ExecutionServiceID = 0
+		exec.FIXSessionID = sql.NullString{String: session, Valid: true}
+		exec.ClOrdID = sql.NullString{String: clOrdID, Valid: true}
+		return exec
+	}
+	assert.NoError(t, repo.Create(ctx, newFIXExec("FIX.4.4:GLOBECO->CLIENT", "ORD-1")))
+	assert.NoError(t, repo.Create(ctx, newFIXExec("FIX.4.4:GLOBECO->CLIENT", "ORD-2")))
+	assert.NoError(t, repo.Create(ctx, newFIXExec("FIX.4.4:GLOBECO->OTHER", "ORD-1")))
+	assert.ErrorIs(t, repo.Create(ctx, newFIXExec("FIX.4.4:GLOBECO->CLIENT", "ORD-1")), ErrDuplicateExecution)
+
+	fixExec, err := repo.GetByClOrdID(ctx, "FIX.4.4:GLOBECO->OTHER", "ORD-1")
+	assert.NoError(t, err)
+	assert.Equal(t, "ORD-1", fixExec.ClOrdID.String)
+	kafkaExec, err := repo.GetByExecutionServiceID(ctx, 4242)
+	assert.NoError(t, err)
+	assert.False(t, kafkaExec.FIXSessionID.Valid)
+	_, err = repo.GetByExecutionServiceID(ctx, 0)
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+}
+
+func TestExecutionRepository_StatusConstraints(t *testing.T) {
+	db, cleanup := setupTestDBWithContainer(t)
+	defer cleanup()
+	repo := NewExecutionRepository(db)
+	ctx := context.Background()
+	now := time.Now().UTC()
+	newExec := func(id int, status string, isOpen bool) *Execution {
+		return &Execution{
+			ExecutionServiceID: id,
+			IsOpen:             isOpen,
+			ExecutionStatus:    status,
+			TradeType:          "BUY",
+			Destination:        "DEST",
+			SecurityID:         "SECID123",
+			Ticker:             "AAPL",
+			QuantityOrdered:    decimal.NewFromInt(100),
+			ReceivedTimestamp:  now,
+			SentTimestamp:      now,
+			TimeInForce:        "GTC",
+			Version:            1,
+		}
+	}
+	assert.NoError(t, repo.Create(ctx, newExec(1, "NEW", true)))
+	assert.NoError(t, repo.Create(ctx, newExec(2, "FULL", false)))
+	assert.Error(t, repo.Create(ctx, newExec(3, "DONE", false)), "unknown status")
+	assert.Error(t, repo.Create(ctx, newExec(4, "FULL", true)), "is_open disagrees with status")
+	assert.Error(t, repo.Create(ctx, newExec(5, "PART", false)), "is_open disagrees with status")
+}
+
+func TestExecutionRepository_WithinTxClaimsEachExecutionOnce(t *testing.T) {
+	db, cleanup := setupTestDBWithContainer(t)
+	defer cleanup()
+	repo := NewExecutionRepository(db)
+	ctx := context.Background()
+	now := time.Now().UTC()
+	const executions, workers = 20, 8
+	for i := 1; i <= executions; i++ {
+		assert.NoError(t, repo.Create(ctx, &Execution{
+			ExecutionServiceID: i,
+			IsOpen:             true,
+			ExecutionStatus:    "WORK",
+			TradeType:          "BUY",
+			Destination:        "DEST",
+			SecurityID:         "SECID123",
+			Ticker:             "AAPL",
+			QuantityOrdered:    decimal.NewFromInt(100),
+			ReceivedTimestamp:  now,
+			SentTimestamp:      now,
+			NextFillTimestamp:  sql.NullTime{Time: now.Add(-time.Second), Valid: true},
+			TimeInForce:        "GTC",
+			Version:            1,
+		}))
+	}
+
+	// Each worker claims, "prices" and "publishes" while holding the lock, then
+	// pushes the next fill into the future, like the fill loop does.
+	var mu sync.Mutex
+	claims := map[int]int{}
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				err := repo.WithinTx(ctx, func(ctx context.Context, tx ExecutionRepository) error {
+					exec, err := tx.PollNextForFill(ctx, time.Now())
+					if err != nil {
+						return err
+					}
+					time.Sleep(20 * time.Millisecond)
+					exec.QuantityFilled = exec.QuantityFilled.Add(decimal.NewFromInt(10))
+					exec.NextFillTimestamp = sql.NullTime{Time: time.Now().UTC().Add(time.Hour), Valid: true}
+					if err := tx.Update(ctx, exec); err != nil {
+						return err
+					}
+					mu.Lock()
+					claims[exec.ExecutionServiceID]++
+					mu.Unlock()
+					return nil
+				})
+				if errors.Is(err, sql.ErrNoRows) {
+					return
+				}
+				assert.NoError(t, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Len(t, claims, executions)
+	for id, n := range claims {
+		assert.Equal(t, 1, n, "execution %d filled more than once", id)
+	}
+	execs, err := repo.List(ctx)
+	assert.NoError(t, err)
+	for _, exec := range execs {
+		assert.Equal(t, "10", exec.QuantityFilled.String())
+	}
+}
+
+func TestExecutionRepository_WithinTxRollsBackOnError(t *testing.T) {
+	db, cleanup := setupTestDBWithContainer(t)
+	defer cleanup()
+	repo := NewExecutionRepository(db)
+	ctx := context.Background()
+	now := time.Now().UTC()
+	assert.NoError(t, repo.Create(ctx, &Execution{
+		ExecutionServiceID: 99,
+		IsOpen:             true,
+		ExecutionStatus:    "WORK",
+		TradeType:          "BUY",
+		Destination:        "DEST",
+		SecurityID:         "SECID123",
+		Ticker:             "AAPL",
+		QuantityOrdered:    decimal.NewFromInt(100),
+		ReceivedTimestamp:  now,
+		SentTimestamp:      now,
+		NextFillTimestamp:  sql.NullTime{Time: now.Add(-time.Second), Valid: true},
+		TimeInForce:        "GTC",
+		Version:            1,
+	}))
+
+	publishErr := errors.New("publish failed")
+	err := repo.WithinTx(ctx, func(ctx context.Context, tx ExecutionRepository) error {
+		exec, err := tx.PollNextForFill(ctx, time.Now())
+		if err != nil {
+			return err
+		}
+		exec.QuantityFilled = decimal.NewFromInt(50)
+		if err := tx.Update(ctx, exec); err != nil {
+			return err
+		}
+		return publishErr
+	})
+	assert.ErrorIs(t, err, publishErr)
+
+	// The fill was rolled back and the execution is eligible again.
+	exec, err := repo.PollNextForFill(ctx, time.Now())
+	assert.NoError(t, err)
+	assert.Equal(t, "0", exec.QuantityFilled.String())
+}
+
+func TestExecutionRepository_UpdateVersionConflict(t *testing.T) {
+	db, cleanup := setupTestDBWithContainer(t)
+	defer cleanup()
+	repo := NewExecutionRepository(db)
+	ctx := context.Background()
+	now := time.Now().UTC()
+	assert.NoError(t, repo.Create(ctx, &Execution{
+		ExecutionServiceID: 31,
+		IsOpen:             true,
+		ExecutionStatus:    "WORK",
+		TradeType:          "BUY",
+		Destination:        "DEST",
+		SecurityID:         "SECID123",
+		Ticker:             "AAPL",
+		QuantityOrdered:    decimal.NewFromInt(100),
+		ReceivedTimestamp:  now,
+		SentTimestamp:      now,
+		TimeInForce:        "GTC",
+		Version:            1,
+	}))
+
+	first, err := repo.GetByExecutionServiceID(ctx, 31)
+	assert.NoError(t, err)
+	second, err := repo.GetByExecutionServiceID(ctx, 31)
+	assert.NoError(t, err)
+
+	first.QuantityFilled = decimal.NewFromInt(40)
+	assert.NoError(t, repo.Update(ctx, first))
+	assert.Equal(t, 2, first.Version)
+
+	// The stale copy may not overwrite the first writer's fill.
+	second.QuantityOrdered = decimal.NewFromInt(200)
+	err = repo.Update(ctx, second)
+	assert.ErrorIs(t, err, ErrVersionConflict)
+	var conflict *VersionConflictError
+	assert.ErrorAs(t, err, &conflict)
+	assert.Equal(t, 1, conflict.Version)
+
+	// Closing the execution bumps the version too, so a copy read before it is stale.
+	cancelled, err := repo.GetByExecutionServiceID(ctx, 31)
+	assert.NoError(t, err)
+	cancelled.IsOpen = false
+	cancelled.ExecutionStatus = "CANC"
+	assert.NoError(t, repo.Update(ctx, cancelled))
+	assert.Equal(t, 3, cancelled.Version)
+	first.QuantityFilled = decimal.NewFromInt(100)
+	assert.ErrorIs(t, repo.Update(ctx, first), ErrVersionConflict)
+
+	stored, err := repo.GetByExecutionServiceID(ctx, 31)
+	assert.NoError(t, err)
+	assert.Equal(t, "40", stored.QuantityFilled.String())
+	assert.Equal(t, "100", stored.QuantityOrdered.String())
+	assert.False(t, stored.IsOpen)
+}
+
+func TestExecutionRepository_Outbox(t *testing.T) {
+	db, cleanup := setupTestDBWithContainer(t)
+	defer cleanup()
+	repo := NewExecutionRepository(db)
+	ctx := context.Background()
+
+	// Messages written in a rolled back transaction are never relayed.
+	rollback := errors.New("rollback")
+	err := repo.WithinTx(ctx, func(ctx context.Context, tx ExecutionRepository) error {
+		assert.NoError(t, tx.EnqueueOutbox(ctx, &OutboxMessage{MessageKey: []byte("1"), Payload: []byte(`{"id":0}`)}))
+		return rollback
+	})
+	assert.ErrorIs(t, err, rollback)
+
+	for i := 1; i <= 3; i++ {
+		msg := &OutboxMessage{MessageKey: []byte("1"), Payload: []byte(fmt.Sprintf(`{"id":%d}`, i))}
+		assert.NoError(t, repo.EnqueueOutbox(ctx, msg))
+		assert.NotZero(t, msg.ID)
+	}
+
+	pending, err := repo.PendingOutbox(ctx, 2)
+	assert.NoError(t, err)
+	assert.Len(t, pending, 2)
+	assert.Equal(t, `{"id":1}`, string(pending[0].Payload))
+	assert.Equal(t, `{"id":2}`, string(pending[1].Payload))
+
+	assert.NoError(t, repo.MarkOutboxSent(ctx, []int64{pending[0].ID, pending[1].ID}, time.Now().UTC()))
+	pending, err = repo.PendingOutbox(ctx, 10)
+	assert.NoError(t, err)
+	assert.Len(t, pending, 1)
+	assert.Equal(t, `{"id":3}`, string(pending[0].Payload))
+
+	// Reports for FIX sessions are queued apart from the fills topic, per session.
+	session := sql.NullString{String: "FIX.4.4:GLOBECO->CLIENT", Valid: true}
+	assert.NoError(t, repo.EnqueueOutbox(ctx, &OutboxMessage{Payload: []byte("8=FIX.4.4"), FIXSessionID: session}))
+	pending, err = repo.PendingOutbox(ctx, 10)
+	assert.NoError(t, err)
+	assert.Len(t, pending, 1)
+	pending, err = repo.PendingFIXOutbox(ctx, session.String, 10)
+	assert.NoError(t, err)
+	assert.Len(t, pending, 1)
+	assert.Equal(t, session, pending[0].FIXSessionID)
+	pending, err = repo.PendingFIXOutbox(ctx, "FIX.4.4:GLOBECO->OTHER", 10)
+	assert.NoError(t, err)
+	assert.Empty(t, pending)
+
+	// Only sent messages are pruned.
+	pruned, err := repo.PruneOutbox(ctx, time.Now().Add(time.Minute))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), pruned)
+	pending, err = repo.PendingOutbox(ctx, 10)
+	assert.NoError(t, err)
+	assert.Len(t, pending, 1)
+}
+
+func TestExecutionRepository_Fills(t *testing.T) {
+	db, cleanup := setupTestDBWithContainer(t)
+	defer cleanup()
+	repo := NewExecutionRepository(db)
+	ctx := context.Background()
+	now := time.Now().UTC()
+	exec := &Execution{
+		ExecutionServiceID: 51,
+		IsOpen:             true,
+		ExecutionStatus:    "WORK",
+		TradeType:          "BUY",
+		Destination:        "NYSE",
+		SecurityID:         "SECID123",
+		Ticker:             "AAPL",
+		QuantityOrdered:    decimal.NewFromInt(100),
+		ReceivedTimestamp:  now,
+		SentTimestamp:      now,
+		TimeInForce:        "GTC",
+		Version:            1,
+	}
+	assert.NoError(t, repo.Create(ctx, exec))
+
+	first := &Fill{ExecutionID: exec.ID, Quantity: decimal.NewFromInt(40), Price: decimal.RequireFromString("101.5"), FillTimestamp: now, Venue: "NYSE"}
+	assert.NoError(t, repo.CreateFill(ctx, first))
+	second := &Fill{ExecutionID: exec.ID, Quantity: decimal.NewFromInt(60), Price: decimal.RequireFromString("102.25"), Fee: decimal.RequireFromString("0.18"), FillTimestamp: now.Add(time.Second), Venue: "NYSE"}
+	assert.NoError(t, repo.CreateFill(ctx, second))
+	assert.Equal(t, 1, first.FillSequence)
+	assert.Equal(t, 2, second.FillSequence)
+
+	fills, err := repo.ListFills(ctx, exec.ID)
+	assert.NoError(t, err)
+	assert.Len(t, fills, 2)
+	assert.Equal(t, "40", fills[0].Quantity.String())
+	assert.Equal(t, "102.25", fills[1].Price.String())
+	assert.Equal(t, "0", fills[0].Fee.String())
+	assert.Equal(t, "0.18", fills[1].Fee.String())
+	assert.Equal(t, "NYSE", fills[1].Venue)
+
+	fills, err = repo.ListFills(ctx, exec.ID+1)
+	assert.NoError(t, err)
+	assert.Empty(t, fills)
 }
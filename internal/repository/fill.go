@@ -0,0 +1,43 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/shopspring/decimal"
+)
+
+// Fill represents a row in the fill table: one fill of an execution.
+type Fill struct {
+	ID            int64           `db:"id"`
+	ExecutionID   int             `db:"execution_id"`
+	FillSequence  int             `db:"fill_sequence"`
+	Quantity      decimal.Decimal `db:"quantity"`
+	Price         decimal.Decimal `db:"price"`
+	Fee           decimal.Decimal `db:"fee"`
+	FillTimestamp time.Time       `db:"fill_timestamp"`
+	Venue         string          `db:"venue"`
+}
+
+// CreateFill records fill as the next fill of its execution, setting its ID
+// and FillSequence. Callers hold the execution's row lock (see WithinTx), so
+// sequences are gap free.
+func (r *executionRepository) CreateFill(ctx context.Context, fill *Fill) error {
+	query := `INSERT INTO fill (execution_id, fill_sequence, quantity, price, fee, fill_timestamp, venue)
+	SELECT $1, COALESCE(MAX(fill_sequence), 0) + 1, $2, $3, $4, $5, $6 FROM fill WHERE execution_id = $1
+	RETURNING id, fill_sequence`
+	row := r.db.QueryRowxContext(ctx, query, fill.ExecutionID, fill.Quantity, fill.Price, fill.Fee, fill.FillTimestamp, fill.Venue)
+	return row.Scan(&fill.ID, &fill.FillSequence)
+}
+
+// ListFills returns the fills of an execution in sequence.
+func (r *executionRepository) ListFills(ctx context.Context, executionID int) ([]*Fill, error) {
+	var fills []*Fill
+	query := `SELECT * FROM fill WHERE execution_id = $1 ORDER BY fill_sequence`
+	err := sqlx.SelectContext(ctx, r.db, &fills, query, executionID)
+	if err != nil {
+		return nil, err
+	}
+	return fills, nil
+}
@@ -0,0 +1,125 @@
+package repository
+
+import (
+	"context"
+	"sync"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/kasbench/globeco-fix-engine/internal/fix"
+)
+
+// fixSessionStore is a fix.MessageStore backed by the fix_session and
+// fix_message tables, so sequence numbers and the outbound journal survive
+// restarts. Sequence numbers are cached and written through on every change.
+type fixSessionStore struct {
+	db         *sqlx.DB
+	sessionID  string
+	mu         sync.Mutex
+	nextSender int
+	nextTarget int
+}
+
+// FIXSessionStoreFactory creates Postgres-backed FIX message stores.
+type FIXSessionStoreFactory struct {
+	db *sqlx.DB
+}
+
+// NewFIXSessionStoreFactory constructs a new FIXSessionStoreFactory.
+func NewFIXSessionStoreFactory(db *sqlx.DB) *FIXSessionStoreFactory {
+	return &FIXSessionStoreFactory{db: db}
+}
+
+// Create loads the stored state for sessionID, creating the row on first use.
+func (f *FIXSessionStoreFactory) Create(ctx context.Context, sessionID fix.SessionID) (fix.MessageStore, error) {
+	s := &fixSessionStore{db: f.db, sessionID: sessionID.String()}
+	_, err := f.db.ExecContext(ctx, `INSERT INTO fix_session (session_id) VALUES ($1) ON CONFLICT (session_id) DO NOTHING`, s.sessionID)
+	if err != nil {
+		return nil, err
+	}
+	row := f.db.QueryRowxContext(ctx, `SELECT next_sender_seq_num, next_target_seq_num FROM fix_session WHERE session_id = $1`, s.sessionID)
+	if err := row.Scan(&s.nextSender, &s.nextTarget); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *fixSessionStore) NextSenderMsgSeqNum() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.nextSender
+}
+
+func (s *fixSessionStore) NextTargetMsgSeqNum() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.nextTarget
+}
+
+func (s *fixSessionStore) SetNextSenderMsgSeqNum(ctx context.Context, next int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, err := s.db.ExecContext(ctx, `UPDATE fix_session SET next_sender_seq_num = $1 WHERE session_id = $2`, next, s.sessionID); err != nil {
+		return err
+	}
+	s.nextSender = next
+	return nil
+}
+
+func (s *fixSessionStore) SetNextTargetMsgSeqNum(ctx context.Context, next int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, err := s.db.ExecContext(ctx, `UPDATE fix_session SET next_target_seq_num = $1 WHERE session_id = $2`, next, s.sessionID); err != nil {
+		return err
+	}
+	s.nextTarget = next
+	return nil
+}
+
+func (s *fixSessionStore) IncrNextSenderMsgSeqNum(ctx context.Context) error {
+	return s.SetNextSenderMsgSeqNum(ctx, s.NextSenderMsgSeqNum()+1)
+}
+
+func (s *fixSessionStore) IncrNextTargetMsgSeqNum(ctx context.Context) error {
+	return s.SetNextTargetMsgSeqNum(ctx, s.NextTargetMsgSeqNum()+1)
+}
+
+func (s *fixSessionStore) SaveMessage(ctx context.Context, seqNum int, msg []byte) error {
+	query := `INSERT INTO fix_message (session_id, msg_seq_num, message) VALUES ($1, $2, $3)
+	ON CONFLICT (session_id, msg_seq_num) DO UPDATE SET message = EXCLUDED.message`
+	_, err := s.db.ExecContext(ctx, query, s.sessionID, seqNum, msg)
+	return err
+}
+
+func (s *fixSessionStore) GetMessages(ctx context.Context, beginSeqNo, endSeqNo int) ([][]byte, error) {
+	var msgs [][]byte
+	query := `SELECT message FROM fix_message
+	WHERE session_id = $1 AND msg_seq_num BETWEEN $2 AND $3
+	ORDER BY msg_seq_num`
+	err := s.db.SelectContext(ctx, &msgs, query, s.sessionID, beginSeqNo, endSeqNo)
+	if err != nil {
+		return nil, err
+	}
+	return msgs, nil
+}
+
+func (s *fixSessionStore) Reset(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if _, err := tx.ExecContext(ctx, `DELETE FROM fix_message WHERE session_id = $1`, s.sessionID); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `UPDATE fix_session SET next_sender_seq_num = 1, next_target_seq_num = 1, creation_time = NOW() WHERE session_id = $1`, s.sessionID); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	s.nextSender = 1
+	s.nextTarget = 1
+	return nil
+}
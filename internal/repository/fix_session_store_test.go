@@ -0,0 +1,43 @@
+package repository
+
+import (
+	"context"
+	"testing"
+
+	"github.com/kasbench/globeco-fix-engine/internal/fix"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFIXSessionStore_PersistsAcrossReload(t *testing.T) {
+	db, cleanup := setupTestDBWithContainer(t)
+	defer cleanup()
+	factory := NewFIXSessionStoreFactory(db)
+	ctx := context.Background()
+	sessionID := fix.SessionID{BeginString: fix.BeginString, SenderCompID: "GLOBECO", TargetCompID: "CLIENT"}
+
+	store, err := factory.Create(ctx, sessionID)
+	require.NoError(t, err)
+	assert.Equal(t, 1, store.NextSenderMsgSeqNum())
+	assert.Equal(t, 1, store.NextTargetMsgSeqNum())
+
+	assert.NoError(t, store.SaveMessage(ctx, 1, []byte("first")))
+	assert.NoError(t, store.SaveMessage(ctx, 2, []byte("second")))
+	assert.NoError(t, store.IncrNextSenderMsgSeqNum(ctx))
+	assert.NoError(t, store.IncrNextSenderMsgSeqNum(ctx))
+	assert.NoError(t, store.SetNextTargetMsgSeqNum(ctx, 5))
+
+	reloaded, err := factory.Create(ctx, sessionID)
+	require.NoError(t, err)
+	assert.Equal(t, 3, reloaded.NextSenderMsgSeqNum())
+	assert.Equal(t, 5, reloaded.NextTargetMsgSeqNum())
+	msgs, err := reloaded.GetMessages(ctx, 2, 10)
+	require.NoError(t, err)
+	assert.Equal(t, [][]byte{[]byte("second")}, msgs)
+
+	require.NoError(t, reloaded.Reset(ctx))
+	assert.Equal(t, 1, reloaded.NextSenderMsgSeqNum())
+	msgs, err = reloaded.GetMessages(ctx, 1, 10)
+	require.NoError(t, err)
+	assert.Empty(t, msgs)
+}
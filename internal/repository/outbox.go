@@ -0,0 +1,75 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// OutboxMessage is a row in the outbox table: a message for the fills topic,
+// or an encoded ExecutionReport for a FIX session, that was written in the
+// same transaction as the change it reports.
+type OutboxMessage struct {
+	ID               int64          `db:"id"`
+	MessageKey       []byte         `db:"message_key"`
+	Payload          []byte         `db:"payload"`
+	FIXSessionID     sql.NullString `db:"fix_session_id"` // Session the report is for; NULL for the fills topic
+	CreatedTimestamp time.Time      `db:"created_timestamp"`
+	SentTimestamp    sql.NullTime   `db:"sent_timestamp"`
+}
+
+func (r *executionRepository) EnqueueOutbox(ctx context.Context, msg *OutboxMessage) error {
+	query := `INSERT INTO outbox (message_key, payload, fix_session_id) VALUES ($1, $2, $3) RETURNING id, created_timestamp`
+	return sqlx.GetContext(ctx, r.db, msg, query, msg.MessageKey, msg.Payload, msg.FIXSessionID)
+}
+
+// PendingOutbox returns up to limit unsent messages for the fills topic in the
+// order they were written. The rows stay locked until the transaction ends, so
+// concurrent relays take turns rather than publishing the same messages out of order.
+func (r *executionRepository) PendingOutbox(ctx context.Context, limit int) ([]*OutboxMessage, error) {
+	var msgs []*OutboxMessage
+	query := `SELECT * FROM outbox
+	WHERE sent_timestamp IS NULL
+	  AND fix_session_id IS NULL
+	ORDER BY id
+	LIMIT $1
+	FOR UPDATE`
+	err := sqlx.SelectContext(ctx, r.db, &msgs, query, limit)
+	if err != nil {
+		return nil, err
+	}
+	return msgs, nil
+}
+
+// PendingFIXOutbox is PendingOutbox for the reports queued for the FIX session fixSessionID.
+func (r *executionRepository) PendingFIXOutbox(ctx context.Context, fixSessionID string, limit int) ([]*OutboxMessage, error) {
+	var msgs []*OutboxMessage
+	query := `SELECT * FROM outbox
+	WHERE sent_timestamp IS NULL
+	  AND fix_session_id = $1
+	ORDER BY id
+	LIMIT $2
+	FOR UPDATE`
+	err := sqlx.SelectContext(ctx, r.db, &msgs, query, fixSessionID, limit)
+	if err != nil {
+		return nil, err
+	}
+	return msgs, nil
+}
+
+func (r *executionRepository) MarkOutboxSent(ctx context.Context, ids []int64, sent time.Time) error {
+	_, err := r.db.ExecContext(ctx, `UPDATE outbox SET sent_timestamp = $1 WHERE id = ANY($2)`, sent, pq.Array(ids))
+	return err
+}
+
+// PruneOutbox deletes the messages sent before before and returns how many it deleted.
+func (r *executionRepository) PruneOutbox(ctx context.Context, before time.Time) (int64, error) {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM outbox WHERE sent_timestamp < $1`, before)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
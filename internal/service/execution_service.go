@@ -3,32 +3,100 @@ package service
 import (
 	"context"
 	"database/sql"
+	"database/sql/driver"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"log"
-	"math/rand"
+	"net"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/jmoiron/sqlx"
+	"github.com/kasbench/globeco-fix-engine/internal/calendar"
+	"github.com/kasbench/globeco-fix-engine/internal/clock"
 	"github.com/kasbench/globeco-fix-engine/internal/domain"
+	"github.com/kasbench/globeco-fix-engine/internal/fix"
 	"github.com/kasbench/globeco-fix-engine/internal/metrics"
 	"github.com/kasbench/globeco-fix-engine/internal/repository"
+	"github.com/lib/pq"
 	"github.com/segmentio/kafka-go"
+	"github.com/shopspring/decimal"
 	"go.uber.org/zap"
 )
 
 // ExecutionService wires together the repository, Kafka, and external service clients.
 type ExecutionService struct {
-	Repo           repository.ExecutionRepository
-	DB             *sqlx.DB
-	OrdersConsumer *kafka.Reader
-	FillsProducer  *kafka.Writer
-	SecurityClient *SecurityServiceClient
-	PricingClient  *PricingServiceClient
-	Logger         *zap.Logger
-	Metrics        *metrics.ConsumerMetrics
-	KafkaReady     *KafkaReadiness
+	Repo            repository.ExecutionRepository
+	DB              *sqlx.DB
+	OrdersConsumer  *kafka.Reader
+	CancelsConsumer *kafka.Reader
+	AmendsConsumer  *kafka.Reader
+	FillsProducer   MessageWriter
+	RejectsProducer MessageWriter
+	SecurityClient  *SecurityServiceClient
+	PricingClient   *PricingServiceClient
+	Logger          *zap.Logger
+	Metrics         *metrics.ConsumerMetrics
+	// ExecutionMetrics counts status transitions refused by the state machine; nil disables them.
+	ExecutionMetrics *metrics.ExecutionMetrics
+	KafkaReady       *KafkaReadiness
+	// FIXSender routes fills of FIX-originated orders back to their session.
+	// It is nil when the FIX acceptor is disabled.
+	FIXSender FIXSender
+	// SessionClose determines when DAY orders expire without a Calendar; nil means 16:00 UTC.
+	SessionClose *SessionClose
+	// DLQ receives order messages that failed permanently; nil disables dead-lettering.
+	DLQ DeadLetterPublisher
+	// FillModel simulates fill quantities and timing; nil means the original random model.
+	FillModel FillModel
+	// FillSeed makes fill simulation deterministic per execution when non-zero.
+	FillSeed int64
+	// PriceSimulator moves prices intraday; nil fills at the Pricing Service's price.
+	PriceSimulator *PriceSimulator
+	// Slippage moves fill prices off the market price; nil fills at the market price.
+	Slippage *SlippageModel
+	// Venues simulates executions by destination; others use defaultVenue.
+	Venues map[string]*Venue
+	// Calendar restricts fills to exchange trading hours; nil trades around the clock.
+	Calendar *calendar.Calendar
+	// Clock timestamps and schedules executions; nil is the wall clock.
+	Clock clock.Clock
+	// AveragePriceRounding rounds reported average prices; nil rounds to 4 places, half-even.
+	AveragePriceRounding *domain.Rounding
+}
+
+// MessageWriter publishes messages to a Kafka topic; *kafka.Writer implements it.
+type MessageWriter interface {
+	WriteMessages(ctx context.Context, msgs ...kafka.Message) error
+}
+
+// MessageReader consumes messages from a Kafka consumer group; *kafka.Reader implements it.
+type MessageReader interface {
+	FetchMessage(ctx context.Context) (kafka.Message, error)
+	CommitMessages(ctx context.Context, msgs ...kafka.Message) error
+}
+
+// DeadLetterPublisher writes messages that could not be processed to a dead-letter topic.
+type DeadLetterPublisher interface {
+	Publish(ctx context.Context, m kafka.Message, reasonCode, reason string) error
+}
+
+var (
+	// ErrExecutionClosed is returned when an operation requires an open execution.
+	ErrExecutionClosed = errors.New("execution is not open")
+	// ErrInvalidAmend is returned (wrapped with the reason) when an amendment is refused.
+	ErrInvalidAmend = errors.New("invalid amendment")
+)
+
+// FIXSender delivers application messages to FIX sessions; *fix.Acceptor implements it.
+type FIXSender interface {
+	Send(ctx context.Context, sessionID fix.SessionID, m *fix.Message) error
+	// Sessions returns the sessions currently logged on.
+	Sessions() []fix.SessionID
 }
 
 // KafkaReadiness tracks whether the Kafka consumer has successfully connected and received partition assignments.
@@ -61,7 +129,10 @@ func NewExecutionService(
 	repo repository.ExecutionRepository,
 	db *sqlx.DB,
 	ordersConsumer *kafka.Reader,
+	cancelsConsumer *kafka.Reader,
+	amendsConsumer *kafka.Reader,
 	fillsProducer *kafka.Writer,
+	rejectsProducer *kafka.Writer,
 	securityClient *SecurityServiceClient,
 	pricingClient *PricingServiceClient,
 	logger *zap.Logger,
@@ -69,15 +140,18 @@ func NewExecutionService(
 	kafkaReady *KafkaReadiness,
 ) *ExecutionService {
 	return &ExecutionService{
-		Repo:           repo,
-		DB:             db,
-		OrdersConsumer: ordersConsumer,
-		FillsProducer:  fillsProducer,
-		SecurityClient: securityClient,
-		PricingClient:  pricingClient,
-		Logger:         logger,
-		Metrics:        m,
-		KafkaReady:     kafkaReady,
+		Repo:            repo,
+		DB:              db,
+		OrdersConsumer:  ordersConsumer,
+		CancelsConsumer: cancelsConsumer,
+		AmendsConsumer:  amendsConsumer,
+		FillsProducer:   fillsProducer,
+		RejectsProducer: rejectsProducer,
+		SecurityClient:  securityClient,
+		PricingClient:   pricingClient,
+		Logger:          logger,
+		Metrics:         m,
+		KafkaReady:      kafkaReady,
 	}
 }
 
@@ -99,7 +173,7 @@ func (s *ExecutionService) StartOrderIntakeLoop(ctx context.Context) {
 		// Capture poll start time for idle/poll duration measurement
 		pollStart := time.Now()
 
-		m, err := s.OrdersConsumer.ReadMessage(ctx)
+		m, err := s.OrdersConsumer.FetchMessage(ctx)
 
 		// Calculate poll duration (monotonic via time.Since)
 		pollDuration := time.Since(pollStart).Seconds()
@@ -196,77 +270,357 @@ func (s *ExecutionService) StartOrderIntakeLoop(ctx context.Context) {
 		}
 
 		var postDTO domain.ExecutionDTO
-		if err := json.Unmarshal(m.Value, &postDTO); err != nil {
-			recordFailure()
-			log.Printf("error unmarshalling order: %v", err)
-			continue
+		exec, reasonCode, err := s.ingestOrderRetrying(ctx, m.Value, &postDTO)
+		if err != nil && ctx.Err() != nil {
+			return
 		}
-
-		ticker, err := s.SecurityClient.GetTickerBySecurityID(ctx, postDTO.SecurityID)
 		if err != nil {
 			recordFailure()
-			log.Printf("error looking up ticker: %v", err)
-			continue
+			log.Printf("error ingesting order: %v", err)
+			// The message may only be committed once the reject and DLQ entry are out.
+			if !s.retryUntilDone(ctx, "record failed order", func() error {
+				return s.failOrder(ctx, m, &postDTO, reasonCode, err)
+			}) {
+				return
+			}
+		} else {
+			// Success — record processing success metrics
+			processingDuration := time.Since(processingStart).Seconds()
+			completionTime := time.Now()
+			var latencyPtr *float64
+			if creationTime, ok := metrics.ResolveMessageCreationTime(m, m.Value); ok {
+				if latency, ok := metrics.CalculateLatency(creationTime, completionTime); ok {
+					latencyPtr = &latency
+				}
+			}
+			if s.Metrics != nil {
+				s.Metrics.RecordProcessingSuccess(ctx, processingDuration, latencyPtr, m.Topic, m.Partition)
+			}
+			s.Logger.Debug("order ingested", zap.Int("order_id", exec.ExecutionServiceID), zap.String("ticker", exec.Ticker))
 		}
 
-		now := time.Now().UTC()
-		var limitPricePtr *float64
-		if postDTO.LimitPrice != nil {
-			if *postDTO.LimitPrice > -0.0001 && *postDTO.LimitPrice < 0.0001 {
-				limitPricePtr = nil
-			} else {
-				limitPricePtr = postDTO.LimitPrice
-			}
+		// Commit only now that the order is durably stored, rejected or dead-lettered
+		if !s.retryUntilDone(ctx, "commit order offset", func() error {
+			return s.OrdersConsumer.CommitMessages(ctx, m)
+		}) {
+			return
 		}
-		exec := &repository.Execution{
-			ExecutionServiceID: postDTO.ID, // This should be the order ID from the message if present
-			IsOpen:             true,
-			ExecutionStatus:    "WORK",
-			TradeType:          postDTO.TradeType,
-			Destination:        postDTO.Destination,
-			SecurityID:         postDTO.SecurityID,
-			Ticker:             ticker,
-			QuantityOrdered:    postDTO.QuantityOrdered,
-			LimitPrice:         sqlNullFloat64(limitPricePtr),
-			ReceivedTimestamp:  postDTO.ReceivedTimestamp.Time(),
-			SentTimestamp:      now, // Set to current time when processing the order
-			LastFillTimestamp:  sqlNullTime(nil),
-			QuantityFilled:     0,
-			NextFillTimestamp:  sqlNullTime(&now),
-			NumberOfFills:      0,
-			TotalAmount:        0,
-			Version:            postDTO.Version,
-		}
-
-		if err := s.Repo.Create(ctx, exec); err != nil {
-			recordFailure()
-			log.Printf("error saving execution: %v", err)
-			continue
+	}
+}
+
+// ingestOrder maps and persists one order message and publishes its NEW event.
+// On failure it returns the reject reason code. A redelivered order that is already stored counts as
+// ingested, which makes intake idempotent on execution_service_id.
+func (s *ExecutionService) ingestOrder(ctx context.Context, value []byte, postDTO *domain.ExecutionDTO) (*repository.Execution, string, error) {
+	if err := json.Unmarshal(value, postDTO); err != nil {
+		return nil, domain.RejectReasonInvalidMessage, err
+	}
+	exec, err := s.newExecution(ctx, postDTO, nil)
+	if err != nil {
+		return nil, rejectReasonFor(err, domain.RejectReasonSecurityLookup), err
+	}
+	err = s.Repo.WithinTx(ctx, func(ctx context.Context, repo repository.ExecutionRepository) error {
+		if err := repo.Create(ctx, exec); err != nil {
+			return err
+		}
+		return s.publish(ctx, repo, exec, routeEvent(exec))
+	})
+	if err != nil {
+		if errors.Is(err, repository.ErrDuplicateExecution) {
+			s.Logger.Debug("order already ingested", zap.Int("order_id", exec.ExecutionServiceID))
+			return exec, "", nil
 		}
+		return nil, rejectReasonFor(err, domain.RejectReasonPersistence), err
+	}
+	return exec, "", nil
+}
 
-		// Success — record processing success metrics
-		processingDuration := time.Since(processingStart).Seconds()
-		completionTime := time.Now()
-		var latencyPtr *float64
-		if creationTime, ok := metrics.ResolveMessageCreationTime(m, m.Value); ok {
-			if latency, ok := metrics.CalculateLatency(creationTime, completionTime); ok {
-				latencyPtr = &latency
+// maxPersistenceAttempts caps how often an order is stored after connection
+// or serialization failures before it is dead-lettered.
+var maxPersistenceAttempts = 10
+
+// ingestOrderRetrying is ingestOrder, retrying transient failures until the
+// order is stored or found to be one that can never be accepted, so that the
+// intake loop only moves past an order once its fate is final. Security
+// Service failures are retried indefinitely; database failures only if they
+// are transient, and at most maxPersistenceAttempts times. It returns ctx's
+// error if ctx is cancelled first.
+func (s *ExecutionService) ingestOrderRetrying(ctx context.Context, value []byte, postDTO *domain.ExecutionDTO) (*repository.Execution, string, error) {
+	var (
+		exec       *repository.Execution
+		reasonCode string
+		err        error
+		attempts   int
+	)
+	if !s.retryUntilDone(ctx, "ingest order", func() error {
+		*postDTO = domain.ExecutionDTO{}
+		exec, reasonCode, err = s.ingestOrder(ctx, value, postDTO)
+		switch {
+		case err == nil, rejectable(reasonCode):
+			return nil
+		case reasonCode == domain.RejectReasonPersistence:
+			if attempts++; attempts >= maxPersistenceAttempts || !transientPersistenceError(err) {
+				return nil
 			}
 		}
-		if s.Metrics != nil {
-			s.Metrics.RecordProcessingSuccess(ctx, processingDuration, latencyPtr, m.Topic, m.Partition)
+		return err
+	}) {
+		return nil, "", ctx.Err()
+	}
+	return exec, reasonCode, err
+}
+
+// retryUntilDone runs op until it succeeds, backing off between attempts.
+// It returns false if ctx is cancelled first.
+func (s *ExecutionService) retryUntilDone(ctx context.Context, what string, op func() error) bool {
+	const (
+		initialBackoff = 100 * time.Millisecond
+		maxBackoff     = 5 * time.Second
+	)
+	backoff := initialBackoff
+	for {
+		err := op()
+		if err == nil {
+			return true
+		}
+		if ctx.Err() != nil {
+			return false
+		}
+		s.Logger.Warn("retrying after failure", zap.String("operation", what), zap.Error(err), zap.Duration("backoff", backoff))
+		select {
+		case <-ctx.Done():
+			return false
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+		if backoff > maxBackoff {
+			backoff = maxBackoff
+		}
+	}
+}
+
+// fixOrigin identifies an order received over FIX by its session and the
+// counterparty's ClOrdID, which is opaque to us.
+type fixOrigin struct {
+	sessionID fix.SessionID
+	clOrdID   string
+}
+
+// newExecution maps an inbound order onto a new open execution, looking up the
+// ticker via the Security Service. Both the Kafka and FIX intake paths use it;
+// origin is nil for Kafka orders, which are keyed by postDTO.ID instead.
+// Malformed orders are reported with an error wrapping ErrInvalidOrder.
+func (s *ExecutionService) newExecution(ctx context.Context, postDTO *domain.ExecutionDTO, origin *fixOrigin) (*repository.Execution, error) {
+	if !isTradeType(postDTO.TradeType) {
+		return nil, fmt.Errorf("%w: unknown tradeType %q", ErrInvalidOrder, postDTO.TradeType)
+	}
+	if !postDTO.QuantityOrdered.IsPositive() {
+		return nil, fmt.Errorf("%w: quantity must be positive", ErrInvalidOrder)
+	}
+	now := s.now()
+	timeInForce, expire, err := s.resolveTimeInForce(postDTO, now)
+	if err != nil {
+		return nil, err
+	}
+
+	ticker, err := s.SecurityClient.GetTickerBySecurityID(ctx, postDTO.SecurityID)
+	if err != nil {
+		return nil, err
+	}
+
+	var limitPrice *decimal.Decimal
+	if postDTO.LimitPrice != nil {
+		limitPrice = &postDTO.LimitPrice.Decimal
+	}
+	limitPricePtr := normalizeLimitPrice(limitPrice)
+	exec := &repository.Execution{
+		ExecutionServiceID: postDTO.ID, // This should be the order ID from the message if present
+		IsOpen:             true,
+		ExecutionStatus:    string(domain.StatusNew),
+		TradeType:          postDTO.TradeType,
+		Destination:        postDTO.Destination,
+		SecurityID:         postDTO.SecurityID,
+		Ticker:             ticker,
+		QuantityOrdered:    postDTO.QuantityOrdered.Decimal,
+		LimitPrice:         sqlNullDecimal(limitPricePtr),
+		ReceivedTimestamp:  postDTO.ReceivedTimestamp.Time(),
+		SentTimestamp:      now, // Set to current time when processing the order
+		LastFillTimestamp:  sqlNullTime(nil),
+		QuantityFilled:     decimal.Zero,
+		NextFillTimestamp:  sqlNullTime(&now),
+		NumberOfFills:      0,
+		TotalAmount:        decimal.Zero,
+		Version:            postDTO.Version,
+		TimeInForce:        timeInForce,
+		ExpireTimestamp:    sqlNullTime(expire),
+	}
+	if origin != nil {
+		exec.ExecutionServiceID = 0
+		exec.FIXSessionID = sql.NullString{String: origin.sessionID.String(), Valid: true}
+		exec.ClOrdID = sql.NullString{String: origin.clOrdID, Valid: true}
+	}
+
+	// Route to the venue, which may reject the order outright
+	venue := s.venue(exec.Destination)
+	rng := routeRand(s.FillSeed, exec)
+	if venue.Rejects(rng) {
+		if err := s.transition(ctx, exec, domain.StatusRejected); err != nil {
+			return nil, err
+		}
+		exec.NextFillTimestamp = sqlNullTime(nil)
+	} else {
+		if err := s.transition(ctx, exec, domain.StatusWorking); err != nil {
+			return nil, err
+		}
+		next := s.tradingTime(venue, now.Add(venue.Latency(rng)))
+		exec.NextFillTimestamp = sqlNullTime(&next)
+	}
+	return exec, nil
+}
+
+// failOrder handles an order message the intake loop could not accept. Orders
+// that can never be accepted are rejected, so the upstream order service
+// learns that they died; transient failures are not, as the order may yet be
+// accepted (the intake loop retries those rather than failing them). Either
+// way the message is dead-lettered so it can be re-driven once the underlying
+// problem is fixed. If either publish fails the whole step is retried, so a
+// reject may be published more than once.
+func (s *ExecutionService) failOrder(ctx context.Context, m kafka.Message, postDTO *domain.ExecutionDTO, reasonCode string, cause error) error {
+	if rejectable(reasonCode) {
+		if err := s.rejectOrder(ctx, postDTO, reasonCode, cause); err != nil {
+			return err
+		}
+	}
+	return s.deadLetter(ctx, m, reasonCode, cause)
+}
+
+// rejectOrder publishes a reject for an order the intake loop could not accept,
+// so the upstream order service learns that it died.
+func (s *ExecutionService) rejectOrder(ctx context.Context, postDTO *domain.ExecutionDTO, reasonCode string, cause error) error {
+	reject := domain.RejectDTO{
+		EventType:          domain.EventTypeReject,
+		ExecutionServiceID: postDTO.ID,
+		ExecutionStatus:    string(domain.StatusRejected),
+		ReasonCode:         reasonCode,
+		Reason:             cause.Error(),
+		TradeType:          postDTO.TradeType,
+		SecurityID:         postDTO.SecurityID,
+		RejectedTimestamp:  domain.EpochTimeFromTime(s.now()),
+	}
+	if !postDTO.QuantityOrdered.IsZero() {
+		reject.QuantityOrdered = &postDTO.QuantityOrdered
+	}
+	msg, err := json.Marshal(reject)
+	if err != nil {
+		return fmt.Errorf("marshalling reject: %w", err)
+	}
+	if err := s.RejectsProducer.WriteMessages(ctx, kafka.Message{Value: msg}); err != nil {
+		return fmt.Errorf("publishing reject: %w", err)
+	}
+	s.Logger.Debug("order rejected", zap.Int("order_id", postDTO.ID), zap.String("reason_code", reasonCode))
+	return nil
+}
+
+// rejectReasonFor classifies an intake failure into a reject reason code,
+// falling back to the reason for the stage that failed.
+func rejectReasonFor(err error, fallback string) string {
+	switch {
+	case errors.Is(err, ErrInvalidOrder):
+		return domain.RejectReasonInvalidOrder
+	case errors.Is(err, ErrSecurityNotFound):
+		return domain.RejectReasonUnknownSecurity
+	}
+	// Data exceptions and integrity violations reject the order's values.
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && (pqErr.Code.Class() == "22" || pqErr.Code.Class() == "23") {
+		return domain.RejectReasonInvalidOrder
+	}
+	return fallback
+}
+
+// transientPersistenceError reports whether a database failure may clear on
+// retry: a lost or refused connection, or a serialization failure or deadlock.
+func transientPersistenceError(err error) bool {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		switch pqErr.Code.Class() {
+		case "08", "40": // connection exception, transaction rollback
+			return true
+		}
+		switch pqErr.Code {
+		case "57P01", "57P02", "57P03": // admin shutdown, crash shutdown, cannot connect now
+			return true
+		}
+		return false
+	}
+	var netErr net.Error
+	return errors.Is(err, driver.ErrBadConn) || errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) || errors.As(err, &netErr)
+}
+
+// rejectable reports whether an order that failed intake with reasonCode can
+// never be accepted, however often it is retried, so that rejecting it is final.
+// Security Service failures, and database failures other than data exceptions
+// and integrity violations, are not.
+func rejectable(reasonCode string) bool {
+	switch reasonCode {
+	case domain.RejectReasonInvalidMessage, domain.RejectReasonInvalidOrder, domain.RejectReasonUnknownSecurity:
+		return true
+	}
+	return false
+}
+
+// now returns the current time on the service's clock.
+func (s *ExecutionService) now() time.Time {
+	if s.Clock == nil {
+		return time.Now().UTC()
+	}
+	return s.Clock.Now()
+}
+
+// averagePriceRounding returns how reported average prices are rounded.
+func (s *ExecutionService) averagePriceRounding() domain.Rounding {
+	if s.AveragePriceRounding == nil {
+		return domain.Rounding{Places: 4, Mode: domain.RoundHalfEven}
+	}
+	return *s.AveragePriceRounding
+}
+
+// transition moves exec to status to through the order state machine and
+// derives IsOpen from it. Staying in the same status is not a transition.
+// Refused transitions are counted and leave exec unchanged.
+func (s *ExecutionService) transition(ctx context.Context, exec *repository.Execution, to domain.ExecutionStatus) error {
+	from := domain.ExecutionStatus(exec.ExecutionStatus)
+	if from == to {
+		return nil
+	}
+	if err := domain.Transition(from, to); err != nil {
+		if s.ExecutionMetrics != nil {
+			s.ExecutionMetrics.RecordInvalidTransition(ctx, string(from), string(to))
 		}
+		s.Logger.Warn("invalid execution status transition",
+			zap.Int("execution_service_id", exec.ExecutionServiceID),
+			zap.String("from", string(from)),
+			zap.String("to", string(to)))
+		return err
+	}
+	exec.ExecutionStatus = string(to)
+	exec.IsOpen = to.IsOpen()
+	return nil
+}
 
-		// Kafka-go commits automatically when using ReadMessage
-		s.Logger.Debug("order ingested", zap.Int("order_id", exec.ExecutionServiceID), zap.String("ticker", exec.Ticker))
+// normalizeLimitPrice treats a (near) zero limit price as no limit, i.e. a market order.
+func normalizeLimitPrice(limitPrice *decimal.Decimal) *decimal.Decimal {
+	if limitPrice != nil && limitPrice.Abs().LessThan(decimal.New(1, -4)) {
+		return nil
 	}
+	return limitPrice
 }
 
-func sqlNullFloat64(f *float64) sql.NullFloat64 {
-	if f == nil {
-		return sql.NullFloat64{Valid: false}
+func sqlNullDecimal(d *decimal.Decimal) decimal.NullDecimal {
+	if d == nil {
+		return decimal.NullDecimal{Valid: false}
 	}
-	return sql.NullFloat64{Float64: *f, Valid: true}
+	return decimal.NullDecimal{Decimal: *d, Valid: true}
 }
 
 func sqlNullTime(t *time.Time) sql.NullTime {
@@ -277,7 +631,8 @@ func sqlNullTime(t *time.Time) sql.NullTime {
 }
 
 // StartFillProcessingLoop polls the database for eligible executions and processes fills.
-// Uses FOR UPDATE SKIP LOCKED for concurrency control. Publishes fills to the fills topic.
+// Each fill runs in its own transaction using FOR UPDATE SKIP LOCKED for concurrency control. Publishes fills to the fills topic,
+// or as ExecutionReports to the originating session for orders received over FIX.
 func (s *ExecutionService) StartFillProcessingLoop(ctx context.Context) {
 	ticker := time.NewTicker(5 * time.Millisecond)
 	defer ticker.Stop()
@@ -286,107 +641,519 @@ func (s *ExecutionService) StartFillProcessingLoop(ctx context.Context) {
 		case <-ctx.Done():
 			return
 		case <-ticker.C:
-			// Poll for eligible execution
-			exec, err := s.Repo.PollNextForFill(ctx)
-			if err != nil {
-				if err == sql.ErrNoRows {
-					continue // nothing to process
-				}
-				log.Printf("error polling for fill: %v", err)
-				continue
+			if err := s.processNextFill(ctx); err != nil && !errors.Is(err, sql.ErrNoRows) {
+				log.Printf("error processing fill: %v", err)
 			}
+		}
+	}
+}
 
-			quantityRemaining := exec.QuantityOrdered - exec.QuantityFilled
-			fillQty := calculateFillQuantity(quantityRemaining)
+// processNextFill claims the next eligible execution and makes one fill attempt.
+// The claim is a transaction that holds the row lock across the price lookup,
+// the update and the publish, so concurrent workers and replicas never fill
+// the same execution twice. Returns sql.ErrNoRows if nothing is eligible.
+func (s *ExecutionService) processNextFill(ctx context.Context) error {
+	return s.Repo.WithinTx(ctx, func(ctx context.Context, repo repository.ExecutionRepository) error {
+		now := s.now()
+		exec, err := repo.PollNextForFill(ctx, now)
+		if err != nil {
+			return err
+		}
 
-			// Price check
-			price, err := s.PricingClient.GetPrice(ctx, exec.Ticker)
-			s.Logger.Debug("price received", zap.Float64("price", price))
-			if err != nil {
-				log.Printf("error getting price: %v", err)
-				continue
-			}
-			if (exec.TradeType == "BUY" || exec.TradeType == "COVER") && exec.LimitPrice.Valid && price > exec.LimitPrice.Float64 {
-				fillQty = 0
-			}
-			if (exec.TradeType == "SELL" || exec.TradeType == "SHORT") && exec.LimitPrice.Valid && price < exec.LimitPrice.Float64 {
-				fillQty = 0
-			}
+		quantityRemaining := exec.QuantityOrdered.Sub(exec.QuantityFilled)
+		venue := s.venue(exec.Destination)
+		fillModel := venue.FillModel
+		if fillModel == nil {
+			fillModel = s.FillModel
+		}
+		if fillModel == nil {
+			fillModel = defaultFillModel
+		}
 
-			// Cap fillQty to quantityRemaining
-			if fillQty > quantityRemaining {
-				fillQty = quantityRemaining
+		// Outside the venue's or exchange's trading hours, wait for the open
+		if open := s.tradingTime(venue, now); open.After(now) {
+			exec.NextFillTimestamp = sqlNullTime(&open)
+			if err := repo.Update(ctx, exec); err != nil {
+				return fmt.Errorf("deferring execution: %w", err)
 			}
+			return nil
+		}
+
+		// Price check
+		bar, err := s.PricingClient.GetPriceBar(ctx, exec.Ticker)
+		if err != nil {
+			return fmt.Errorf("getting price: %w", err)
+		}
+		marketPrice := bar.Close
+		if s.PriceSimulator != nil {
+			marketPrice = s.PriceSimulator.Price(bar, s.exchange(venue), now)
+		}
+		price := domain.RoundScale(decimal.NewFromFloat(marketPrice))
+		s.Logger.Debug("price received", zap.Stringer("price", price), zap.Int("volume", bar.Volume))
 
-			// Update execution
-			exec.QuantityFilled += fillQty
-			exec.TotalAmount += fillQty * price
+		rng := fillRand(s.FillSeed, exec)
+		fillQty := fillModel.FillQuantity(rng, exec, quantityRemaining, bar)
+		if isBuy(exec.TradeType) && exec.LimitPrice.Valid && price.GreaterThan(exec.LimitPrice.Decimal) {
+			fillQty = decimal.Zero
+		}
+		if !isBuy(exec.TradeType) && exec.LimitPrice.Valid && price.LessThan(exec.LimitPrice.Decimal) {
+			fillQty = decimal.Zero
+		}
+
+		// Cap fillQty to quantityRemaining
+		if fillQty.GreaterThan(quantityRemaining) {
+			fillQty = quantityRemaining
+		}
+		// Fill or kill never fills partially
+		if exec.TimeInForce == domain.TimeInForceFOK && fillQty.LessThan(quantityRemaining) {
+			fillQty = decimal.Zero
+		}
+
+		if s.Slippage != nil && fillQty.IsPositive() {
+			price = s.Slippage.FillPrice(rng, exec, price, fillQty, bar)
+		}
+
+		// Update execution. Attempts that fill nothing (e.g. the limit check
+		// failed) are not fills: they are neither counted nor reported. The
+		// value of each fill is rounded to the stored scale before it is added,
+		// so the total always equals the sum over the fill history.
+		if fillQty.IsPositive() {
+			exec.QuantityFilled = exec.QuantityFilled.Add(fillQty)
+			exec.TotalAmount = exec.TotalAmount.Add(domain.RoundScale(fillQty.Mul(price)))
 			exec.NumberOfFills += 1
-			now := time.Now().UTC()
 			exec.LastFillTimestamp = sqlNullTime(&now)
-			if exec.QuantityFilled >= exec.QuantityOrdered {
-				exec.IsOpen = false
-				exec.ExecutionStatus = "FULL"
-			} else if fillQty > 0 {
-				exec.ExecutionStatus = "PART"
+		}
+		status := domain.ExecutionStatus(exec.ExecutionStatus)
+		if exec.QuantityFilled.GreaterThanOrEqual(exec.QuantityOrdered) {
+			status = domain.StatusFilled
+		} else if fillQty.IsPositive() {
+			status = domain.StatusPartial
+		}
+		if err := s.transition(ctx, exec, status); err != nil {
+			return err
+		}
+		remainderCancelled, err := s.applyTimeInForce(ctx, exec)
+		if err != nil {
+			return err
+		}
+		if exec.IsOpen {
+			delay := fillModel.NextFillDelay(rng, exec) + venue.Latency(rng)
+			next := s.tradingTime(venue, now.Add(delay))
+			exec.NextFillTimestamp = sqlNullTime(&next)
+		}
+
+		if err := repo.Update(ctx, exec); err != nil {
+			return fmt.Errorf("updating execution: %w", err)
+		}
+		var fill *repository.Fill
+		if fillQty.IsPositive() {
+			fill = &repository.Fill{
+				ExecutionID:   exec.ID,
+				Quantity:      fillQty,
+				Price:         price,
+				Fee:           venue.Fee(fillQty, price),
+				FillTimestamp: now,
+				Venue:         exec.Destination,
 			}
-			if exec.IsOpen {
-				delta := time.Duration(rand.Intn(115)+5) * time.Second // 5s to 2m
-				next := now.Add(delta)
-				exec.NextFillTimestamp = sqlNullTime(&next)
+			if err := repo.CreateFill(ctx, fill); err != nil {
+				return fmt.Errorf("recording fill: %w", err)
 			}
+		}
+
+		if err := s.publishFill(ctx, repo, exec, fill, remainderCancelled); err != nil {
+			return fmt.Errorf("publishing fill: %w", err)
+		}
+		s.Logger.Debug("fill published",
+			zap.Int("execution_service_id", exec.ExecutionServiceID),
+			zap.Stringer("fill_qty", fillQty),
+			zap.Stringer("price", price))
+		return nil
+	})
+}
+
+// publishFill reports a processed fill, which is nil if the attempt filled
+// nothing, followed by the cancellation of the remainder if the time in force
+// cancelled it.
+func (s *ExecutionService) publishFill(ctx context.Context, repo repository.ExecutionRepository, exec *repository.Execution, fill *repository.Fill, remainderCancelled bool) error {
+	var events []executionEvent
+	if fill != nil {
+		events = append(events, fillEvent(exec, fill, s.averagePriceRounding()))
+	}
+	if remainderCancelled {
+		events = append(events, cancelEvent(exec, s.now(), s.averagePriceRounding()))
+	}
+	return s.publish(ctx, repo, exec, events...)
+}
+
+// executionEvent is one change to an execution, reported to orders received
+// over FIX as an ExecutionReport and to Kafka orders as an event on the fills topic.
+type executionEvent struct {
+	eventType string               // domain.EventType*
+	fill      *repository.Fill     // set for PARTIAL and FILL events
+	report    *fix.ExecutionReport // nil if a FIX counterparty is not told
+}
+
+func fillEvent(exec *repository.Execution, fill *repository.Fill, r domain.Rounding) executionEvent {
+	eventType := domain.EventTypePartial
+	if exec.ExecutionStatus == string(domain.StatusFilled) {
+		eventType = domain.EventTypeFill
+	}
+	return executionEvent{eventType: eventType, fill: fill, report: fillReport(exec, fill.Quantity, fill.Price, r)}
+}
+
+// routeEvent reports a new execution that its venue accepted (NEW) or rejected (REJECT).
+func routeEvent(exec *repository.Execution) executionEvent {
+	if exec.ExecutionStatus == string(domain.StatusRejected) {
+		return executionEvent{eventType: domain.EventTypeReject, report: venueRejectedReport(exec)}
+	}
+	return executionEvent{eventType: domain.EventTypeNew, report: newOrderReport(exec, exec.ClOrdID.String)}
+}
+
+func cancelEvent(exec *repository.Execution, now time.Time, r domain.Rounding) executionEvent {
+	return executionEvent{eventType: domain.EventTypeCancel, report: canceledReport(exec, exec.ClOrdID.String, "", now, r)}
+}
 
-			if err := s.Repo.Update(ctx, exec); err != nil {
-				log.Printf("error updating execution: %v", err)
+func expireEvent(exec *repository.Execution, now time.Time, r domain.Rounding) executionEvent {
+	return executionEvent{eventType: domain.EventTypeExpire, report: expiredReport(exec, now, r)}
+}
+
+func replaceEvent(exec *repository.Execution, now time.Time, r domain.Rounding) executionEvent {
+	return executionEvent{eventType: domain.EventTypeReplace, report: replacedReport(exec, now, r)}
+}
+
+// publish writes the events to the outbox through repo, from where they are
+// relayed once repo's transaction commits: for FIX orders the events' reports,
+// to the session the order originated on, and for Kafka orders each event
+// with the execution snapshot, to the fills topic.
+func (s *ExecutionService) publish(ctx context.Context, repo repository.ExecutionRepository, exec *repository.Execution, events ...executionEvent) error {
+	if exec.FIXSessionID.Valid {
+		for _, event := range events {
+			if event.report == nil {
 				continue
 			}
-
-			// Publish fill to Kafka
-			dto := domain.MapExecutionToDTO(exec)
-			msg, err := json.Marshal(dto)
+			msg, err := fix.Encode(event.report.ToMessage())
 			if err != nil {
-				log.Printf("error marshalling fill DTO: %v", err)
-				continue
+				return fmt.Errorf("encoding %s report: %w", event.eventType, err)
 			}
-			err = s.FillsProducer.WriteMessages(ctx, kafka.Message{Value: msg})
+			err = repo.EnqueueOutbox(ctx, &repository.OutboxMessage{
+				Payload:      msg,
+				FIXSessionID: exec.FIXSessionID,
+			})
 			if err != nil {
-				log.Printf("error publishing fill: %v", err)
-				continue
+				return err
 			}
-			s.Logger.Debug("fill published",
-				zap.Int("execution_service_id", exec.ExecutionServiceID),
-				zap.Float64("fill_qty", fillQty),
-				zap.Float64("price", price))
+		}
+		return nil
+	}
+
+	for _, event := range events {
+		msg, err := json.Marshal(domain.MapExecutionEventToDTO(event.eventType, exec, event.fill, s.averagePriceRounding()))
+		if err != nil {
+			return fmt.Errorf("marshalling %s event: %w", event.eventType, err)
+		}
+		err = repo.EnqueueOutbox(ctx, &repository.OutboxMessage{
+			MessageKey: []byte(strconv.Itoa(exec.ExecutionServiceID)),
+			Payload:    msg,
+		})
+		if err != nil {
+			return err
 		}
 	}
+	return nil
+}
+
+// StartCancelLoop consumes cancel requests from the cancels topic, closes the
+// referenced executions and publishes their final state. A cancel for an
+// execution that is already closed has nothing left to do and is committed.
+func (s *ExecutionService) StartCancelLoop(ctx context.Context) {
+	s.consumeRequests(ctx, s.CancelsConsumer, "cancel", s.handleCancel)
 }
 
-func calculateFillQuantity(quantityRemaining float64) float64 {
-	if quantityRemaining <= 0 {
-		return 0
+// handleCancel applies one cancel request message. See requestHandler.
+func (s *ExecutionService) handleCancel(ctx context.Context, m kafka.Message) (string, error) {
+	var cancelDTO domain.CancelDTO
+	if err := json.Unmarshal(m.Value, &cancelDTO); err != nil {
+		return domain.RejectReasonInvalidMessage, err
 	}
-	p := rand.Float64()
-	if p < 0.10 {
-		fill := quantityRemaining
-		fill = float64(int64(fill)) // round to whole units
-		if fill > 10000 {
-			fill = 10000
+
+	err := s.withinTxRetryingConflicts(ctx, "cancel", func(ctx context.Context, repo repository.ExecutionRepository) error {
+		exec, err := s.cancelExecution(ctx, repo, byExecutionServiceID(cancelDTO.ExecutionServiceID))
+		if err != nil {
+			return fmt.Errorf("cancelling execution %d: %w", cancelDTO.ExecutionServiceID, err)
+		}
+		if err := s.publish(ctx, repo, exec, cancelEvent(exec, s.now(), s.averagePriceRounding())); err != nil {
+			return fmt.Errorf("publishing cancel: %w", err)
 		}
-		return fill
+		return nil
+	})
+	switch {
+	case err == nil:
+		s.Logger.Debug("execution cancelled", zap.Int("execution_service_id", cancelDTO.ExecutionServiceID))
+		return "", nil
+	case errors.Is(err, ErrExecutionClosed):
+		s.Logger.Debug("cancel for closed execution ignored", zap.Int("execution_service_id", cancelDTO.ExecutionServiceID))
+		return "", nil
+	case errors.Is(err, sql.ErrNoRows):
+		return domain.DeadLetterReasonUnknownExecution, err
 	}
-	if p < 0.15 {
-		return 0 // 5% probability: no fill
+	return "", err
+}
+
+// requestHandler applies one message from a request topic. It returns a nil
+// error once the message is processed; otherwise a reason code if the message
+// can never succeed and should be dead-lettered, or "" if it failed for a
+// transient reason and should be retried.
+type requestHandler func(ctx context.Context, m kafka.Message) (reasonCode string, err error)
+
+// unknownExecutionWait is how long a request for an execution that does not
+// exist is retried before it is dead-lettered. Requests travel on their own
+// topics and can overtake the order they refer to.
+var unknownExecutionWait = 30 * time.Second
+
+// Backoff between failed fetches from a request topic, as for order intake.
+var (
+	fetchInitialBackoff = 1 * time.Second
+	fetchMaxBackoff     = 30 * time.Second
+)
+
+// consumeRequests runs handle for each message on reader and commits its
+// offset once the message is processed or dead-lettered, so a request is
+// never lost to a failure. Transient failures are retried until they succeed
+// or ctx is cancelled, holding back the rest of the partition.
+func (s *ExecutionService) consumeRequests(ctx context.Context, reader MessageReader, what string, handle requestHandler) {
+	backoff := fetchInitialBackoff
+	for {
+		m, err := reader.FetchMessage(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			s.Logger.Warn("Error reading Kafka "+what+" message, backing off", zap.Error(err), zap.Duration("backoff", backoff))
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(backoff):
+			}
+			backoff = min(backoff*2, fetchMaxBackoff)
+			continue
+		}
+		backoff = fetchInitialBackoff
+		if !s.handleRequest(ctx, m, what, handle) {
+			return
+		}
+		if !s.retryUntilDone(ctx, "commit "+what+" offset", func() error {
+			return reader.CommitMessages(ctx, m)
+		}) {
+			return
+		}
+	}
+}
+
+// handleRequest runs handle for m until it is processed or dead-lettered. It
+// returns false if ctx is cancelled first.
+func (s *ExecutionService) handleRequest(ctx context.Context, m kafka.Message, what string, handle requestHandler) bool {
+	received := time.Now()
+	return s.retryUntilDone(ctx, "process "+what, func() error {
+		reasonCode, err := handle(ctx, m)
+		switch {
+		case err == nil:
+			return nil
+		case reasonCode == "":
+			return err
+		case errors.Is(err, sql.ErrNoRows) && time.Since(received) < unknownExecutionWait:
+			return err
+		}
+		s.Logger.Warn("dead-lettering "+what, zap.String("reason_code", reasonCode), zap.Error(err))
+		return s.deadLetter(ctx, m, reasonCode, err)
+	})
+}
+
+// deadLetter writes m to the DLQ with the reason it could not be processed.
+// Without a DLQ the message is dropped.
+func (s *ExecutionService) deadLetter(ctx context.Context, m kafka.Message, reasonCode string, cause error) error {
+	if s.DLQ == nil {
+		return nil
+	}
+	if err := s.DLQ.Publish(ctx, m, reasonCode, cause.Error()); err != nil {
+		return fmt.Errorf("dead-lettering %s message: %w", m.Topic, err)
+	}
+	return nil
+}
+
+// CancelExecution closes the open Kafka order for executionServiceID with status CANC.
+// Returns sql.ErrNoRows if it does not exist and ErrExecutionClosed if it is no longer open.
+// If a fill lands between the read and the write, the cancel is retried against the new state.
+func (s *ExecutionService) CancelExecution(ctx context.Context, executionServiceID int) (*repository.Execution, error) {
+	return s.cancelRetryingConflicts(ctx, byExecutionServiceID(executionServiceID))
+}
+
+// executionLookup reads the execution an operation applies to through repo.
+type executionLookup func(ctx context.Context, repo repository.ExecutionRepository) (*repository.Execution, error)
+
+// byExecutionServiceID looks up the Kafka order for executionServiceID.
+func byExecutionServiceID(executionServiceID int) executionLookup {
+	return func(ctx context.Context, repo repository.ExecutionRepository) (*repository.Execution, error) {
+		return repo.GetByExecutionServiceID(ctx, executionServiceID)
+	}
+}
+
+// byID looks up the execution with internal id.
+func byID(id int) executionLookup {
+	return func(ctx context.Context, repo repository.ExecutionRepository) (*repository.Execution, error) {
+		return repo.GetByID(ctx, id)
+	}
+}
+
+func (s *ExecutionService) cancelRetryingConflicts(ctx context.Context, lookup executionLookup) (*repository.Execution, error) {
+	var exec *repository.Execution
+	err := s.withinTxRetryingConflicts(ctx, "cancel", func(ctx context.Context, repo repository.ExecutionRepository) error {
+		var err error
+		exec, err = s.cancelExecution(ctx, repo, lookup)
+		return err
+	})
+	if err != nil {
+		return nil, err
 	}
-	if quantityRemaining <= 100 {
-		return quantityRemaining
+	return exec, nil
+}
+
+// cancelExecution reads the execution, moves it to CANC and writes it back
+// conditional on the version read.
+func (s *ExecutionService) cancelExecution(ctx context.Context, repo repository.ExecutionRepository, lookup executionLookup) (*repository.Execution, error) {
+	exec, err := lookup(ctx, repo)
+	if err != nil {
+		return nil, err
+	}
+	if !exec.IsOpen {
+		return nil, ErrExecutionClosed
+	}
+	if err := s.transition(ctx, exec, domain.StatusCancelled); err != nil {
+		return nil, err
 	}
-	// For >100, pick one of 5 possibilities, each 20%
-	choices := []float64{0.8, 0.6, 4.0, 0.2, 0.1}
-	idx := rand.Intn(5)
-	fill := quantityRemaining * choices[idx]
-	fill = float64(int64(fill)) // round to whole units
-	if fill > 10000 {
-		fill = 10000
+	exec.NextFillTimestamp = sqlNullTime(nil)
+	if err := repo.Update(ctx, exec); err != nil {
+		return nil, err
+	}
+	return exec, nil
+}
+
+// StartAmendLoop consumes amendments from the amends topic and applies them.
+// Amendments that can never apply are dead-lettered; version conflicts and
+// database failures are retried.
+func (s *ExecutionService) StartAmendLoop(ctx context.Context) {
+	s.consumeRequests(ctx, s.AmendsConsumer, "amend", s.handleAmend)
+}
+
+// handleAmend applies one amend request message. See requestHandler.
+func (s *ExecutionService) handleAmend(ctx context.Context, m kafka.Message) (string, error) {
+	var amend domain.AmendDTO
+	if err := json.Unmarshal(m.Value, &amend); err != nil {
+		return domain.RejectReasonInvalidMessage, err
+	}
+
+	_, err := s.AmendExecution(ctx, &amend)
+	switch {
+	case err == nil:
+		return "", nil
+	case errors.Is(err, ErrInvalidAmend), errors.Is(err, domain.ErrInvalidTransition):
+		return domain.DeadLetterReasonInvalidAmend, err
+	case errors.Is(err, ErrExecutionClosed):
+		return domain.DeadLetterReasonExecutionClosed, err
+	case errors.Is(err, sql.ErrNoRows):
+		return domain.DeadLetterReasonUnknownExecution, err
+	}
+	return "", fmt.Errorf("amending execution %d: %w", amend.ExecutionServiceID, err)
+}
+
+// maxConflictRetries bounds how often a read-modify-write is attempted when it
+// keeps losing the race against another writer of the same execution.
+const maxConflictRetries = 3
+
+// withinTxRetryingConflicts runs fn in a transaction, running it again from the
+// start if it loses a version race, up to maxConflictRetries attempts.
+func (s *ExecutionService) withinTxRetryingConflicts(ctx context.Context, what string, fn func(ctx context.Context, repo repository.ExecutionRepository) error) error {
+	for attempt := 1; ; attempt++ {
+		err := s.Repo.WithinTx(ctx, fn)
+		if !errors.Is(err, repository.ErrVersionConflict) || attempt == maxConflictRetries {
+			return err
+		}
+		s.Logger.Debug(what+" lost a version race, retrying", zap.Int("attempt", attempt))
+	}
+}
+
+// AmendExecution changes the ordered quantity and/or limit price of an open
+// execution, bumps its version and publishes the amended state as an
+// acknowledgement. The quantity may not drop below what is already filled;
+// amending it down to exactly the filled quantity completes the execution.
+// If a fill or cancel lands between the read and the write, the amendment is
+// re-validated against the new state and retried. The amendment is rolled back
+// if the acknowledgement cannot be published.
+func (s *ExecutionService) AmendExecution(ctx context.Context, amend *domain.AmendDTO) (*repository.Execution, error) {
+	return s.amendExecution(ctx, amend, byExecutionServiceID(amend.ExecutionServiceID))
+}
+
+// AmendExecutionByID is AmendExecution for the execution with internal id,
+// whichever intake the order came through; amend.ExecutionServiceID is ignored.
+func (s *ExecutionService) AmendExecutionByID(ctx context.Context, id int, amend *domain.AmendDTO) (*repository.Execution, error) {
+	return s.amendExecution(ctx, amend, byID(id))
+}
+
+func (s *ExecutionService) amendExecution(ctx context.Context, amend *domain.AmendDTO, lookup executionLookup) (*repository.Execution, error) {
+	if amend.QuantityOrdered == nil && amend.LimitPrice == nil {
+		return nil, fmt.Errorf("%w: nothing to amend", ErrInvalidAmend)
+	}
+	var exec *repository.Execution
+	err := s.withinTxRetryingConflicts(ctx, "amend", func(ctx context.Context, repo repository.ExecutionRepository) error {
+		var err error
+		exec, err = s.applyAmend(ctx, repo, amend, lookup)
+		if err != nil {
+			return err
+		}
+		return s.publish(ctx, repo, exec, replaceEvent(exec, s.now(), s.averagePriceRounding()))
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.Logger.Debug("execution amended",
+		zap.Int("id", exec.ID),
+		zap.Stringer("quantity", exec.QuantityOrdered),
+		zap.Int("version", exec.Version))
+	return exec, nil
+}
+
+// applyAmend reads the execution, applies the amendment and writes it back
+// conditional on the version read.
+func (s *ExecutionService) applyAmend(ctx context.Context, repo repository.ExecutionRepository, amend *domain.AmendDTO, lookup executionLookup) (*repository.Execution, error) {
+	exec, err := lookup(ctx, repo)
+	if err != nil {
+		return nil, err
+	}
+	if !exec.IsOpen {
+		return nil, ErrExecutionClosed
+	}
+
+	if amend.QuantityOrdered != nil {
+		qty := amend.QuantityOrdered.Decimal
+		if !qty.IsPositive() {
+			return nil, fmt.Errorf("%w: quantity must be positive", ErrInvalidAmend)
+		}
+		if qty.LessThan(exec.QuantityFilled) {
+			return nil, fmt.Errorf("%w: quantity %v is below quantity filled %v", ErrInvalidAmend, qty, exec.QuantityFilled)
+		}
+		exec.QuantityOrdered = qty
+		if exec.QuantityFilled.GreaterThanOrEqual(exec.QuantityOrdered) {
+			if err := s.transition(ctx, exec, domain.StatusFilled); err != nil {
+				return nil, err
+			}
+			exec.NextFillTimestamp = sqlNullTime(nil)
+		}
+	}
+	if amend.LimitPrice != nil {
+		exec.LimitPrice = sqlNullDecimal(normalizeLimitPrice(&amend.LimitPrice.Decimal))
+	}
+
+	if err := repo.Update(ctx, exec); err != nil {
+		return nil, err
 	}
-	return fill
+	return exec, nil
 }
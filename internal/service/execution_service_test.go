@@ -3,11 +3,33 @@ package service
 import (
 	"context"
 	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
 	"testing"
+	"time"
 
+	"github.com/kasbench/globeco-fix-engine/internal/clock"
+	"github.com/kasbench/globeco-fix-engine/internal/config"
+	"github.com/kasbench/globeco-fix-engine/internal/domain"
+	"github.com/kasbench/globeco-fix-engine/internal/fix"
+	"github.com/kasbench/globeco-fix-engine/internal/metrics"
 	"github.com/kasbench/globeco-fix-engine/internal/repository"
+	"github.com/lib/pq"
+	"github.com/segmentio/kafka-go"
+	"github.com/shopspring/decimal"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	"go.uber.org/zap"
 )
 
 type mockRepo struct {
@@ -56,13 +78,14 @@ func (m *mockSecurityClient) GetTickerBySecurityID(ctx context.Context, security
 
 func TestCalculateFillQuantity(t *testing.T) {
 	// Test edge cases for fill quantity logic
-	assert.Equal(t, 0.0, calculateFillQuantity(0))
-	assert.Equal(t, 100.0, calculateFillQuantity(100))
-	assert.Equal(t, 0.0, calculateFillQuantity(-50))
+	rng := rand.New(rand.NewSource(1))
+	assert.Equal(t, "0", calculateFillQuantity(rng, decimal.Zero).String())
+	assert.Equal(t, "100", calculateFillQuantity(rng, dec(100)).String())
+	assert.Equal(t, "0", calculateFillQuantity(rng, dec(-50)).String())
 	// For >100, should be <= 10000
 	for i := 0; i < 100; i++ {
-		fill := calculateFillQuantity(20000)
-		assert.LessOrEqual(t, fill, 10000.0)
+		fill := calculateFillQuantity(rng, dec(20000))
+		assert.True(t, fill.LessThanOrEqual(dec(10000)), fill)
 	}
 }
 
@@ -77,56 +100,82 @@ func TestFillStatusTransitions(t *testing.T) {
 		Destination:        "DEST",
 		SecurityID:         "SECID123",
 		Ticker:             "AAPL",
-		QuantityOrdered:    100,
-		QuantityFilled:     0,
+		QuantityOrdered:    dec(100),
+		QuantityFilled:     decimal.Zero,
 		NumberOfFills:      0,
-		TotalAmount:        0,
+		TotalAmount:        decimal.Zero,
 		Version:            1,
 	}
 
 	// Simulate a partial fill
-	fillQty := 40.0
-	exec.QuantityFilled += fillQty
-	exec.TotalAmount += fillQty * 10.0
+	fillQty := dec(40)
+	exec.QuantityFilled = exec.QuantityFilled.Add(fillQty)
+	exec.TotalAmount = exec.TotalAmount.Add(fillQty.Mul(dec(10)))
 	exec.NumberOfFills++
-	if exec.QuantityFilled >= exec.QuantityOrdered {
+	if exec.QuantityFilled.GreaterThanOrEqual(exec.QuantityOrdered) {
 		exec.IsOpen = false
 		exec.ExecutionStatus = "FULL"
-	} else if fillQty > 0 {
+	} else if fillQty.IsPositive() {
 		exec.ExecutionStatus = "PART"
 	}
 	assert.True(t, exec.IsOpen)
 	assert.Equal(t, "PART", exec.ExecutionStatus)
 
 	// Simulate a full fill
-	fillQty = 60.0
-	exec.QuantityFilled += fillQty
-	exec.TotalAmount += fillQty * 10.0
+	fillQty = dec(60)
+	exec.QuantityFilled = exec.QuantityFilled.Add(fillQty)
+	exec.TotalAmount = exec.TotalAmount.Add(fillQty.Mul(dec(10)))
 	exec.NumberOfFills++
-	if exec.QuantityFilled >= exec.QuantityOrdered {
+	if exec.QuantityFilled.GreaterThanOrEqual(exec.QuantityOrdered) {
 		exec.IsOpen = false
 		exec.ExecutionStatus = "FULL"
-	} else if fillQty > 0 {
+	} else if fillQty.IsPositive() {
 		exec.ExecutionStatus = "PART"
 	}
 	assert.False(t, exec.IsOpen)
 	assert.Equal(t, "FULL", exec.ExecutionStatus)
 }
 
+func TestTransitionRefusesInvalidMoves(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	provider := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+	em, err := metrics.NewExecutionMetrics(provider.Meter("test"))
+	require.NoError(t, err)
+	svc := &ExecutionService{Logger: zap.NewNop(), ExecutionMetrics: em}
+	ctx := context.Background()
+	exec := &repository.Execution{ExecutionServiceID: 1, IsOpen: true, ExecutionStatus: "PART"}
+
+	require.NoError(t, svc.transition(ctx, exec, domain.StatusPartial), "staying put is not a transition")
+	require.NoError(t, svc.transition(ctx, exec, domain.StatusFilled))
+	assert.False(t, exec.IsOpen)
+
+	err = svc.transition(ctx, exec, domain.StatusPartial)
+	assert.ErrorIs(t, err, domain.ErrInvalidTransition)
+	assert.Equal(t, "FULL", exec.ExecutionStatus)
+	assert.False(t, exec.IsOpen)
+
+	var rm metricdata.ResourceMetrics
+	require.NoError(t, reader.Collect(ctx, &rm))
+	require.Len(t, rm.ScopeMetrics, 1)
+	sum := rm.ScopeMetrics[0].Metrics[0].Data.(metricdata.Sum[float64])
+	require.Len(t, sum.DataPoints, 1)
+	assert.Equal(t, 1.0, sum.DataPoints[0].Value)
+}
+
 func TestPriceCheckBlocksFill(t *testing.T) {
 	exec := &repository.Execution{
 		TradeType:      "BUY",
-		LimitPrice:     toNullFloat64(100.0),
+		LimitPrice:     toNullDecimal(100.0),
 		Ticker:         "AAPL",
-		QuantityFilled: 0,
+		QuantityFilled: decimal.Zero,
 	}
 	pricing := &mockPricingClient{price: 120.0}
 	// Price is above limit, should block fill
-	fillQty := 50.0
-	if (exec.TradeType == "BUY" || exec.TradeType == "COVER") && exec.LimitPrice.Valid && pricing.price > exec.LimitPrice.Float64 {
-		fillQty = 0
+	fillQty := dec(50)
+	if (exec.TradeType == "BUY" || exec.TradeType == "COVER") && exec.LimitPrice.Valid && dec(pricing.price).GreaterThan(exec.LimitPrice.Decimal) {
+		fillQty = decimal.Zero
 	}
-	assert.Equal(t, 0.0, fillQty)
+	assert.True(t, fillQty.IsZero())
 }
 
 func TestRepositoryUpdateError(t *testing.T) {
@@ -149,8 +198,665 @@ func TestExternalServiceClientErrors(t *testing.T) {
 	assert.EqualError(t, err, "pricing error")
 }
 
-func toNullFloat64(f float64) (nf sql.NullFloat64) {
-	nf.Valid = true
-	nf.Float64 = f
-	return
+func toNullDecimal(f float64) decimal.NullDecimal {
+	return decimal.NewNullDecimal(dec(f))
+}
+
+// dec converts a test literal to a decimal.
+func dec(f float64) decimal.Decimal {
+	return decimal.NewFromFloat(f)
+}
+
+func TestAmendExecution(t *testing.T) {
+	sessionID := fix.SessionID{BeginString: fix.BeginString, SenderCompID: "GLOBECO", TargetCompID: "CLIENT"}
+	repo := &fakeRepo{execs: map[int]*repository.Execution{
+		3: {ID: 3, IsOpen: true, ExecutionStatus: "PART", TradeType: "BUY", QuantityOrdered: dec(100), QuantityFilled: dec(40), TotalAmount: dec(400),
+			LimitPrice: toNullDecimal(10), Version: 1,
+			FIXSessionID: sql.NullString{String: sessionID.String(), Valid: true}, ClOrdID: sql.NullString{String: "ORD-9", Valid: true}},
+	}}
+	sender := &recordingSender{sessions: []fix.SessionID{sessionID}}
+	svc := &ExecutionService{Repo: repo, Logger: zap.NewNop(), FIXSender: sender}
+	ctx := context.Background()
+	qty := func(v float64) *domain.Decimal { d := domain.NewDecimal(dec(v)); return &d }
+
+	_, err := svc.AmendExecutionByID(ctx, 3, &domain.AmendDTO{})
+	assert.ErrorIs(t, err, ErrInvalidAmend)
+	_, err = svc.AmendExecutionByID(ctx, 3, &domain.AmendDTO{QuantityOrdered: qty(30)})
+	assert.ErrorIs(t, err, ErrInvalidAmend)
+	_, err = svc.AmendExecutionByID(ctx, 404, &domain.AmendDTO{QuantityOrdered: qty(30)})
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+
+	exec, err := svc.AmendExecutionByID(ctx, 3, &domain.AmendDTO{QuantityOrdered: qty(200), LimitPrice: qty(0)})
+	assert.NoError(t, err)
+	assert.Equal(t, "200", exec.QuantityOrdered.String())
+	assert.False(t, exec.LimitPrice.Valid, "a zero limit price makes the order a market order")
+	assert.Equal(t, 2, exec.Version)
+	assert.True(t, exec.IsOpen)
+	assert.Equal(t, "200", repo.execs[3].QuantityOrdered.String())
+	_, err = svc.relayFIXOutbox(ctx, sessionID, 10)
+	require.NoError(t, err)
+	report, err := fix.ParseExecutionReport(sender.sent[0])
+	assert.NoError(t, err)
+	assert.Equal(t, fix.ExecTypeReplaced, report.ExecType)
+	assert.Equal(t, "160", report.LeavesQty.String())
+
+	// Amending down to the filled quantity completes the execution.
+	exec, err = svc.AmendExecutionByID(ctx, 3, &domain.AmendDTO{QuantityOrdered: qty(40)})
+	assert.NoError(t, err)
+	assert.False(t, exec.IsOpen)
+	assert.Equal(t, "FULL", exec.ExecutionStatus)
+	assert.Equal(t, 3, exec.Version)
+
+	_, err = svc.AmendExecutionByID(ctx, 3, &domain.AmendDTO{QuantityOrdered: qty(50)})
+	assert.ErrorIs(t, err, ErrExecutionClosed)
+}
+
+// racingRepo simulates another writer filling the execution between each read
+// and write, for the first races updates.
+type racingRepo struct {
+	*fakeRepo
+	races int
+}
+
+func (r *racingRepo) WithinTx(ctx context.Context, fn func(ctx context.Context, repo repository.ExecutionRepository) error) error {
+	return fn(ctx, r)
+}
+
+func (r *racingRepo) Update(ctx context.Context, exec *repository.Execution) error {
+	if r.races > 0 {
+		r.races--
+		current := r.execs[exec.ID]
+		current.QuantityFilled = current.QuantityFilled.Add(dec(10))
+		current.Version++
+	}
+	return r.fakeRepo.Update(ctx, exec)
+}
+
+func TestAmendExecution_RetriesVersionConflicts(t *testing.T) {
+	newRepo := func(races int) *racingRepo {
+		return &racingRepo{fakeRepo: &fakeRepo{execs: map[int]*repository.Execution{
+			3: {ID: 3, ExecutionServiceID: 9, IsOpen: true, ExecutionStatus: "PART", TradeType: "BUY", QuantityOrdered: dec(100), QuantityFilled: dec(40), Version: 1},
+		}}, races: races}
+	}
+	ctx := context.Background()
+	qty := func(v float64) *domain.Decimal { d := domain.NewDecimal(dec(v)); return &d }
+
+	// The amendment is re-applied on top of the concurrent fill rather than clobbering it.
+	repo := newRepo(1)
+	svc := &ExecutionService{Repo: repo, Logger: zap.NewNop(), FIXSender: &recordingSender{}}
+	exec, err := svc.AmendExecution(ctx, &domain.AmendDTO{ExecutionServiceID: 9, QuantityOrdered: qty(200)})
+	assert.NoError(t, err)
+	assert.Equal(t, "200", exec.QuantityOrdered.String())
+	assert.Equal(t, "50", exec.QuantityFilled.String())
+	assert.Equal(t, 3, exec.Version)
+	assert.Equal(t, "50", repo.execs[3].QuantityFilled.String())
+
+	// The new state is re-validated on each attempt.
+	repo = newRepo(1)
+	svc.Repo = repo
+	_, err = svc.AmendExecution(ctx, &domain.AmendDTO{ExecutionServiceID: 9, QuantityOrdered: qty(45)})
+	assert.ErrorIs(t, err, ErrInvalidAmend)
+
+	// An execution that keeps changing under the amendment gives up with the conflict.
+	repo = newRepo(maxConflictRetries)
+	svc.Repo = repo
+	_, err = svc.AmendExecution(ctx, &domain.AmendDTO{ExecutionServiceID: 9, QuantityOrdered: qty(200)})
+	var conflict *repository.VersionConflictError
+	assert.ErrorAs(t, err, &conflict)
+	assert.Equal(t, "100", repo.execs[3].QuantityOrdered.String())
+}
+
+func TestCancelExecution_RetriesVersionConflicts(t *testing.T) {
+	repo := &racingRepo{fakeRepo: &fakeRepo{execs: map[int]*repository.Execution{
+		3: {ID: 3, ExecutionServiceID: 9, IsOpen: true, ExecutionStatus: "PART", TradeType: "BUY", QuantityOrdered: dec(100), QuantityFilled: dec(40), Version: 1,
+			NextFillTimestamp: sql.NullTime{Time: time.Now(), Valid: true}},
+	}}, races: 1}
+	svc := &ExecutionService{Repo: repo, Logger: zap.NewNop()}
+	ctx := context.Background()
+
+	// The cancel is re-applied on top of the concurrent fill rather than clobbering it.
+	exec, err := svc.CancelExecution(ctx, 9)
+	assert.NoError(t, err)
+	assert.Equal(t, "CANC", exec.ExecutionStatus)
+	assert.Equal(t, 3, exec.Version)
+	assert.Equal(t, "50", repo.execs[3].QuantityFilled.String())
+	assert.False(t, repo.execs[3].IsOpen)
+	assert.False(t, repo.execs[3].NextFillTimestamp.Valid)
+
+	_, err = svc.CancelExecution(ctx, 9)
+	assert.ErrorIs(t, err, ErrExecutionClosed)
+	_, err = svc.CancelExecution(ctx, 404)
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+}
+
+// recordingDLQ records the reason codes of dead-lettered messages.
+type recordingDLQ struct {
+	reasonCodes []string
+}
+
+func (q *recordingDLQ) Publish(ctx context.Context, m kafka.Message, reasonCode, reason string) error {
+	q.reasonCodes = append(q.reasonCodes, reasonCode)
+	return nil
+}
+
+func TestHandleCancel(t *testing.T) {
+	defer func(wait time.Duration) { unknownExecutionWait = wait }(unknownExecutionWait)
+	unknownExecutionWait = 50 * time.Millisecond
+	repo := &fakeRepo{execs: map[int]*repository.Execution{
+		3: {ID: 3, ExecutionServiceID: 9, IsOpen: true, ExecutionStatus: "WORK", TradeType: "BUY", QuantityOrdered: dec(100), Version: 1},
+	}}
+	dlq := &recordingDLQ{}
+	svc := &ExecutionService{Repo: repo, Logger: zap.NewNop(), DLQ: dlq}
+	ctx := context.Background()
+	cancel := func(value string) kafka.Message { return kafka.Message{Topic: "cancels", Value: []byte(value)} }
+
+	assert.True(t, svc.handleRequest(ctx, cancel(`{"executionServiceId": 9}`), "cancel", svc.handleCancel))
+	assert.Equal(t, "CANC", repo.execs[3].ExecutionStatus)
+	require.Len(t, repo.outbox, 1)
+
+	// A redelivered cancel finds the execution already closed and is done.
+	assert.True(t, svc.handleRequest(ctx, cancel(`{"executionServiceId": 9}`), "cancel", svc.handleCancel))
+	assert.Len(t, repo.outbox, 1)
+	assert.Empty(t, dlq.reasonCodes)
+
+	// Malformed cancels are dead-lettered straight away, and cancels for an
+	// order that never arrives once they have waited for it.
+	assert.True(t, svc.handleRequest(ctx, cancel(`{"executionServiceId": `), "cancel", svc.handleCancel))
+	assert.True(t, svc.handleRequest(ctx, cancel(`{"executionServiceId": 404}`), "cancel", svc.handleCancel))
+	assert.Equal(t, []string{domain.RejectReasonInvalidMessage, domain.DeadLetterReasonUnknownExecution}, dlq.reasonCodes)
+}
+
+func TestHandleAmend(t *testing.T) {
+	defer func(wait time.Duration) { unknownExecutionWait = wait }(unknownExecutionWait)
+	unknownExecutionWait = 50 * time.Millisecond
+	repo := &racingRepo{fakeRepo: &fakeRepo{execs: map[int]*repository.Execution{
+		3: {ID: 3, ExecutionServiceID: 9, IsOpen: true, ExecutionStatus: "PART", TradeType: "BUY", QuantityOrdered: dec(100), QuantityFilled: dec(40), Version: 1},
+	}}, races: maxConflictRetries}
+	dlq := &recordingDLQ{}
+	svc := &ExecutionService{Repo: repo, Logger: zap.NewNop(), DLQ: dlq}
+	ctx := context.Background()
+	amend := func(value string) kafka.Message { return kafka.Message{Topic: "amends", Value: []byte(value)} }
+
+	// An amendment that exhausts its version conflict retries is retried again
+	// rather than dropped.
+	assert.True(t, svc.handleRequest(ctx, amend(`{"executionServiceId": 9, "quantity": 200}`), "amend", svc.handleAmend))
+	assert.Equal(t, "200", repo.execs[3].QuantityOrdered.String())
+	assert.Empty(t, dlq.reasonCodes)
+
+	// Amendments that can never apply are dead-lettered.
+	assert.True(t, svc.handleRequest(ctx, amend(`{"executionServiceId": 9, "quantity": 10}`), "amend", svc.handleAmend))
+	assert.True(t, svc.handleRequest(ctx, amend(`{"executionServiceId": `), "amend", svc.handleAmend))
+	assert.True(t, svc.handleRequest(ctx, amend(`{"executionServiceId": 404, "quantity": 200}`), "amend", svc.handleAmend))
+	_, err := svc.CancelExecution(ctx, 9)
+	require.NoError(t, err)
+	assert.True(t, svc.handleRequest(ctx, amend(`{"executionServiceId": 9, "quantity": 300}`), "amend", svc.handleAmend))
+	assert.Equal(t, []string{
+		domain.DeadLetterReasonInvalidAmend,
+		domain.RejectReasonInvalidMessage,
+		domain.DeadLetterReasonUnknownExecution,
+		domain.DeadLetterReasonExecutionClosed,
+	}, dlq.reasonCodes)
+}
+
+func TestHandleRequest_RetriesUntilProcessed(t *testing.T) {
+	dlq := &recordingDLQ{}
+	svc := &ExecutionService{Logger: zap.NewNop(), DLQ: dlq}
+	ctx := context.Background()
+	failing := func(reasonCode string, err error, failures int) requestHandler {
+		attempts := 0
+		return func(ctx context.Context, m kafka.Message) (string, error) {
+			if attempts++; attempts <= failures {
+				return reasonCode, err
+			}
+			return "", nil
+		}
+	}
+
+	// Transient failures, and a request that overtook its order, are retried
+	// rather than dead-lettered.
+	assert.True(t, svc.handleRequest(ctx, kafka.Message{}, "cancel", failing("", errors.New("connection refused"), 2)))
+	assert.True(t, svc.handleRequest(ctx, kafka.Message{}, "cancel", failing(domain.DeadLetterReasonUnknownExecution, sql.ErrNoRows, 2)))
+	assert.Empty(t, dlq.reasonCodes)
+
+	ctx, stop := context.WithCancel(ctx)
+	stop()
+	assert.False(t, svc.handleRequest(ctx, kafka.Message{}, "cancel", failing("", errors.New("connection refused"), 1)))
+}
+
+// flakyReader fails its first failures fetches, then serves one message and
+// cancels the consumer once it is committed.
+type flakyReader struct {
+	failures  int
+	fetches   []time.Time
+	committed []kafka.Message
+	stop      context.CancelFunc
+}
+
+func (r *flakyReader) FetchMessage(ctx context.Context) (kafka.Message, error) {
+	r.fetches = append(r.fetches, time.Now())
+	if len(r.fetches) <= r.failures {
+		return kafka.Message{}, errors.New("broker not available")
+	}
+	if len(r.committed) > 0 {
+		<-ctx.Done()
+		return kafka.Message{}, ctx.Err()
+	}
+	return kafka.Message{Offset: 7}, nil
+}
+
+func (r *flakyReader) CommitMessages(ctx context.Context, msgs ...kafka.Message) error {
+	r.committed = append(r.committed, msgs...)
+	r.stop()
+	return nil
+}
+
+func TestConsumeRequests_BacksOffOnFetchErrors(t *testing.T) {
+	defer func(initial, max time.Duration) { fetchInitialBackoff, fetchMaxBackoff = initial, max }(fetchInitialBackoff, fetchMaxBackoff)
+	fetchInitialBackoff, fetchMaxBackoff = 20*time.Millisecond, 40*time.Millisecond
+	ctx, stop := context.WithCancel(context.Background())
+	defer stop()
+	reader := &flakyReader{failures: 3, stop: stop}
+	svc := &ExecutionService{Logger: zap.NewNop()}
+
+	svc.consumeRequests(ctx, reader, "cancel", func(ctx context.Context, m kafka.Message) (string, error) { return "", nil })
+
+	require.Len(t, reader.fetches, 5)
+	for i, want := range []time.Duration{20 * time.Millisecond, 40 * time.Millisecond, 40 * time.Millisecond} {
+		assert.GreaterOrEqual(t, reader.fetches[i+1].Sub(reader.fetches[i]), want)
+	}
+	assert.Equal(t, []kafka.Message{{Offset: 7}}, reader.committed)
+}
+
+func TestRejectReasonFor(t *testing.T) {
+	cases := []struct {
+		err      error
+		fallback string
+		want     string
+	}{
+		{fmt.Errorf("%w: GTD requires expireTimestamp", ErrInvalidOrder), domain.RejectReasonSecurityLookup, domain.RejectReasonInvalidOrder},
+		{fmt.Errorf("%w: SEC", ErrSecurityNotFound), domain.RejectReasonSecurityLookup, domain.RejectReasonUnknownSecurity},
+		{errors.New("security service returned status 503"), domain.RejectReasonSecurityLookup, domain.RejectReasonSecurityLookup},
+		{errors.New("connection refused"), domain.RejectReasonPersistence, domain.RejectReasonPersistence},
+		{&pq.Error{Code: "22001"}, domain.RejectReasonPersistence, domain.RejectReasonInvalidOrder},
+		{fmt.Errorf("insert: %w", &pq.Error{Code: "23514"}), domain.RejectReasonPersistence, domain.RejectReasonInvalidOrder},
+		{&pq.Error{Code: "40001"}, domain.RejectReasonPersistence, domain.RejectReasonPersistence},
+	}
+	for _, c := range cases {
+		assert.Equal(t, c.want, rejectReasonFor(c.err, c.fallback), c.err.Error())
+	}
+}
+
+func TestTransientPersistenceError(t *testing.T) {
+	assert.True(t, transientPersistenceError(&pq.Error{Code: "08006"}))
+	assert.True(t, transientPersistenceError(&pq.Error{Code: "40001"}))
+	assert.True(t, transientPersistenceError(&pq.Error{Code: "40P01"}))
+	assert.True(t, transientPersistenceError(&pq.Error{Code: "57P01"}))
+	assert.True(t, transientPersistenceError(fmt.Errorf("create: %w", driver.ErrBadConn)))
+	assert.True(t, transientPersistenceError(&net.OpError{Op: "dial", Err: errors.New("connection refused")}))
+	assert.False(t, transientPersistenceError(&pq.Error{Code: "42703"}))
+	assert.False(t, transientPersistenceError(errors.New("sql: unsupported type")))
+}
+
+// failingCreateRepo fails Create with errs, in turn, before storing executions.
+type failingCreateRepo struct {
+	*fakeRepo
+	errs    []error
+	creates int
+}
+
+func (r *failingCreateRepo) WithinTx(ctx context.Context, fn func(ctx context.Context, repo repository.ExecutionRepository) error) error {
+	return fn(ctx, r)
+}
+
+func (r *failingCreateRepo) Create(ctx context.Context, exec *repository.Execution) error {
+	if r.creates++; r.creates <= len(r.errs) {
+		return r.errs[r.creates-1]
+	}
+	return r.fakeRepo.Create(ctx, exec)
+}
+
+func TestIngestOrderRetrying_PersistenceFailures(t *testing.T) {
+	defer func(attempts int) { maxPersistenceAttempts = attempts }(maxPersistenceAttempts)
+	maxPersistenceAttempts = 3
+	ctx := context.Background()
+	order := []byte(`{"id": 101, "tradeType": "BUY", "destination": "ML", "securityId": "SEC1", "quantity": 100, "version": 1}`)
+	newService := func(errs ...error) (*ExecutionService, *failingCreateRepo, *recordingWriter, *recordingDLQ) {
+		repo := &failingCreateRepo{fakeRepo: &fakeRepo{execs: map[int]*repository.Execution{}}, errs: errs}
+		rejects := &recordingWriter{}
+		dlq := &recordingDLQ{}
+		return &ExecutionService{Repo: repo, RejectsProducer: rejects, DLQ: dlq, SecurityClient: newTestSecurityClient(t, "SEC1", "IBM"), Logger: zap.NewNop()}, repo, rejects, dlq
+	}
+
+	// A value the database refuses is rejected and dead-lettered without retrying.
+	svc, repo, rejects, dlq := newService(&pq.Error{Code: "22001", Message: "value too long for type character varying(20)"})
+	var postDTO domain.ExecutionDTO
+	_, reasonCode, err := svc.ingestOrderRetrying(ctx, order, &postDTO)
+	require.Error(t, err)
+	assert.Equal(t, domain.RejectReasonInvalidOrder, reasonCode)
+	assert.Equal(t, 1, repo.creates)
+	require.NoError(t, svc.failOrder(ctx, kafka.Message{Value: order}, &postDTO, reasonCode, err))
+	assert.Len(t, rejects.msgs, 1)
+	assert.Equal(t, []string{domain.RejectReasonInvalidOrder}, dlq.reasonCodes)
+
+	// Serialization failures are retried.
+	svc, repo, _, _ = newService(&pq.Error{Code: "40001"})
+	exec, _, err := svc.ingestOrderRetrying(ctx, order, &domain.ExecutionDTO{})
+	require.NoError(t, err)
+	assert.Equal(t, 101, exec.ExecutionServiceID)
+	assert.Equal(t, 2, repo.creates)
+
+	// Connection failures are retried a limited number of times, then the
+	// order is dead-lettered but not rejected.
+	lost := &pq.Error{Code: "08006"}
+	svc, repo, rejects, dlq = newService(lost, lost, lost, lost)
+	postDTO = domain.ExecutionDTO{}
+	_, reasonCode, err = svc.ingestOrderRetrying(ctx, order, &postDTO)
+	require.Error(t, err)
+	assert.Equal(t, domain.RejectReasonPersistence, reasonCode)
+	assert.Equal(t, 3, repo.creates)
+	require.NoError(t, svc.failOrder(ctx, kafka.Message{Value: order}, &postDTO, reasonCode, err))
+	assert.Empty(t, rejects.msgs)
+	assert.Equal(t, []string{domain.RejectReasonPersistence}, dlq.reasonCodes)
+
+	// Other database failures are dead-lettered straight away.
+	svc, repo, _, _ = newService(&pq.Error{Code: "42703"})
+	_, reasonCode, err = svc.ingestOrderRetrying(ctx, order, &domain.ExecutionDTO{})
+	require.Error(t, err)
+	assert.Equal(t, domain.RejectReasonPersistence, reasonCode)
+	assert.Equal(t, 1, repo.creates)
+}
+
+func TestFailOrder(t *testing.T) {
+	rejects := &recordingWriter{}
+	dlq := &recordingDLQ{}
+	svc := &ExecutionService{RejectsProducer: rejects, DLQ: dlq, Logger: zap.NewNop()}
+	ctx := context.Background()
+	order := &domain.ExecutionDTO{ID: 101, TradeType: "BUY", SecurityID: "SEC1", QuantityOrdered: domain.NewDecimal(dec(100))}
+
+	// Orders that can never be accepted are rejected and dead-lettered.
+	require.NoError(t, svc.failOrder(ctx, kafka.Message{}, order, domain.RejectReasonUnknownSecurity, fmt.Errorf("%w: SEC1", ErrSecurityNotFound)))
+	require.Len(t, rejects.msgs, 1)
+	var reject domain.RejectDTO
+	require.NoError(t, json.Unmarshal(rejects.msgs[0].Value, &reject))
+	assert.Equal(t, 101, reject.ExecutionServiceID)
+	assert.Equal(t, domain.RejectReasonUnknownSecurity, reject.ReasonCode)
+
+	// Transient failures are not final, so they are only dead-lettered.
+	require.NoError(t, svc.failOrder(ctx, kafka.Message{}, order, domain.RejectReasonSecurityLookup, errors.New("security service returned status 503")))
+	require.NoError(t, svc.failOrder(ctx, kafka.Message{}, order, domain.RejectReasonPersistence, errors.New("connection refused")))
+	assert.Len(t, rejects.msgs, 1)
+	assert.Equal(t, []string{domain.RejectReasonUnknownSecurity, domain.RejectReasonSecurityLookup, domain.RejectReasonPersistence}, dlq.reasonCodes)
+}
+
+func TestFailOrder_RedrivenRejectIsLaterAccepted(t *testing.T) {
+	known := false
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !known {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		fmt.Fprint(w, `{"ticker": "IBM"}`)
+	}))
+	defer srv.Close()
+	u, err := url.Parse(srv.URL)
+	require.NoError(t, err)
+	port, err := strconv.Atoi(u.Port())
+	require.NoError(t, err)
+	rejects := &recordingWriter{}
+	dlq := &recordingDLQ{}
+	repo := &fakeRepo{execs: map[int]*repository.Execution{}}
+	svc := &ExecutionService{Repo: repo, RejectsProducer: rejects, DLQ: dlq, SecurityClient: NewSecurityServiceClient(config.ServiceConfig{Host: u.Hostname(), Port: port}), Logger: zap.NewNop()}
+	ctx := context.Background()
+	m := kafka.Message{Value: []byte(`{"id": 101, "tradeType": "BUY", "destination": "ML", "securityId": "SEC9", "quantity": 100, "version": 1}`)}
+
+	var postDTO domain.ExecutionDTO
+	_, reasonCode, err := svc.ingestOrder(ctx, m.Value, &postDTO)
+	require.Error(t, err)
+	require.NoError(t, svc.failOrder(ctx, m, &postDTO, reasonCode, err))
+	require.Len(t, rejects.msgs, 1)
+	assert.Equal(t, []string{domain.RejectReasonUnknownSecurity}, dlq.reasonCodes)
+
+	// Once the security is set up, re-driving the order accepts it: REJ is
+	// followed by NEW for the same order.
+	known = true
+	exec, _, err := svc.ingestOrder(ctx, m.Value, &domain.ExecutionDTO{})
+	require.NoError(t, err)
+	assert.Equal(t, 101, exec.ExecutionServiceID)
+	require.Len(t, repo.outbox, 1)
+	var event domain.ExecutionEventDTO
+	require.NoError(t, json.Unmarshal(repo.outbox[0].Payload, &event))
+	assert.Equal(t, domain.EventTypeNew, event.EventType)
+	assert.Equal(t, 101, event.ExecutionServiceID)
+}
+
+// newTestSecurityClient returns a client for a fake Security Service that knows a single security.
+func newTestSecurityClient(t *testing.T, securityID, ticker string) *SecurityServiceClient {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/security/"+securityID {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		fmt.Fprintf(w, `{"ticker": %q}`, ticker)
+	}))
+	t.Cleanup(srv.Close)
+	u, err := url.Parse(srv.URL)
+	require.NoError(t, err)
+	port, err := strconv.Atoi(u.Port())
+	require.NoError(t, err)
+	return NewSecurityServiceClient(config.ServiceConfig{Host: u.Hostname(), Port: port})
+}
+
+func TestIngestOrder(t *testing.T) {
+	repo := &fakeRepo{execs: map[int]*repository.Execution{}}
+	svc := &ExecutionService{Repo: repo, SecurityClient: newTestSecurityClient(t, "SEC1", "IBM"), Logger: zap.NewNop()}
+	ctx := context.Background()
+	order := []byte(`{"id": 101, "tradeType": "BUY", "destination": "ML", "securityId": "SEC1", "quantity": 100, "version": 1}`)
+
+	exec, _, err := svc.ingestOrder(ctx, order, &domain.ExecutionDTO{})
+	require.NoError(t, err)
+	assert.Equal(t, "IBM", exec.Ticker)
+
+	// Redelivery of the same order is harmless.
+	_, _, err = svc.ingestOrder(ctx, order, &domain.ExecutionDTO{})
+	assert.NoError(t, err)
+	assert.Len(t, repo.execs, 1)
+	require.Len(t, repo.outbox, 1)
+	var event domain.ExecutionEventDTO
+	require.NoError(t, json.Unmarshal(repo.outbox[0].Payload, &event))
+	assert.Equal(t, domain.EventTypeNew, event.EventType)
+	assert.Equal(t, 101, event.ExecutionServiceID)
+
+	_, reasonCode, err := svc.ingestOrder(ctx, []byte(`{"id": `), &domain.ExecutionDTO{})
+	assert.Error(t, err)
+	assert.Equal(t, domain.RejectReasonInvalidMessage, reasonCode)
+
+	_, reasonCode, err = svc.ingestOrder(ctx, []byte(`{"id": 102, "tradeType": "BUY", "securityId": "NOPE", "quantity": 1}`), &domain.ExecutionDTO{})
+	assert.ErrorIs(t, err, ErrSecurityNotFound)
+	assert.Equal(t, domain.RejectReasonUnknownSecurity, reasonCode)
+
+	// Orders that could never be filled are rejected as invalid.
+	for _, invalid := range []string{
+		`{"id": 103, "tradeType": "BUY", "securityId": "SEC1", "quantity": 0}`,
+		`{"id": 104, "tradeType": "BUY", "securityId": "SEC1", "quantity": -5}`,
+		`{"id": 105, "securityId": "SEC1", "quantity": 100}`,
+		`{"id": 106, "tradeType": "HOLD", "securityId": "SEC1", "quantity": 100}`,
+	} {
+		_, reasonCode, err = svc.ingestOrder(ctx, []byte(invalid), &domain.ExecutionDTO{})
+		assert.ErrorIs(t, err, ErrInvalidOrder, invalid)
+		assert.Equal(t, domain.RejectReasonInvalidOrder, reasonCode, invalid)
+	}
+	assert.Len(t, repo.execs, 1)
+}
+
+func TestIngestOrderRetrying(t *testing.T) {
+	failures := 2
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if failures > 0 {
+			failures--
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprint(w, `{"ticker": "IBM"}`)
+	}))
+	defer srv.Close()
+	u, err := url.Parse(srv.URL)
+	require.NoError(t, err)
+	port, err := strconv.Atoi(u.Port())
+	require.NoError(t, err)
+	repo := &fakeRepo{execs: map[int]*repository.Execution{}}
+	svc := &ExecutionService{Repo: repo, SecurityClient: NewSecurityServiceClient(config.ServiceConfig{Host: u.Hostname(), Port: port}), Logger: zap.NewNop()}
+	ctx := context.Background()
+	order := []byte(`{"id": 101, "tradeType": "BUY", "destination": "ML", "securityId": "SEC1", "quantity": 100, "version": 1}`)
+
+	// Security Service outages are waited out rather than failing the order.
+	exec, _, err := svc.ingestOrderRetrying(ctx, order, &domain.ExecutionDTO{})
+	require.NoError(t, err)
+	assert.Equal(t, "IBM", exec.Ticker)
+	assert.Len(t, repo.execs, 1)
+
+	// Orders that can never be accepted fail straight away.
+	_, reasonCode, err := svc.ingestOrderRetrying(ctx, []byte(`{"id": `), &domain.ExecutionDTO{})
+	assert.Error(t, err)
+	assert.Equal(t, domain.RejectReasonInvalidMessage, reasonCode)
+
+	// Shutdown ends the wait without an outcome. SEC1's ticker is cached by now, so look up another.
+	failures = 1000
+	ctx, cancel := context.WithCancel(ctx)
+	cancel()
+	exec, reasonCode, err = svc.ingestOrderRetrying(ctx, []byte(`{"id": 102, "tradeType": "BUY", "securityId": "SEC2", "quantity": 1}`), &domain.ExecutionDTO{})
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Nil(t, exec)
+	assert.Empty(t, reasonCode)
+	assert.Len(t, repo.execs, 1)
+}
+
+func TestPublishFillEvents(t *testing.T) {
+	repo := &fakeRepo{}
+	svc := &ExecutionService{Logger: zap.NewNop()}
+	ctx := context.Background()
+	filled := time.Unix(1748345329, 0).UTC()
+	exec := &repository.Execution{ID: 7, ExecutionServiceID: 42, IsOpen: true, ExecutionStatus: "PART", TradeType: "BUY",
+		QuantityOrdered: dec(100), QuantityFilled: dec(60), TotalAmount: dec(600), NumberOfFills: 2}
+	events := func() []domain.ExecutionEventDTO {
+		var dtos []domain.ExecutionEventDTO
+		for _, msg := range repo.outbox {
+			var dto domain.ExecutionEventDTO
+			require.NoError(t, json.Unmarshal(msg.Payload, &dto))
+			dtos = append(dtos, dto)
+		}
+		return dtos
+	}
+
+	// Attempts that fill nothing are not published.
+	require.NoError(t, svc.publishFill(ctx, repo, exec, nil, false))
+	assert.Empty(t, repo.outbox)
+
+	require.NoError(t, svc.publishFill(ctx, repo, exec, &repository.Fill{ID: 11, ExecutionID: 7, Quantity: dec(20), Price: dec(10), FillTimestamp: filled}, false))
+	got := events()
+	require.Len(t, got, 1)
+	assert.Equal(t, domain.EventTypePartial, got[0].EventType)
+	assert.Equal(t, int64(11), *got[0].FillID)
+	assert.Equal(t, "20", got[0].LastQuantity.String())
+	assert.Equal(t, "10", got[0].LastPrice.String())
+	assert.Equal(t, domain.EpochTime(1748345329), *got[0].FillTimestamp)
+	assert.Equal(t, "60", got[0].QuantityFilled.String(), "the cumulative snapshot is carried alongside the fill")
+
+	// An IOC fill that leaves a remainder is followed by its cancellation.
+	exec.IsOpen = false
+	exec.ExecutionStatus = "CANC"
+	require.NoError(t, svc.publishFill(ctx, repo, exec, &repository.Fill{ID: 12, ExecutionID: 7, Quantity: dec(10), Price: dec(10), FillTimestamp: filled}, true))
+	got = events()
+	require.Len(t, got, 3)
+	assert.Equal(t, domain.EventTypePartial, got[1].EventType)
+	assert.Equal(t, domain.EventTypeCancel, got[2].EventType)
+	assert.Nil(t, got[2].LastQuantity)
+
+	exec.ExecutionStatus = "FULL"
+	require.NoError(t, svc.publishFill(ctx, repo, exec, &repository.Fill{ID: 13, ExecutionID: 7, Quantity: dec(40), Price: dec(10), FillTimestamp: filled}, false))
+	got = events()
+	assert.Equal(t, domain.EventTypeFill, got[3].EventType)
+}
+
+func TestRetryUntilDone(t *testing.T) {
+	svc := &ExecutionService{Logger: zap.NewNop()}
+	attempts := 0
+	ok := svc.retryUntilDone(context.Background(), "test", func() error {
+		attempts++
+		if attempts < 3 {
+			return errors.New("transient")
+		}
+		return nil
+	})
+	assert.True(t, ok)
+	assert.Equal(t, 3, attempts)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.False(t, svc.retryUntilDone(ctx, "test", func() error { return errors.New("down") }))
+}
+
+func TestProcessNextFillFollowsClock(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"ticker": "IBM", "date": "2026-11-25", "close": 11, "high": 11, "low": 11, "volume": 250000}`)
+	}))
+	defer srv.Close()
+	u, err := url.Parse(srv.URL)
+	require.NoError(t, err)
+	port, err := strconv.Atoi(u.Port())
+	require.NoError(t, err)
+
+	start := time.Date(2026, 11, 25, 14, 30, 0, 0, time.UTC)
+	simClock := clock.NewFixed(start)
+	fillModel, err := NewFillModel(config.FillModelConfig{Model: FillModelFixedRatio, FixedRatio: 0.5, MinDelay: 30, MaxDelay: 30})
+	require.NoError(t, err)
+	repo := &fakeRepo{execs: map[int]*repository.Execution{
+		1: {ID: 1, ExecutionServiceID: 7, IsOpen: true, ExecutionStatus: "WORK", TradeType: "BUY", Ticker: "IBM", QuantityOrdered: dec(100),
+			NextFillTimestamp: sql.NullTime{Time: start, Valid: true}, Version: 1},
+	}}
+	svc := &ExecutionService{
+		Repo:          repo,
+		PricingClient: NewPricingServiceClient(config.ServiceConfig{Host: u.Hostname(), Port: port}, zap.NewNop()),
+		FillModel:     fillModel,
+		Clock:         simClock,
+		Logger:        zap.NewNop(),
+	}
+	ctx := context.Background()
+
+	require.NoError(t, svc.processNextFill(ctx))
+	exec := repo.execs[1]
+	assert.Equal(t, "50", exec.QuantityFilled.String())
+	assert.Equal(t, start, exec.LastFillTimestamp.Time)
+	assert.Equal(t, start.Add(30*time.Second), exec.NextFillTimestamp.Time)
+	assert.Equal(t, start, repo.fills[0].FillTimestamp)
+
+	// Nothing is due until the clock reaches the next fill
+	simClock.Advance(29 * time.Second)
+	assert.ErrorIs(t, svc.processNextFill(ctx), sql.ErrNoRows)
+	simClock.Advance(time.Second)
+	require.NoError(t, svc.processNextFill(ctx))
+	assert.Equal(t, "75", repo.execs[1].QuantityFilled.String())
+	assert.Equal(t, start.Add(30*time.Second), repo.fills[1].FillTimestamp)
+}
+
+func TestPricingServiceClientGetPriceBar(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/price/IBM" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		fmt.Fprint(w, `{"id": 1, "ticker": "IBM", "date": "2025-06-02", "open": 10.5, "close": 11, "high": 11.5, "low": 10, "volume": 250000}`)
+	}))
+	defer srv.Close()
+	u, err := url.Parse(srv.URL)
+	require.NoError(t, err)
+	port, err := strconv.Atoi(u.Port())
+	require.NoError(t, err)
+	client := NewPricingServiceClient(config.ServiceConfig{Host: u.Hostname(), Port: port}, zap.NewNop())
+
+	bar, err := client.GetPriceBar(context.Background(), "IBM")
+	require.NoError(t, err)
+	assert.Equal(t, &PriceBar{ID: 1, Ticker: "IBM", Date: "2025-06-02", Open: 10.5, Close: 11, High: 11.5, Low: 10, Volume: 250000}, bar)
+
+	_, err = client.GetPriceBar(context.Background(), "NOPE")
+	assert.Error(t, err)
 }
@@ -0,0 +1,256 @@
+package service
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"math/rand"
+	"time"
+
+	"github.com/kasbench/globeco-fix-engine/internal/config"
+	"github.com/kasbench/globeco-fix-engine/internal/repository"
+	"github.com/shopspring/decimal"
+)
+
+// Fill model names accepted in FillModelConfig.Model.
+const (
+	FillModelRandom              = "random"
+	FillModelFixedRatio          = "fixed-ratio"
+	FillModelAlwaysFull          = "always-full"
+	FillModelPoisson             = "poisson"
+	FillModelVolumeParticipation = "volume-participation"
+)
+
+// FillModel simulates the market filling an execution: how much each fill
+// attempt fills and how long until the next attempt, given the ticker's
+// current price bar. The fill loop caps the quantity at what remains and
+// applies the limit price check. Models draw all
+// randomness from rng, which is deterministic per attempt in seeded mode.
+type FillModel interface {
+	FillQuantity(rng *rand.Rand, exec *repository.Execution, quantityRemaining decimal.Decimal, bar *PriceBar) decimal.Decimal
+	NextFillDelay(rng *rand.Rand, exec *repository.Execution) time.Duration
+}
+
+// defaultFillModel is used when the service has no FillModel configured.
+var defaultFillModel FillModel = randomFillModel{delay: delayRange{min: 5 * time.Second, max: 120 * time.Second}}
+
+// NewFillModel builds the fill model selected in config.
+func NewFillModel(cfg config.FillModelConfig) (FillModel, error) {
+	if cfg.MinDelay <= 0 || cfg.MaxDelay < cfg.MinDelay {
+		return nil, fmt.Errorf("invalid fill delay range %d-%ds", cfg.MinDelay, cfg.MaxDelay)
+	}
+	delay := delayRange{min: time.Duration(cfg.MinDelay) * time.Second, max: time.Duration(cfg.MaxDelay) * time.Second}
+	switch cfg.Model {
+	case FillModelRandom, "":
+		return randomFillModel{delay: delay}, nil
+	case FillModelFixedRatio:
+		if cfg.FixedRatio <= 0 || cfg.FixedRatio > 1 {
+			return nil, fmt.Errorf("invalid fixed fill ratio %v", cfg.FixedRatio)
+		}
+		return fixedRatioFillModel{ratio: decimal.NewFromFloat(cfg.FixedRatio), delay: delay}, nil
+	case FillModelAlwaysFull:
+		return alwaysFullFillModel{delay: delay}, nil
+	case FillModelPoisson:
+		if cfg.MeanDelay <= 0 || cfg.LotSize <= 0 {
+			return nil, fmt.Errorf("invalid poisson fill model: mean delay %vs, lot size %v", cfg.MeanDelay, cfg.LotSize)
+		}
+		return poissonFillModel{meanDelay: time.Duration(cfg.MeanDelay * float64(time.Second)), lotSize: decimal.NewFromFloat(cfg.LotSize)}, nil
+	case FillModelVolumeParticipation:
+		if cfg.ParticipationRate <= 0 || cfg.ParticipationRate > 1 || cfg.IntervalVolume <= 0 || cfg.TradingDayMinutes <= 0 {
+			return nil, fmt.Errorf("invalid volume participation fill model: rate %v, interval volume %v, trading day %dm",
+				cfg.ParticipationRate, cfg.IntervalVolume, cfg.TradingDayMinutes)
+		}
+		return volumeParticipationFillModel{
+			rate:           cfg.ParticipationRate,
+			intervalVolume: cfg.IntervalVolume,
+			tradingDay:     time.Duration(cfg.TradingDayMinutes) * time.Minute,
+			delay:          delay,
+		}, nil
+	}
+	return nil, fmt.Errorf("unknown fill model %q", cfg.Model)
+}
+
+// fillRand returns the random source for one fill attempt on exec. A non-zero
+// seed derives it from the seed, the order's key and the execution's version,
+// which every attempt increments, so an execution's fill stream is the same
+// whichever replica processes each attempt and whenever it does.
+// A zero seed gives an unseeded source.
+func fillRand(seed int64, exec *repository.Execution) *rand.Rand {
+	return seededRand(seed, orderKey(exec), int64(exec.Version))
+}
+
+// routeRand returns the random source for routing exec to its venue, derived
+// like fillRand's but independent of the first fill attempt's.
+func routeRand(seed int64, exec *repository.Execution) *rand.Rand {
+	return seededRand(seed, orderKey(exec), int64(exec.Version), -1)
+}
+
+// orderKey identifies the order behind exec before it is stored: the
+// execution_service_id of a Kafka order, or a hash of a FIX order's session
+// and ClOrdID.
+func orderKey(exec *repository.Execution) int64 {
+	if !exec.FIXSessionID.Valid {
+		return int64(exec.ExecutionServiceID)
+	}
+	h := fnv.New64a()
+	h.Write([]byte(exec.FIXSessionID.String))
+	h.Write([]byte{0})
+	h.Write([]byte(exec.ClOrdID.String))
+	return int64(h.Sum64())
+}
+
+// seededRand derives a random source from seed and keys, or returns an
+// unseeded source if seed is zero.
+func seededRand(seed int64, keys ...int64) *rand.Rand {
+	if seed == 0 {
+		return rand.New(rand.NewSource(rand.Int63()))
+	}
+	h := fnv.New64a()
+	var b [8]byte
+	for _, v := range append([]int64{seed}, keys...) {
+		binary.BigEndian.PutUint64(b[:], uint64(v))
+		h.Write(b[:])
+	}
+	return rand.New(rand.NewSource(int64(h.Sum64())))
+}
+
+// delayRange draws whole-second delays uniformly from [min, max).
+type delayRange struct {
+	min, max time.Duration
+}
+
+func (d delayRange) mean() time.Duration {
+	return (d.min + d.max) / 2
+}
+
+func (d delayRange) next(rng *rand.Rand) time.Duration {
+	seconds := int(d.max/time.Second - d.min/time.Second)
+	if seconds <= 0 {
+		return d.min
+	}
+	return d.min + time.Duration(rng.Intn(seconds))*time.Second
+}
+
+// randomFillModel is the original simulation: 10% of attempts fill everything
+// (up to 10,000), 5% fill nothing, and the rest fill 80%, 60%, 400%, 20% or
+// 10% of the remainder with equal probability. Remainders of 100 or less fill
+// completely.
+type randomFillModel struct {
+	delay delayRange
+}
+
+func (m randomFillModel) FillQuantity(rng *rand.Rand, exec *repository.Execution, quantityRemaining decimal.Decimal, bar *PriceBar) decimal.Decimal {
+	return calculateFillQuantity(rng, quantityRemaining)
+}
+
+func (m randomFillModel) NextFillDelay(rng *rand.Rand, exec *repository.Execution) time.Duration {
+	return m.delay.next(rng)
+}
+
+// fixedRatioFillModel fills the same fraction of the remainder on every
+// attempt, rounded up to whole units so executions always make progress.
+type fixedRatioFillModel struct {
+	ratio decimal.Decimal
+	delay delayRange
+}
+
+func (m fixedRatioFillModel) FillQuantity(rng *rand.Rand, exec *repository.Execution, quantityRemaining decimal.Decimal, bar *PriceBar) decimal.Decimal {
+	if !quantityRemaining.IsPositive() {
+		return decimal.Zero
+	}
+	return quantityRemaining.Mul(m.ratio).Ceil()
+}
+
+func (m fixedRatioFillModel) NextFillDelay(rng *rand.Rand, exec *repository.Execution) time.Duration {
+	return m.delay.next(rng)
+}
+
+// alwaysFullFillModel fills the whole remainder on the first attempt.
+type alwaysFullFillModel struct {
+	delay delayRange
+}
+
+func (m alwaysFullFillModel) FillQuantity(rng *rand.Rand, exec *repository.Execution, quantityRemaining decimal.Decimal, bar *PriceBar) decimal.Decimal {
+	return decimal.Max(quantityRemaining, decimal.Zero)
+}
+
+func (m alwaysFullFillModel) NextFillDelay(rng *rand.Rand, exec *repository.Execution) time.Duration {
+	return m.delay.next(rng)
+}
+
+// poissonFillModel treats counterparties as a Poisson process: attempts are
+// exponentially distributed apart with the given mean, and each fills one lot.
+type poissonFillModel struct {
+	meanDelay time.Duration
+	lotSize   decimal.Decimal
+}
+
+func (m poissonFillModel) FillQuantity(rng *rand.Rand, exec *repository.Execution, quantityRemaining decimal.Decimal, bar *PriceBar) decimal.Decimal {
+	if !quantityRemaining.IsPositive() {
+		return decimal.Zero
+	}
+	return m.lotSize
+}
+
+func (m poissonFillModel) NextFillDelay(rng *rand.Rand, exec *repository.Execution) time.Duration {
+	return time.Duration(rng.ExpFloat64() * float64(m.meanDelay))
+}
+
+// volumeParticipationFillModel takes a fixed share of the market volume traded
+// between attempts, in whole units, so large orders in illiquid
+// names take longer to fill. The interval volume is the ticker's daily volume
+// spread evenly over the trading day, for the mean delay between attempts;
+// intervalVolume is used if the price bar has no volume.
+type volumeParticipationFillModel struct {
+	rate           float64
+	intervalVolume float64
+	tradingDay     time.Duration
+	delay          delayRange
+}
+
+func (m volumeParticipationFillModel) FillQuantity(rng *rand.Rand, exec *repository.Execution, quantityRemaining decimal.Decimal, bar *PriceBar) decimal.Decimal {
+	if !quantityRemaining.IsPositive() {
+		return decimal.Zero
+	}
+	volume := m.intervalVolume
+	if bar != nil && bar.Volume > 0 {
+		volume = float64(bar.Volume) * float64(m.delay.mean()) / float64(m.tradingDay)
+	}
+	// Round randomly in proportion to the fraction, so names that trade less
+	// than one unit per interval still fill now and again.
+	return decimal.NewFromFloat(math.Floor(m.rate*volume + rng.Float64()))
+}
+
+func (m volumeParticipationFillModel) NextFillDelay(rng *rand.Rand, exec *repository.Execution) time.Duration {
+	return m.delay.next(rng)
+}
+
+// maxRandomFill caps a single fill of the random fill model.
+var maxRandomFill = decimal.NewFromInt(10000)
+
+// randomFillRatios are the shares of the remainder the random fill model fills.
+var randomFillRatios = []decimal.Decimal{
+	decimal.New(8, -1), decimal.New(6, -1), decimal.New(4, 0), decimal.New(2, -1), decimal.New(1, -1),
+}
+
+func calculateFillQuantity(rng *rand.Rand, quantityRemaining decimal.Decimal) decimal.Decimal {
+	if !quantityRemaining.IsPositive() {
+		return decimal.Zero
+	}
+	p := rng.Float64()
+	if p < 0.10 {
+		fill := quantityRemaining.Truncate(0) // round to whole units
+		return decimal.Min(fill, maxRandomFill)
+	}
+	if p < 0.15 {
+		return decimal.Zero // 5% probability: no fill
+	}
+	if quantityRemaining.LessThanOrEqual(decimal.NewFromInt(100)) {
+		return quantityRemaining
+	}
+	// For >100, pick one of 5 possibilities, each 20%
+	idx := rng.Intn(len(randomFillRatios))
+	fill := quantityRemaining.Mul(randomFillRatios[idx]).Truncate(0) // round to whole units
+	return decimal.Min(fill, maxRandomFill)
+}
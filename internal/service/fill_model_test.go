@@ -0,0 +1,160 @@
+package service
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/kasbench/globeco-fix-engine/internal/config"
+	"github.com/kasbench/globeco-fix-engine/internal/repository"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testFillModelConfig(model string) config.FillModelConfig {
+	return config.FillModelConfig{
+		Model:             model,
+		MinDelay:          5,
+		MaxDelay:          120,
+		FixedRatio:        0.25,
+		MeanDelay:         30,
+		LotSize:           100,
+		ParticipationRate: 0.1,
+		IntervalVolume:    10000,
+		TradingDayMinutes: 390,
+	}
+}
+
+func TestNewFillModel(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	exec := &repository.Execution{ExecutionServiceID: 1, QuantityOrdered: dec(1000)}
+	cases := []struct {
+		model string
+		want  string // fill quantity with 1000 remaining
+	}{
+		{FillModelFixedRatio, "250"},
+		{FillModelAlwaysFull, "1000"},
+		{FillModelPoisson, "100"},
+		{FillModelVolumeParticipation, "1000"},
+	}
+	for _, c := range cases {
+		m, err := NewFillModel(testFillModelConfig(c.model))
+		require.NoError(t, err, c.model)
+		assert.Equal(t, c.want, m.FillQuantity(rng, exec, dec(1000), nil).String(), c.model)
+		assert.True(t, m.FillQuantity(rng, exec, decimal.Zero, nil).IsZero(), c.model)
+	}
+
+	m, err := NewFillModel(testFillModelConfig(""))
+	require.NoError(t, err)
+	assert.IsType(t, randomFillModel{}, m, "the original random model is the default")
+
+	_, err = NewFillModel(testFillModelConfig("martingale"))
+	assert.Error(t, err)
+	cfg := testFillModelConfig(FillModelFixedRatio)
+	cfg.FixedRatio = 1.5
+	_, err = NewFillModel(cfg)
+	assert.Error(t, err)
+	cfg = testFillModelConfig(FillModelRandom)
+	cfg.MaxDelay = 1
+	_, err = NewFillModel(cfg)
+	assert.Error(t, err)
+}
+
+func TestVolumeParticipationFillModel(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	exec := &repository.Execution{ExecutionServiceID: 1, QuantityOrdered: dec(1_000_000)}
+	m, err := NewFillModel(testFillModelConfig(FillModelVolumeParticipation))
+	require.NoError(t, err)
+
+	// 2,340,000 a day over 390 minutes is 100 a second; attempts are 62.5s apart
+	// on average, and 10% of the 6,250 traded in between is 625.
+	assert.Equal(t, "625", m.FillQuantity(rng, exec, dec(1_000_000), &PriceBar{Close: 10, Volume: 2_340_000}).String())
+
+	// An illiquid name fills a fraction of a unit per attempt on average.
+	var total float64
+	const n = 1000
+	for i := 0; i < n; i++ {
+		total += m.FillQuantity(rng, exec, dec(1_000_000), &PriceBar{Close: 10, Volume: 1_872}).InexactFloat64()
+	}
+	assert.InDelta(t, 0.5, total/n, 0.1)
+
+	// Without volume from pricing the configured interval volume is used.
+	assert.Equal(t, "1000", m.FillQuantity(rng, exec, dec(1_000_000), &PriceBar{Close: 10}).String())
+}
+
+func TestFillModelDelays(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	exec := &repository.Execution{ExecutionServiceID: 1}
+	m, err := NewFillModel(testFillModelConfig(FillModelRandom))
+	require.NoError(t, err)
+	for i := 0; i < 100; i++ {
+		d := m.NextFillDelay(rng, exec)
+		assert.GreaterOrEqual(t, d, 5*time.Second)
+		assert.Less(t, d, 120*time.Second)
+		assert.Zero(t, d%time.Second)
+	}
+
+	m, err = NewFillModel(testFillModelConfig(FillModelPoisson))
+	require.NoError(t, err)
+	var total time.Duration
+	const n = 2000
+	for i := 0; i < n; i++ {
+		d := m.NextFillDelay(rng, exec)
+		assert.GreaterOrEqual(t, d, time.Duration(0))
+		total += d
+	}
+	assert.InDelta(t, 30, (total / n).Seconds(), 5, "exponential delays average out at the mean")
+}
+
+func TestFixedRatioFillModelAlwaysProgresses(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	m := fixedRatioFillModel{ratio: dec(0.1)}
+	assert.Equal(t, "1", m.FillQuantity(rng, nil, dec(3), nil).String())
+	assert.Equal(t, "10", m.FillQuantity(rng, nil, dec(95), nil).String())
+	m.ratio = dec(0.07)
+	assert.Equal(t, "7", m.FillQuantity(rng, nil, dec(100), nil).String(), "exact, where 100 * 0.07 in floating point rounds up to 8")
+}
+
+// simulateFills runs the fill model over an execution like the fill loop does,
+// returning its fill quantities and delays.
+func simulateFills(m FillModel, seed int64, exec *repository.Execution) []float64 {
+	var stream []float64
+	for exec.QuantityFilled.LessThan(exec.QuantityOrdered) && len(stream) < 200 {
+		rng := fillRand(seed, exec)
+		remaining := exec.QuantityOrdered.Sub(exec.QuantityFilled)
+		qty := decimal.Min(m.FillQuantity(rng, exec, remaining, nil), remaining)
+		stream = append(stream, qty.InexactFloat64(), m.NextFillDelay(rng, exec).Seconds())
+		exec.QuantityFilled = exec.QuantityFilled.Add(qty)
+		exec.Version++
+	}
+	return stream
+}
+
+func TestFillRandIsDeterministicPerExecution(t *testing.T) {
+	m, err := NewFillModel(testFillModelConfig(FillModelRandom))
+	require.NoError(t, err)
+	newExec := func(id int) *repository.Execution {
+		return &repository.Execution{ExecutionServiceID: id, QuantityOrdered: dec(5000), Version: 1}
+	}
+
+	first := simulateFills(m, 42, newExec(7))
+	assert.Equal(t, first, simulateFills(m, 42, newExec(7)), "same seed and order, same fills")
+	assert.NotEqual(t, first, simulateFills(m, 42, newExec(8)), "each execution has its own stream")
+	assert.NotEqual(t, first, simulateFills(m, 43, newExec(7)), "the seed changes the stream")
+
+	// Interleaving attempts on other executions, as another replica or
+	// processing order would, does not change an execution's stream.
+	a, b := newExec(7), newExec(8)
+	var interleaved []float64
+	for a.QuantityFilled.LessThan(a.QuantityOrdered) && len(interleaved) < 200 {
+		rng := fillRand(42, a)
+		remaining := a.QuantityOrdered.Sub(a.QuantityFilled)
+		qty := decimal.Min(m.FillQuantity(rng, a, remaining, nil), remaining)
+		interleaved = append(interleaved, qty.InexactFloat64(), m.NextFillDelay(rng, a).Seconds())
+		a.QuantityFilled = a.QuantityFilled.Add(qty)
+		a.Version++
+		simulateFills(m, 42, b)
+	}
+	assert.Equal(t, first, interleaved)
+}
@@ -0,0 +1,422 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/kasbench/globeco-fix-engine/internal/domain"
+	"github.com/kasbench/globeco-fix-engine/internal/fix"
+	"github.com/kasbench/globeco-fix-engine/internal/repository"
+	"github.com/shopspring/decimal"
+	"go.uber.org/zap"
+)
+
+// OrdRejReason values (tag 103) used when refusing FIX orders.
+const (
+	ordRejReasonUnknownSymbol  = 1
+	ordRejReasonDuplicateOrder = 6
+	ordRejReasonOther          = 99
+)
+
+// CxlRejReason values (tag 102) used when refusing FIX cancel requests.
+const (
+	cxlRejReasonTooLate      = 0
+	cxlRejReasonUnknownOrder = 1
+	cxlRejReasonOther        = 99
+)
+
+// FIXOrderHandler is the fix.Application that turns inbound FIX orders into
+// executions, so that the fill loop treats them exactly like Kafka orders.
+type FIXOrderHandler struct {
+	svc *ExecutionService
+}
+
+// NewFIXOrderHandler constructs a new FIXOrderHandler.
+func NewFIXOrderHandler(svc *ExecutionService) *FIXOrderHandler {
+	return &FIXOrderHandler{svc: svc}
+}
+
+// Reports queued for a session are flushed in batches of flushBatchSize; on
+// logon, for at most logonFlushTimeout.
+const (
+	flushBatchSize    = 100
+	logonFlushTimeout = 10 * time.Second
+)
+
+// OnLogon implements fix.Application. Reports queued in the outbox while the
+// session was logged out are sent straight away, ahead of anything new,
+// rather than on the relay's next poll.
+func (h *FIXOrderHandler) OnLogon(sessionID fix.SessionID) {
+	h.svc.Logger.Info("FIX counterparty logged on", zap.String("session", sessionID.String()))
+	if h.svc.FIXSender == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), logonFlushTimeout)
+	defer cancel()
+	drainOutbox("FIX outbox", flushBatchSize, func() (int, error) {
+		return h.svc.relayFIXOutbox(ctx, sessionID, flushBatchSize)
+	})
+}
+
+// OnLogout implements fix.Application.
+func (h *FIXOrderHandler) OnLogout(sessionID fix.SessionID) {
+	h.svc.Logger.Info("FIX counterparty logged out", zap.String("session", sessionID.String()))
+}
+
+// FromApp implements fix.Application. Reports on an order's lifecycle are
+// queued in the session's outbox with the change they report and flushed
+// before FromApp returns, so they reach the counterparty in order with the
+// fills relayed for it. Only refusals that change nothing are returned directly.
+func (h *FIXOrderHandler) FromApp(ctx context.Context, sessionID fix.SessionID, msg *fix.Message) ([]*fix.Message, error) {
+	switch msg.MsgType() {
+	case fix.MsgTypeNewOrderSingle:
+		order, err := fix.ParseNewOrderSingle(msg)
+		if err != nil {
+			return nil, err
+		}
+		var reply *fix.Message
+		if report := h.newOrderSingle(ctx, sessionID, order); report != nil {
+			reply = report.ToMessage()
+		}
+		return h.flush(ctx, sessionID, reply), nil
+	case fix.MsgTypeOrderCancelRequest:
+		cancel, err := fix.ParseOrderCancelRequest(msg)
+		if err != nil {
+			return nil, err
+		}
+		return h.flush(ctx, sessionID, h.orderCancelRequest(ctx, sessionID, cancel)), nil
+	default:
+		return nil, fix.ErrUnsupportedMsgType
+	}
+}
+
+// flush sends the reports queued for sessionID, ahead of reply, and returns
+// reply as FromApp's response if it is not nil. Reports that cannot be sent
+// now stay queued for the outbox relay.
+func (h *FIXOrderHandler) flush(ctx context.Context, sessionID fix.SessionID, reply *fix.Message) []*fix.Message {
+	if h.svc.FIXSender != nil {
+		drainOutbox("FIX outbox", flushBatchSize, func() (int, error) {
+			return h.svc.relayFIXOutbox(ctx, sessionID, flushBatchSize)
+		})
+	}
+	if reply == nil {
+		return nil
+	}
+	return []*fix.Message{reply}
+}
+
+// newOrderSingle persists the order and queues its acknowledgement, or
+// returns the rejection of an order it could not accept.
+func (h *FIXOrderHandler) newOrderSingle(ctx context.Context, sessionID fix.SessionID, order *fix.NewOrderSingle) *fix.ExecutionReport {
+	now := h.svc.now()
+	tradeType, ok := tradeTypeForSide(order.Side, order.PositionEffect)
+	if !ok {
+		return rejectedReport(order, now, ordRejReasonOther, fmt.Sprintf("unsupported Side %q", order.Side))
+	}
+	if order.SecurityID == "" {
+		return rejectedReport(order, now, ordRejReasonUnknownSymbol, "SecurityID is required")
+	}
+
+	postDTO := &domain.ExecutionDTO{
+		TradeType:         tradeType,
+		Destination:       order.ExDestination,
+		SecurityID:        order.SecurityID,
+		QuantityOrdered:   domain.NewDecimal(order.OrderQty),
+		ReceivedTimestamp: domain.EpochTimeFromTime(order.TransactTime),
+		Version:           1,
+	}
+	if order.OrdType == fix.OrdTypeLimit && order.Price != nil {
+		price := domain.NewDecimal(*order.Price)
+		postDTO.LimitPrice = &price
+	}
+	timeInForce, ok := timeInForceForFIX(order.TimeInForce)
+	if !ok {
+		return rejectedReport(order, now, ordRejReasonOther, fmt.Sprintf("unsupported TimeInForce %q", order.TimeInForce))
+	}
+	postDTO.TimeInForce = timeInForce
+	if order.ExpireTime != nil {
+		expire := domain.EpochTimeFromTime(*order.ExpireTime)
+		postDTO.ExpireTimestamp = &expire
+	}
+
+	exec, err := h.svc.newExecution(ctx, postDTO, &fixOrigin{sessionID: sessionID, clOrdID: order.ClOrdID})
+	if errors.Is(err, ErrInvalidOrder) {
+		return rejectedReport(order, now, ordRejReasonOther, err.Error())
+	}
+	if errors.Is(err, ErrSecurityNotFound) {
+		return rejectedReport(order, now, ordRejReasonUnknownSymbol, "unknown security")
+	}
+	if err != nil {
+		h.svc.Logger.Warn("FIX order security lookup failed", zap.String("session", sessionID.String()), zap.Error(err))
+		return rejectedReport(order, now, ordRejReasonOther, "security service unavailable")
+	}
+	err = h.svc.Repo.WithinTx(ctx, func(ctx context.Context, repo repository.ExecutionRepository) error {
+		if err := repo.Create(ctx, exec); err != nil {
+			return err
+		}
+		return h.svc.publish(ctx, repo, exec, routeEvent(exec))
+	})
+	if err != nil {
+		if errors.Is(err, repository.ErrDuplicateExecution) {
+			return rejectedReport(order, now, ordRejReasonDuplicateOrder, "duplicate ClOrdID")
+		}
+		h.svc.Logger.Warn("FIX order could not be saved", zap.String("session", sessionID.String()), zap.Error(err))
+		return rejectedReport(order, now, ordRejReasonOther, "order could not be accepted")
+	}
+	h.svc.Logger.Debug("FIX order ingested", zap.Int("id", exec.ID), zap.String("cl_ord_id", order.ClOrdID), zap.String("ticker", exec.Ticker))
+	return nil
+}
+
+// orderCancelRequest cancels an order previously placed on the same session
+// and queues the Canceled ExecutionReport, or returns an OrderCancelReject.
+func (h *FIXOrderHandler) orderCancelRequest(ctx context.Context, sessionID fix.SessionID, cancel *fix.OrderCancelRequest) *fix.Message {
+	reject := func(reason int, ordStatus, text string) *fix.Message {
+		r := &fix.OrderCancelReject{
+			OrderID:          "NONE",
+			ClOrdID:          cancel.ClOrdID,
+			OrigClOrdID:      cancel.OrigClOrdID,
+			OrdStatus:        ordStatus,
+			CxlRejResponseTo: fix.CxlRejResponseToCancel,
+			CxlRejReason:     &reason,
+			Text:             text,
+		}
+		return r.ToMessage()
+	}
+
+	exec, err := h.svc.Repo.GetByClOrdID(ctx, sessionID.String(), cancel.OrigClOrdID)
+	if err != nil {
+		return reject(cxlRejReasonUnknownOrder, fix.OrdStatusRejected, "unknown order")
+	}
+
+	err = h.svc.withinTxRetryingConflicts(ctx, "cancel", func(ctx context.Context, repo repository.ExecutionRepository) error {
+		canceled, err := h.svc.cancelExecution(ctx, repo, byID(exec.ID))
+		if err != nil {
+			return err
+		}
+		report := canceledReport(canceled, cancel.ClOrdID, cancel.OrigClOrdID, h.svc.now(), h.svc.averagePriceRounding())
+		return h.svc.publish(ctx, repo, canceled, executionEvent{eventType: domain.EventTypeCancel, report: report})
+	})
+	if err != nil {
+		if current, gerr := h.svc.Repo.GetByID(ctx, exec.ID); gerr == nil {
+			exec = current
+		}
+		if errors.Is(err, ErrExecutionClosed) {
+			return reject(cxlRejReasonTooLate, ordStatusForExecution(exec), "order is no longer open")
+		}
+		h.svc.Logger.Warn("FIX cancel failed", zap.String("session", sessionID.String()), zap.Error(err))
+		return reject(cxlRejReasonOther, ordStatusForExecution(exec), "cancel could not be processed")
+	}
+	return nil
+}
+
+// newOrderReport acknowledges a newly accepted execution.
+func newOrderReport(exec *repository.Execution, clOrdID string) *fix.ExecutionReport {
+	report := &fix.ExecutionReport{
+		OrderID:      strconv.Itoa(exec.ID),
+		ClOrdID:      clOrdID,
+		ExecID:       fmt.Sprintf("%d-0", exec.ID),
+		ExecType:     fix.ExecTypeNew,
+		OrdStatus:    fix.OrdStatusNew,
+		SecurityID:   exec.SecurityID,
+		Symbol:       exec.Ticker,
+		Side:         sideForTradeType(exec.TradeType),
+		OrderQty:     exec.QuantityOrdered,
+		TimeInForce:  fixTimeInForce(exec.TimeInForce),
+		LeavesQty:    exec.QuantityOrdered,
+		TransactTime: exec.SentTimestamp,
+	}
+	if exec.LimitPrice.Valid {
+		price := exec.LimitPrice.Decimal
+		report.OrdType = fix.OrdTypeLimit
+		report.Price = &price
+	} else {
+		report.OrdType = fix.OrdTypeMarket
+	}
+	return report
+}
+
+// fillReport reports a single fill of fillQty at price, with the average price
+// rounded by r. exec must already reflect the fill.
+func fillReport(exec *repository.Execution, fillQty, price decimal.Decimal, r domain.Rounding) *fix.ExecutionReport {
+	report := newOrderReport(exec, exec.ClOrdID.String)
+	report.ExecID = fmt.Sprintf("%d-%d", exec.ID, exec.NumberOfFills)
+	report.ExecType = fix.ExecTypeTrade
+	report.OrdStatus = fix.OrdStatusPartiallyFilled
+	if exec.ExecutionStatus == string(domain.StatusFilled) {
+		report.OrdStatus = fix.OrdStatusFilled
+	}
+	report.LastQty = fillQty
+	report.LastPx = price
+	report.CumQty = exec.QuantityFilled
+	report.LeavesQty = decimal.Max(exec.QuantityOrdered.Sub(exec.QuantityFilled), decimal.Zero)
+	report.AvgPx = avgPx(exec, r)
+	if exec.LastFillTimestamp.Valid {
+		report.TransactTime = exec.LastFillTimestamp.Time
+	}
+	return report
+}
+
+// canceledReport confirms that exec has been cancelled at now, with the
+// average price rounded by r.
+func canceledReport(exec *repository.Execution, clOrdID, origClOrdID string, now time.Time, r domain.Rounding) *fix.ExecutionReport {
+	report := newOrderReport(exec, clOrdID)
+	report.OrigClOrdID = origClOrdID
+	report.ExecID = fmt.Sprintf("%d-CANC", exec.ID)
+	report.ExecType = fix.ExecTypeCanceled
+	report.OrdStatus = fix.OrdStatusCanceled
+	report.CumQty = exec.QuantityFilled
+	report.LeavesQty = decimal.Zero
+	report.AvgPx = avgPx(exec, r)
+	report.TransactTime = now
+	return report
+}
+
+// expiredReport tells a FIX counterparty that a DAY or GTD order expired at now.
+func expiredReport(exec *repository.Execution, now time.Time, r domain.Rounding) *fix.ExecutionReport {
+	report := canceledReport(exec, exec.ClOrdID.String, "", now, r)
+	report.ExecID = fmt.Sprintf("%d-EXPD", exec.ID)
+	report.ExecType = fix.ExecTypeExpired
+	report.OrdStatus = fix.OrdStatusExpired
+	return report
+}
+
+// replacedReport tells a FIX counterparty that exec was amended (e.g. via Kafka or REST) at now.
+func replacedReport(exec *repository.Execution, now time.Time, r domain.Rounding) *fix.ExecutionReport {
+	report := newOrderReport(exec, exec.ClOrdID.String)
+	report.ExecID = fmt.Sprintf("%d-V%d", exec.ID, exec.Version)
+	report.ExecType = fix.ExecTypeReplaced
+	report.OrdStatus = ordStatusForExecution(exec)
+	report.CumQty = exec.QuantityFilled
+	report.LeavesQty = decimal.Zero
+	if exec.IsOpen {
+		report.LeavesQty = exec.QuantityOrdered.Sub(exec.QuantityFilled)
+	}
+	report.AvgPx = avgPx(exec, r)
+	report.TransactTime = now
+	return report
+}
+
+// avgPx is exec's average price rounded by r, as reported on the fills topic,
+// or 0 if nothing has filled.
+func avgPx(exec *repository.Execution, r domain.Rounding) decimal.Decimal {
+	if avg := domain.AveragePrice(exec, r); avg != nil {
+		return *avg
+	}
+	return decimal.Zero
+}
+
+// venueRejectedReport tells a FIX counterparty that exec's venue rejected it.
+func venueRejectedReport(exec *repository.Execution) *fix.ExecutionReport {
+	report := newOrderReport(exec, exec.ClOrdID.String)
+	reason := ordRejReasonOther
+	report.ExecID = fmt.Sprintf("%d-REJ", exec.ID)
+	report.ExecType = fix.ExecTypeRejected
+	report.OrdStatus = fix.OrdStatusRejected
+	report.OrdRejReason = &reason
+	report.LeavesQty = decimal.Zero
+	report.Text = fmt.Sprintf("rejected by venue %s", exec.Destination)
+	return report
+}
+
+// ordStatusForExecution maps an execution status onto FIX OrdStatus.
+func ordStatusForExecution(exec *repository.Execution) string {
+	switch domain.ExecutionStatus(exec.ExecutionStatus) {
+	case domain.StatusFilled:
+		return fix.OrdStatusFilled
+	case domain.StatusCancelled:
+		return fix.OrdStatusCanceled
+	case domain.StatusPartial:
+		return fix.OrdStatusPartiallyFilled
+	case domain.StatusExpired:
+		return fix.OrdStatusExpired
+	case domain.StatusRejected:
+		return fix.OrdStatusRejected
+	case domain.StatusPendingCancel:
+		return fix.OrdStatusPendingCancel
+	case domain.StatusPendingReplace:
+		return fix.OrdStatusPendingReplace
+	}
+	return fix.OrdStatusNew
+}
+
+// rejectedReport rejects order at now without creating an execution.
+func rejectedReport(order *fix.NewOrderSingle, now time.Time, reason int, text string) *fix.ExecutionReport {
+	return &fix.ExecutionReport{
+		OrderID:      "NONE",
+		ClOrdID:      order.ClOrdID,
+		ExecID:       fmt.Sprintf("REJ-%s-%d", order.ClOrdID, now.UnixNano()),
+		ExecType:     fix.ExecTypeRejected,
+		OrdStatus:    fix.OrdStatusRejected,
+		OrdRejReason: &reason,
+		Symbol:       order.Symbol,
+		SecurityID:   order.SecurityID,
+		Side:         order.Side,
+		OrderQty:     order.OrderQty,
+		TransactTime: now,
+		Text:         text,
+	}
+}
+
+// tradeTypeForSide maps FIX Side/PositionEffect onto the engine's trade types.
+func tradeTypeForSide(side, positionEffect string) (string, bool) {
+	switch side {
+	case fix.SideBuy:
+		if positionEffect == fix.PositionEffectClose {
+			return "COVER", true
+		}
+		return "BUY", true
+	case fix.SideSell:
+		return "SELL", true
+	case fix.SideSellShort, fix.SideSellShortExempt:
+		return "SHORT", true
+	}
+	return "", false
+}
+
+// sideForTradeType is the inverse of tradeTypeForSide.
+func sideForTradeType(tradeType string) string {
+	switch tradeType {
+	case "SELL":
+		return fix.SideSell
+	case "SHORT":
+		return fix.SideSellShort
+	default:
+		return fix.SideBuy
+	}
+}
+
+// timeInForceForFIX maps FIX TimeInForce (tag 59) onto the engine's values.
+// FIX defines an absent TimeInForce as Day.
+func timeInForceForFIX(tif string) (string, bool) {
+	switch tif {
+	case "", fix.TimeInForceDay:
+		return domain.TimeInForceDay, true
+	case fix.TimeInForceGoodTillCancel:
+		return domain.TimeInForceGTC, true
+	case fix.TimeInForceImmediateOrCancel:
+		return domain.TimeInForceIOC, true
+	case fix.TimeInForceFillOrKill:
+		return domain.TimeInForceFOK, true
+	case fix.TimeInForceGoodTillDate:
+		return domain.TimeInForceGTD, true
+	}
+	return "", false
+}
+
+// fixTimeInForce is the inverse of timeInForceForFIX.
+func fixTimeInForce(tif string) string {
+	switch tif {
+	case domain.TimeInForceDay:
+		return fix.TimeInForceDay
+	case domain.TimeInForceIOC:
+		return fix.TimeInForceImmediateOrCancel
+	case domain.TimeInForceFOK:
+		return fix.TimeInForceFillOrKill
+	case domain.TimeInForceGTD:
+		return fix.TimeInForceGoodTillDate
+	}
+	return fix.TimeInForceGoodTillCancel
+}
@@ -0,0 +1,477 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/kasbench/globeco-fix-engine/internal/clock"
+	"github.com/kasbench/globeco-fix-engine/internal/config"
+	"github.com/kasbench/globeco-fix-engine/internal/fix"
+	"github.com/kasbench/globeco-fix-engine/internal/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestTradeTypeForSide(t *testing.T) {
+	cases := []struct {
+		side, positionEffect, want string
+	}{
+		{fix.SideBuy, "", "BUY"},
+		{fix.SideBuy, fix.PositionEffectClose, "COVER"},
+		{fix.SideSell, "", "SELL"},
+		{fix.SideSellShort, "", "SHORT"},
+		{fix.SideSellShortExempt, "", "SHORT"},
+	}
+	for _, c := range cases {
+		got, ok := tradeTypeForSide(c.side, c.positionEffect)
+		assert.True(t, ok)
+		assert.Equal(t, c.want, got)
+	}
+	_, ok := tradeTypeForSide("9", "")
+	assert.False(t, ok)
+}
+
+func TestFIXOrderHandler_NewOrderSingle(t *testing.T) {
+	sessionID := fix.SessionID{BeginString: fix.BeginString, SenderCompID: "GLOBECO", TargetCompID: "CLIENT"}
+	other := fix.SessionID{BeginString: fix.BeginString, SenderCompID: "GLOBECO", TargetCompID: "OTHER"}
+	repo := &fakeRepo{execs: map[int]*repository.Execution{
+		1: {ID: 1, ExecutionServiceID: 42, IsOpen: true, ExecutionStatus: "WORK"},
+	}}
+	sender := &recordingSender{sessions: []fix.SessionID{sessionID, other}}
+	h := NewFIXOrderHandler(&ExecutionService{Repo: repo, SecurityClient: newTestSecurityClient(t, "SEC", "IBM"), Logger: zap.NewNop(), FIXSender: sender})
+	// Acknowledgements are relayed from the outbox, rejects returned directly.
+	send := func(sessionID fix.SessionID, order *fix.NewOrderSingle) *fix.ExecutionReport {
+		relayed := len(sender.sent)
+		responses, err := h.FromApp(context.Background(), sessionID, order.ToMessage())
+		require.NoError(t, err)
+		msgs := slices.Concat(sender.sent[relayed:], responses)
+		require.Len(t, msgs, 1)
+		report, err := fix.ParseExecutionReport(msgs[0])
+		require.NoError(t, err)
+		return report
+	}
+
+	// ClOrdIDs are opaque, and numeric ones do not collide with Kafka orders.
+	for _, clOrdID := range []string{"ABC-1", "42"} {
+		report := send(sessionID, &fix.NewOrderSingle{ClOrdID: clOrdID, SecurityID: "SEC", Side: fix.SideBuy, OrderQty: dec(10), OrdType: fix.OrdTypeMarket})
+		assert.Equal(t, fix.OrdStatusNew, report.OrdStatus)
+		assert.Equal(t, clOrdID, report.ClOrdID)
+	}
+	exec, err := repo.GetByClOrdID(context.Background(), sessionID.String(), "ABC-1")
+	require.NoError(t, err)
+	assert.Equal(t, 0, exec.ExecutionServiceID)
+	assert.Equal(t, 2, exec.ID)
+
+	// ClOrdIDs are unique per session.
+	report := send(sessionID, &fix.NewOrderSingle{ClOrdID: "ABC-1", SecurityID: "SEC", Side: fix.SideBuy, OrderQty: dec(10), OrdType: fix.OrdTypeMarket})
+	assert.Equal(t, fix.OrdStatusRejected, report.OrdStatus)
+	report = send(other, &fix.NewOrderSingle{ClOrdID: "ABC-1", SecurityID: "SEC", Side: fix.SideBuy, OrderQty: dec(10), OrdType: fix.OrdTypeMarket})
+	assert.Equal(t, fix.OrdStatusNew, report.OrdStatus)
+	assert.Len(t, repo.execs, 4)
+}
+
+func TestFIXOrderHandler_NewOrderSingleRejectsOnSecurityLookupFailure(t *testing.T) {
+	sessionID := fix.SessionID{BeginString: fix.BeginString, SenderCompID: "GLOBECO", TargetCompID: "CLIENT"}
+	unavailable := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	t.Cleanup(unavailable.Close)
+	u, err := url.Parse(unavailable.URL)
+	require.NoError(t, err)
+	port, err := strconv.Atoi(u.Port())
+	require.NoError(t, err)
+	now := time.Date(2024, 3, 4, 15, 0, 0, 0, time.UTC)
+
+	cases := []struct {
+		name   string
+		client *SecurityServiceClient
+		reason int
+		text   string
+	}{
+		{"unknown security", newTestSecurityClient(t, "SEC", "IBM"), ordRejReasonUnknownSymbol, "unknown security"},
+		{"service unavailable", NewSecurityServiceClient(config.ServiceConfig{Host: u.Hostname(), Port: port}), ordRejReasonOther, "security service unavailable"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			repo := &fakeRepo{execs: map[int]*repository.Execution{}}
+			h := NewFIXOrderHandler(&ExecutionService{Repo: repo, SecurityClient: c.client, Clock: clock.NewFixed(now), Logger: zap.NewNop()})
+			order := &fix.NewOrderSingle{ClOrdID: "A-1", SecurityID: "OTHER", Side: fix.SideBuy, OrderQty: dec(10), OrdType: fix.OrdTypeMarket}
+			responses, err := h.FromApp(context.Background(), sessionID, order.ToMessage())
+			require.NoError(t, err)
+			require.Len(t, responses, 1)
+			report, err := fix.ParseExecutionReport(responses[0])
+			require.NoError(t, err)
+			assert.Equal(t, fix.OrdStatusRejected, report.OrdStatus)
+			require.NotNil(t, report.OrdRejReason)
+			assert.Equal(t, c.reason, *report.OrdRejReason)
+			assert.Equal(t, c.text, report.Text)
+			assert.Equal(t, fmt.Sprintf("REJ-A-1-%d", now.UnixNano()), report.ExecID)
+			assert.Empty(t, repo.execs)
+		})
+	}
+}
+
+func TestFIXOrderHandler_UnsupportedMsgType(t *testing.T) {
+	h := NewFIXOrderHandler(&ExecutionService{Logger: zap.NewNop()})
+	_, err := h.FromApp(context.Background(), fix.SessionID{}, fix.NewMessage(fix.MsgTypeExecutionReport))
+	assert.ErrorIs(t, err, fix.ErrUnsupportedMsgType)
+}
+
+// recordingSender is a FIXSender with sessions logged on.
+type recordingSender struct {
+	sessions  []fix.SessionID
+	sessionID fix.SessionID
+	sent      []*fix.Message
+}
+
+func (r *recordingSender) Send(ctx context.Context, sessionID fix.SessionID, m *fix.Message) error {
+	if !slices.Contains(r.sessions, sessionID) {
+		return fmt.Errorf("%w: %s", fix.ErrSessionNotFound, sessionID)
+	}
+	r.sessionID = sessionID
+	r.sent = append(r.sent, m)
+	return nil
+}
+
+func (r *recordingSender) Sessions() []fix.SessionID {
+	return r.sessions
+}
+
+func TestPublishFill_RoutesFIXOrdersToOriginatingSession(t *testing.T) {
+	sessionID := fix.SessionID{BeginString: fix.BeginString, SenderCompID: "GLOBECO", TargetCompID: "CLIENT"}
+	sender := &recordingSender{}
+	repo := &fakeRepo{}
+	svc := &ExecutionService{Repo: repo, Logger: zap.NewNop(), FIXSender: sender}
+	ctx := context.Background()
+	exec := &repository.Execution{
+		ID:              7,
+		IsOpen:          true,
+		ExecutionStatus: "PART",
+		TradeType:       "SELL",
+		SecurityID:      "SEC",
+		Ticker:          "IBM",
+		QuantityOrdered: dec(100),
+		QuantityFilled:  dec(60),
+		TotalAmount:     dec(600),
+		NumberOfFills:   2,
+		FIXSessionID:    sql.NullString{String: sessionID.String(), Valid: true},
+		ClOrdID:         sql.NullString{String: "ORD-42", Valid: true},
+	}
+
+	// Zero quantity fills are not reported.
+	require.NoError(t, svc.publishFill(ctx, repo, exec, nil, false))
+	assert.Empty(t, repo.outbox)
+
+	// Reports are queued with the fill rather than sent inside its transaction,
+	// so a session that is not logged on neither fails the fill nor loses the report.
+	require.NoError(t, svc.publishFill(ctx, repo, exec, &repository.Fill{ID: 1, Quantity: dec(20), Price: dec(10)}, false))
+	require.Len(t, repo.outbox, 1)
+	assert.Equal(t, sessionID.String(), repo.outbox[0].FIXSessionID.String)
+	n, err := svc.relayFIXOutbox(ctx, sessionID, 10)
+	assert.ErrorIs(t, err, fix.ErrSessionNotFound)
+	assert.Equal(t, 0, n)
+	assert.Empty(t, sender.sent)
+	assert.False(t, repo.outbox[0].SentTimestamp.Valid)
+
+	sender.sessions = []fix.SessionID{sessionID}
+	n, err = svc.relayFIXOutbox(ctx, sessionID, 10)
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	require.Len(t, sender.sent, 1)
+	assert.Equal(t, sessionID, sender.sessionID)
+	assert.True(t, repo.outbox[0].SentTimestamp.Valid)
+	report, err := fix.ParseExecutionReport(sender.sent[0])
+	require.NoError(t, err)
+	assert.Equal(t, fix.ExecTypeTrade, report.ExecType)
+	assert.Equal(t, fix.OrdStatusPartiallyFilled, report.OrdStatus)
+	assert.Equal(t, "7-2", report.ExecID)
+	assert.Equal(t, "ORD-42", report.ClOrdID)
+	assert.Equal(t, fix.SideSell, report.Side)
+	assert.Equal(t, "20", report.LastQty.String())
+	assert.Equal(t, "10", report.LastPx.String())
+	assert.Equal(t, "60", report.CumQty.String())
+	assert.Equal(t, "40", report.LeavesQty.String())
+	assert.Equal(t, "10", report.AvgPx.String())
+
+	exec.IsOpen = false
+	exec.ExecutionStatus = "FULL"
+	exec.QuantityFilled = dec(100)
+	exec.TotalAmount = dec(1000)
+	require.NoError(t, svc.publishFill(ctx, repo, exec, &repository.Fill{ID: 2, Quantity: dec(40), Price: dec(10)}, false))
+	_, err = svc.relayFIXOutbox(ctx, sessionID, 10)
+	require.NoError(t, err)
+	report, err = fix.ParseExecutionReport(sender.sent[1])
+	require.NoError(t, err)
+	assert.Equal(t, fix.OrdStatusFilled, report.OrdStatus)
+	assert.Equal(t, "0", report.LeavesQty.String())
+	pending, err := repo.PendingOutbox(ctx, 10)
+	require.NoError(t, err)
+	assert.Empty(t, pending, "FIX orders are reported on their session, not the fills topic")
+}
+
+func TestFIXOrderHandler_OnLogonFlushesQueuedReports(t *testing.T) {
+	sessionID := fix.SessionID{BeginString: fix.BeginString, SenderCompID: "GLOBECO", TargetCompID: "CLIENT"}
+	repo := &fakeRepo{}
+	sender := &recordingSender{}
+	svc := &ExecutionService{Repo: repo, Logger: zap.NewNop(), FIXSender: sender}
+	h := NewFIXOrderHandler(svc)
+	ctx := context.Background()
+	exec := &repository.Execution{ID: 7, IsOpen: true, ExecutionStatus: "PART", TradeType: "BUY", QuantityOrdered: dec(100),
+		FIXSessionID: sql.NullString{String: sessionID.String(), Valid: true}, ClOrdID: sql.NullString{String: "ORD-7", Valid: true}}
+
+	// Fills while the counterparty is disconnected are kept for it.
+	for i := 1; i <= 2; i++ {
+		exec.QuantityFilled = dec(float64(10 * i))
+		exec.TotalAmount = dec(float64(100 * i))
+		exec.NumberOfFills = int16(i)
+		require.NoError(t, svc.publishFill(ctx, repo, exec, &repository.Fill{ID: int64(i), Quantity: dec(10), Price: dec(10)}, false))
+	}
+	h.OnLogon(sessionID) // logs on elsewhere, e.g. to another replica
+	assert.Empty(t, sender.sent)
+	pending, err := repo.PendingFIXOutbox(ctx, sessionID.String(), 10)
+	require.NoError(t, err)
+	assert.Len(t, pending, 2)
+
+	// Once it logs on here, the backlog is flushed in order.
+	sender.sessions = []fix.SessionID{sessionID}
+	h.OnLogon(sessionID)
+	require.Len(t, sender.sent, 2)
+	for i, m := range sender.sent {
+		report, err := fix.ParseExecutionReport(m)
+		require.NoError(t, err)
+		assert.Equal(t, fmt.Sprintf("7-%d", i+1), report.ExecID)
+	}
+	pending, err = repo.PendingFIXOutbox(ctx, sessionID.String(), 10)
+	require.NoError(t, err)
+	assert.Empty(t, pending)
+}
+
+// fakeRepo is an in-memory ExecutionRepository keyed by id.
+// Methods not overridden panic via the nil embedded interface.
+type fakeRepo struct {
+	repository.ExecutionRepository
+	execs  map[int]*repository.Execution
+	outbox []*repository.OutboxMessage
+	fills  []*repository.Fill
+}
+
+func (r *fakeRepo) PollNextForFill(ctx context.Context, now time.Time) (*repository.Execution, error) {
+	var next *repository.Execution
+	for _, exec := range r.execs {
+		due := exec.IsOpen && exec.NextFillTimestamp.Valid && !exec.NextFillTimestamp.Time.After(now) &&
+			(!exec.ExpireTimestamp.Valid || exec.ExpireTimestamp.Time.After(now))
+		if !due {
+			continue
+		}
+		if next == nil || exec.NextFillTimestamp.Time.Before(next.NextFillTimestamp.Time) ||
+			(exec.NextFillTimestamp.Time.Equal(next.NextFillTimestamp.Time) && exec.ID < next.ID) {
+			next = exec
+		}
+	}
+	if next == nil {
+		return nil, sql.ErrNoRows
+	}
+	copied := *next
+	return &copied, nil
+}
+
+func (r *fakeRepo) CreateFill(ctx context.Context, fill *repository.Fill) error {
+	fill.ID = int64(len(r.fills) + 1)
+	r.fills = append(r.fills, fill)
+	return nil
+}
+
+func (r *fakeRepo) GetByID(ctx context.Context, id int) (*repository.Execution, error) {
+	exec, ok := r.execs[id]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	copied := *exec
+	return &copied, nil
+}
+
+func (r *fakeRepo) GetByExecutionServiceID(ctx context.Context, executionServiceID int) (*repository.Execution, error) {
+	for _, exec := range r.execs {
+		if !exec.FIXSessionID.Valid && exec.ExecutionServiceID == executionServiceID {
+			copied := *exec
+			return &copied, nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (r *fakeRepo) GetByClOrdID(ctx context.Context, fixSessionID, clOrdID string) (*repository.Execution, error) {
+	for _, exec := range r.execs {
+		if exec.FIXSessionID.String == fixSessionID && exec.ClOrdID.String == clOrdID {
+			copied := *exec
+			return &copied, nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (r *fakeRepo) Create(ctx context.Context, exec *repository.Execution) error {
+	var err error
+	if exec.FIXSessionID.Valid {
+		_, err = r.GetByClOrdID(ctx, exec.FIXSessionID.String, exec.ClOrdID.String)
+	} else {
+		_, err = r.GetByExecutionServiceID(ctx, exec.ExecutionServiceID)
+	}
+	if err == nil {
+		return repository.ErrDuplicateExecution
+	}
+	exec.ID = len(r.execs) + 1
+	copied := *exec
+	r.execs[exec.ID] = &copied
+	return nil
+}
+
+func (r *fakeRepo) WithinTx(ctx context.Context, fn func(ctx context.Context, repo repository.ExecutionRepository) error) error {
+	return fn(ctx, r)
+}
+
+func (r *fakeRepo) EnqueueOutbox(ctx context.Context, msg *repository.OutboxMessage) error {
+	msg.ID = 1
+	if len(r.outbox) > 0 {
+		msg.ID = r.outbox[len(r.outbox)-1].ID + 1
+	}
+	r.outbox = append(r.outbox, msg)
+	return nil
+}
+
+func (r *fakeRepo) PendingOutbox(ctx context.Context, limit int) ([]*repository.OutboxMessage, error) {
+	return r.pendingOutbox(sql.NullString{}, limit), nil
+}
+
+func (r *fakeRepo) PendingFIXOutbox(ctx context.Context, fixSessionID string, limit int) ([]*repository.OutboxMessage, error) {
+	return r.pendingOutbox(sql.NullString{String: fixSessionID, Valid: true}, limit), nil
+}
+
+func (r *fakeRepo) pendingOutbox(fixSessionID sql.NullString, limit int) []*repository.OutboxMessage {
+	var pending []*repository.OutboxMessage
+	for _, msg := range r.outbox {
+		if !msg.SentTimestamp.Valid && msg.FIXSessionID == fixSessionID && len(pending) < limit {
+			pending = append(pending, msg)
+		}
+	}
+	return pending
+}
+
+func (r *fakeRepo) MarkOutboxSent(ctx context.Context, ids []int64, sent time.Time) error {
+	for _, msg := range r.outbox {
+		if slices.Contains(ids, msg.ID) {
+			msg.SentTimestamp = sql.NullTime{Time: sent, Valid: true}
+		}
+	}
+	return nil
+}
+
+func (r *fakeRepo) PruneOutbox(ctx context.Context, before time.Time) (int64, error) {
+	n := len(r.outbox)
+	r.outbox = slices.DeleteFunc(r.outbox, func(msg *repository.OutboxMessage) bool {
+		return msg.SentTimestamp.Valid && msg.SentTimestamp.Time.Before(before)
+	})
+	return int64(n - len(r.outbox)), nil
+}
+
+func (r *fakeRepo) Update(ctx context.Context, exec *repository.Execution) error {
+	if current, ok := r.execs[exec.ID]; !ok || current.Version != exec.Version {
+		return &repository.VersionConflictError{ID: exec.ID, Version: exec.Version}
+	}
+	exec.Version++
+	copied := *exec
+	r.execs[exec.ID] = &copied
+	return nil
+}
+
+func (r *fakeRepo) PollDueForExpiry(ctx context.Context, now time.Time) ([]*repository.Execution, error) {
+	var due []*repository.Execution
+	for _, exec := range r.execs {
+		if exec.IsOpen && exec.ExpireTimestamp.Valid && !exec.ExpireTimestamp.Time.After(now) {
+			copied := *exec
+			due = append(due, &copied)
+		}
+	}
+	return due, nil
+}
+
+func TestFIXOrderHandler_OrderCancelRequest(t *testing.T) {
+	sessionID := fix.SessionID{BeginString: fix.BeginString, SenderCompID: "GLOBECO", TargetCompID: "CLIENT"}
+	other := fix.SessionID{BeginString: fix.BeginString, SenderCompID: "GLOBECO", TargetCompID: "OTHER"}
+	repo := &fakeRepo{execs: map[int]*repository.Execution{
+		1: {ID: 1, IsOpen: true, ExecutionStatus: "PART", TradeType: "BUY", QuantityOrdered: dec(100), QuantityFilled: dec(30), TotalAmount: dec(300),
+			FIXSessionID: sql.NullString{String: sessionID.String(), Valid: true}, ClOrdID: sql.NullString{String: "ORD-42", Valid: true}},
+	}}
+	sender := &recordingSender{sessions: []fix.SessionID{sessionID, other}}
+	h := NewFIXOrderHandler(&ExecutionService{Repo: repo, Logger: zap.NewNop(), FIXSender: sender})
+	cancel := &fix.OrderCancelRequest{OrigClOrdID: "ORD-42", ClOrdID: "ORD-43", Side: fix.SideBuy}
+
+	// Orders cannot be cancelled from another session.
+	responses, err := h.FromApp(context.Background(), other, cancel.ToMessage())
+	require.NoError(t, err)
+	reject, err := fix.ParseOrderCancelReject(responses[0])
+	require.NoError(t, err)
+	assert.Equal(t, cxlRejReasonUnknownOrder, *reject.CxlRejReason)
+
+	// The Canceled report is relayed from the outbox.
+	responses, err = h.FromApp(context.Background(), sessionID, cancel.ToMessage())
+	require.NoError(t, err)
+	assert.Empty(t, responses)
+	require.Len(t, sender.sent, 1)
+	assert.Equal(t, sessionID, sender.sessionID)
+	report, err := fix.ParseExecutionReport(sender.sent[0])
+	require.NoError(t, err)
+	assert.Equal(t, fix.ExecTypeCanceled, report.ExecType)
+	assert.Equal(t, fix.OrdStatusCanceled, report.OrdStatus)
+	assert.Equal(t, "ORD-43", report.ClOrdID)
+	assert.Equal(t, "ORD-42", report.OrigClOrdID)
+	assert.Equal(t, "30", report.CumQty.String())
+	assert.Equal(t, "0", report.LeavesQty.String())
+	assert.Equal(t, "CANC", repo.execs[1].ExecutionStatus)
+
+	// A second cancel is too late.
+	responses, err = h.FromApp(context.Background(), sessionID, cancel.ToMessage())
+	require.NoError(t, err)
+	reject, err = fix.ParseOrderCancelReject(responses[0])
+	require.NoError(t, err)
+	assert.Equal(t, cxlRejReasonTooLate, *reject.CxlRejReason)
+	assert.Equal(t, fix.OrdStatusCanceled, reject.OrdStatus)
+}
+
+func TestFIXOrderHandler_QueuedFillReachesWireBeforeCancel(t *testing.T) {
+	sessionID := fix.SessionID{BeginString: fix.BeginString, SenderCompID: "GLOBECO", TargetCompID: "CLIENT"}
+	repo := &fakeRepo{execs: map[int]*repository.Execution{
+		1: {ID: 1, IsOpen: true, ExecutionStatus: "PART", TradeType: "BUY", QuantityOrdered: dec(100), QuantityFilled: dec(30), TotalAmount: dec(300),
+			FIXSessionID: sql.NullString{String: sessionID.String(), Valid: true}, ClOrdID: sql.NullString{String: "ORD-42", Valid: true}},
+	}}
+	sender := &recordingSender{sessions: []fix.SessionID{sessionID}}
+	svc := &ExecutionService{Repo: repo, Logger: zap.NewNop(), FIXSender: sender}
+	h := NewFIXOrderHandler(svc)
+
+	// A fill committed but not yet relayed when the cancel arrives.
+	fill := &repository.Fill{ExecutionID: 1, Quantity: dec(30), Price: dec(10)}
+	require.NoError(t, svc.publishFill(context.Background(), repo, repo.execs[1], fill, false))
+
+	cancel := &fix.OrderCancelRequest{OrigClOrdID: "ORD-42", ClOrdID: "ORD-43", Side: fix.SideBuy}
+	responses, err := h.FromApp(context.Background(), sessionID, cancel.ToMessage())
+	require.NoError(t, err)
+	assert.Empty(t, responses)
+
+	require.Len(t, sender.sent, 2)
+	var execTypes []string
+	for _, m := range sender.sent {
+		report, err := fix.ParseExecutionReport(m)
+		require.NoError(t, err)
+		execTypes = append(execTypes, report.ExecType)
+	}
+	assert.Equal(t, []string{fix.ExecTypeTrade, fix.ExecTypeCanceled}, execTypes)
+}
@@ -0,0 +1,160 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/kasbench/globeco-fix-engine/internal/fix"
+	"github.com/kasbench/globeco-fix-engine/internal/repository"
+	"github.com/segmentio/kafka-go"
+	"go.uber.org/zap"
+)
+
+// outboxPruneInterval is how often sent outbox messages past their retention are deleted.
+const outboxPruneInterval = time.Minute
+
+// StartOutboxRelay periodically publishes pending outbox messages to the fills
+// topic, and sends the reports queued for the FIX sessions logged on to this
+// process, in the order they were written and marks them sent. Messages stay
+// in the outbox while Kafka or the session is unavailable and are retried on
+// the next poll. Sent messages are deleted once they are older than
+// retention; a zero retention keeps them.
+func (s *ExecutionService) StartOutboxRelay(ctx context.Context, interval time.Duration, batchSize int, retention time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	var prune <-chan time.Time
+	if retention > 0 {
+		pruneTicker := time.NewTicker(outboxPruneInterval)
+		defer pruneTicker.Stop()
+		prune = pruneTicker.C
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-prune:
+			if err := s.pruneOutbox(ctx, retention); err != nil {
+				log.Printf("error pruning outbox: %v", err)
+			}
+		case <-ticker.C:
+			drainOutbox("outbox", batchSize, func() (int, error) {
+				return s.relayOutbox(ctx, batchSize)
+			})
+			if s.FIXSender == nil {
+				continue
+			}
+			for _, sessionID := range s.FIXSender.Sessions() {
+				drainOutbox("FIX outbox", batchSize, func() (int, error) {
+					return s.relayFIXOutbox(ctx, sessionID, batchSize)
+				})
+			}
+		}
+	}
+}
+
+// drainOutbox relays batches until the backlog is drained or relaying fails.
+func drainOutbox(what string, batchSize int, relay func() (int, error)) {
+	for {
+		n, err := relay()
+		if err != nil {
+			log.Printf("error relaying %s: %v", what, err)
+			return
+		}
+		if n < batchSize {
+			return
+		}
+	}
+}
+
+// relayOutbox publishes a single batch of pending outbox messages and returns
+// how many it published. The batch is marked sent in the transaction that
+// locked it; if that commit fails the batch is published again, so delivery
+// is at least once.
+func (s *ExecutionService) relayOutbox(ctx context.Context, batchSize int) (int, error) {
+	published := 0
+	err := s.Repo.WithinTx(ctx, func(ctx context.Context, repo repository.ExecutionRepository) error {
+		pending, err := repo.PendingOutbox(ctx, batchSize)
+		if err != nil {
+			return fmt.Errorf("reading outbox: %w", err)
+		}
+		if len(pending) == 0 {
+			return nil
+		}
+		msgs := make([]kafka.Message, len(pending))
+		ids := make([]int64, len(pending))
+		for i, p := range pending {
+			msgs[i] = kafka.Message{Key: p.MessageKey, Value: p.Payload}
+			ids[i] = p.ID
+		}
+		if err := s.FillsProducer.WriteMessages(ctx, msgs...); err != nil {
+			return fmt.Errorf("publishing outbox: %w", err)
+		}
+		if err := repo.MarkOutboxSent(ctx, ids, s.now()); err != nil {
+			return fmt.Errorf("marking outbox sent: %w", err)
+		}
+		published = len(pending)
+		return nil
+	})
+	if published > 0 {
+		s.Logger.Debug("outbox relayed", zap.Int("count", published))
+	}
+	return published, err
+}
+
+// relayFIXOutbox sends a single batch of the reports queued for sessionID in
+// the order they were written and returns how many it sent. It stops at the
+// first report that cannot be sent, e.g. because the session has logged out,
+// which stays queued with those after it. Sent reports are marked sent in the
+// transaction that locked them; if that commit fails they are sent again, so
+// delivery is at least once and counterparties recognise repeats by ExecID.
+func (s *ExecutionService) relayFIXOutbox(ctx context.Context, sessionID fix.SessionID, batchSize int) (int, error) {
+	sent := 0
+	var sendErr error
+	err := s.Repo.WithinTx(ctx, func(ctx context.Context, repo repository.ExecutionRepository) error {
+		pending, err := repo.PendingFIXOutbox(ctx, sessionID.String(), batchSize)
+		if err != nil {
+			return fmt.Errorf("reading FIX outbox: %w", err)
+		}
+		var ids []int64
+		for _, p := range pending {
+			m, err := fix.Decode(p.Payload)
+			if err != nil {
+				return fmt.Errorf("decoding FIX outbox message %d: %w", p.ID, err)
+			}
+			if err := s.FIXSender.Send(ctx, sessionID, m); err != nil {
+				sendErr = fmt.Errorf("sending FIX outbox message %d: %w", p.ID, err)
+				break
+			}
+			ids = append(ids, p.ID)
+		}
+		if len(ids) == 0 {
+			return nil
+		}
+		if err := repo.MarkOutboxSent(ctx, ids, s.now()); err != nil {
+			return fmt.Errorf("marking FIX outbox sent: %w", err)
+		}
+		sent = len(ids)
+		return nil
+	})
+	if sent > 0 {
+		s.Logger.Debug("FIX outbox relayed", zap.String("session", sessionID.String()), zap.Int("count", sent))
+	}
+	if err != nil {
+		return sent, err
+	}
+	return sent, sendErr
+}
+
+// pruneOutbox deletes the messages sent more than retention ago on the service's clock.
+func (s *ExecutionService) pruneOutbox(ctx context.Context, retention time.Duration) error {
+	n, err := s.Repo.PruneOutbox(ctx, s.now().Add(-retention))
+	if err != nil {
+		return err
+	}
+	if n > 0 {
+		s.Logger.Debug("outbox pruned", zap.Int64("count", n))
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/kasbench/globeco-fix-engine/internal/clock"
+	"github.com/kasbench/globeco-fix-engine/internal/domain"
+	"github.com/kasbench/globeco-fix-engine/internal/repository"
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// recordingWriter captures messages written to Kafka, or fails with err.
+type recordingWriter struct {
+	msgs []kafka.Message
+	err  error
+}
+
+func (w *recordingWriter) WriteMessages(ctx context.Context, msgs ...kafka.Message) error {
+	if w.err != nil {
+		return w.err
+	}
+	w.msgs = append(w.msgs, msgs...)
+	return nil
+}
+
+func TestRelayOutbox(t *testing.T) {
+	repo := &fakeRepo{execs: map[int]*repository.Execution{
+		1: {ID: 1, ExecutionServiceID: 5, IsOpen: true, ExecutionStatus: "WORK", TradeType: "BUY", QuantityOrdered: dec(100), Version: 1},
+	}}
+	writer := &recordingWriter{err: errors.New("kafka unavailable")}
+	start := time.Date(2026, 11, 25, 14, 30, 0, 0, time.UTC)
+	simClock := clock.NewFixed(start)
+	svc := &ExecutionService{Repo: repo, FillsProducer: writer, Clock: simClock, Logger: zap.NewNop()}
+	ctx := context.Background()
+	qty := func(v float64) *domain.Decimal { d := domain.NewDecimal(dec(v)); return &d }
+
+	// Kafka order state changes are written to the outbox rather than straight to Kafka.
+	_, err := svc.AmendExecution(ctx, &domain.AmendDTO{ExecutionServiceID: 5, QuantityOrdered: qty(200)})
+	require.NoError(t, err)
+	_, err = svc.AmendExecution(ctx, &domain.AmendDTO{ExecutionServiceID: 5, QuantityOrdered: qty(300)})
+	require.NoError(t, err)
+	require.Len(t, repo.outbox, 2)
+	assert.Equal(t, []byte("5"), repo.outbox[0].MessageKey)
+
+	// While Kafka is down nothing is marked sent.
+	n, err := svc.relayOutbox(ctx, 10)
+	assert.Error(t, err)
+	assert.Zero(t, n)
+	assert.False(t, repo.outbox[0].SentTimestamp.Valid)
+
+	// Once it is back the backlog is published in order, in batches.
+	writer.err = nil
+	n, err = svc.relayOutbox(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	n, err = svc.relayOutbox(ctx, 10)
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	n, err = svc.relayOutbox(ctx, 10)
+	require.NoError(t, err)
+	assert.Zero(t, n)
+
+	require.Len(t, writer.msgs, 2)
+	var first, second domain.ExecutionDTO
+	require.NoError(t, json.Unmarshal(writer.msgs[0].Value, &first))
+	require.NoError(t, json.Unmarshal(writer.msgs[1].Value, &second))
+	assert.Equal(t, "200", first.QuantityOrdered.String())
+	assert.Equal(t, "300", second.QuantityOrdered.String())
+	for _, msg := range repo.outbox {
+		assert.Equal(t, start, msg.SentTimestamp.Time, "sent messages are stamped by the service's clock")
+	}
+
+	// Sent messages are kept for the retention period, pending ones until they are sent.
+	_, err = svc.AmendExecution(ctx, &domain.AmendDTO{ExecutionServiceID: 5, QuantityOrdered: qty(400)})
+	require.NoError(t, err)
+	simClock.Advance(time.Hour)
+	require.NoError(t, svc.pruneOutbox(ctx, time.Hour))
+	assert.Len(t, repo.outbox, 3)
+	simClock.Advance(time.Second)
+	require.NoError(t, svc.pruneOutbox(ctx, time.Hour))
+	require.Len(t, repo.outbox, 1)
+	assert.False(t, repo.outbox[0].SentTimestamp.Valid)
+}
@@ -0,0 +1,139 @@
+package service
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/kasbench/globeco-fix-engine/internal/calendar"
+	"github.com/kasbench/globeco-fix-engine/internal/config"
+)
+
+// maxPriceSteps bounds how far a path is followed from its start.
+const maxPriceSteps = 100000
+
+// PriceSimulator simulates an intraday price path per ticker and date: a
+// geometric Brownian motion that starts at the Pricing Service's price at the
+// session open, advances one step per Step of simulated time and is reflected
+// back into the day's High/Low range. The price at any time is a function of
+// the seed, ticker, date and the number of steps since the open only, so
+// replicas and restarts agree on it.
+type PriceSimulator struct {
+	volatility float64       // Standard deviation of log returns per hour
+	step       time.Duration // Time per random walk step
+	seed       int64         // Selects the paths, which are deterministic per ticker and date
+	mu         sync.Mutex
+	paths      map[string]*pricePath
+}
+
+// pricePath caches how far a ticker's path for date has been followed, so
+// that it is only ever walked forward. It is a cache: walking the path again
+// from the start gives the same prices.
+type pricePath struct {
+	date  string
+	steps int
+	price float64
+	rng   *rand.Rand
+}
+
+// NewPriceSimulator creates a PriceSimulator from config.
+func NewPriceSimulator(cfg config.PriceSimulationConfig, seed int64) (*PriceSimulator, error) {
+	if cfg.Volatility < 0 || cfg.Step <= 0 {
+		return nil, fmt.Errorf("invalid price simulation: volatility %v, step %dms", cfg.Volatility, cfg.Step)
+	}
+	return &PriceSimulator{
+		volatility: cfg.Volatility,
+		step:       time.Duration(cfg.Step) * time.Millisecond,
+		seed:       seed,
+		paths:      make(map[string]*pricePath),
+	}, nil
+}
+
+// Price returns the simulated price of bar's ticker at now. The path starts
+// at exchange's open on the bar's date, or at midnight UTC if exchange is nil
+// or does not trade that day; before then the price is the bar's close.
+func (p *PriceSimulator) Price(bar *PriceBar, exchange *calendar.Exchange, now time.Time) float64 {
+	start, ok := pathStart(bar.Date, exchange)
+	if !ok {
+		return bar.Close
+	}
+	steps := int(min(now.Sub(start)/p.step, maxPriceSteps))
+	if steps <= 0 {
+		return bar.Close
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	path, ok := p.paths[bar.Ticker]
+	if !ok || path.date != bar.Date || path.steps > steps {
+		path = &pricePath{date: bar.Date, price: bar.Close, rng: p.pathRand(bar)}
+		p.paths[bar.Ticker] = path
+	}
+	if path.steps == steps {
+		return path.price
+	}
+
+	// Each step multiplies the price by exp((-σ²/2)dt + σ√dt·Z), in log space.
+	dt := p.step.Hours()
+	drift := -p.volatility * p.volatility * dt / 2
+	diffusion := p.volatility * math.Sqrt(dt)
+	logPrice := math.Log(path.price)
+	logLow, logHigh, bounded := math.Inf(-1), math.Inf(1), false
+	if bar.Low > 0 && bar.High >= bar.Low {
+		logLow, logHigh, bounded = math.Log(bar.Low), math.Log(bar.High), true
+	}
+	for ; path.steps < steps; path.steps++ {
+		logPrice += drift + diffusion*path.rng.NormFloat64()
+		if bounded {
+			logPrice = reflect(logPrice, logLow, logHigh)
+		}
+	}
+	path.price = math.Exp(logPrice)
+	if bounded { // undo rounding in and out of log space
+		path.price = math.Min(math.Max(path.price, bar.Low), bar.High)
+	}
+	return path.price
+}
+
+// pathStart returns when the path for date starts.
+func pathStart(date string, exchange *calendar.Exchange) (time.Time, bool) {
+	if exchange != nil {
+		if open, ok := exchange.Open(date); ok {
+			return open, true
+		}
+	}
+	day, err := time.Parse(time.DateOnly, date)
+	return day, err == nil
+}
+
+// pathRand returns the random source for bar's ticker and date.
+func (p *PriceSimulator) pathRand(bar *PriceBar) *rand.Rand {
+	h := fnv.New64a()
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], uint64(p.seed))
+	h.Write(b[:])
+	h.Write([]byte(bar.Ticker))
+	h.Write([]byte{0})
+	h.Write([]byte(bar.Date))
+	return rand.New(rand.NewSource(int64(h.Sum64())))
+}
+
+// reflect folds x back into [lo, hi] as if the range had mirrored walls.
+func reflect(x, lo, hi float64) float64 {
+	width := hi - lo
+	if width <= 0 {
+		return lo
+	}
+	x = math.Mod(x-lo, 2*width)
+	if x < 0 {
+		x += 2 * width
+	}
+	if x > width {
+		x = 2*width - x
+	}
+	return lo + x
+}
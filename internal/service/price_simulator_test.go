@@ -0,0 +1,91 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kasbench/globeco-fix-engine/internal/calendar"
+	"github.com/kasbench/globeco-fix-engine/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testPriceSimulator(t *testing.T, seed int64) *PriceSimulator {
+	sim, err := NewPriceSimulator(config.PriceSimulationConfig{Simulate: true, Volatility: 0.05, Step: 1000}, seed)
+	require.NoError(t, err)
+	return sim
+}
+
+// testPathStart is where paths for 2026-10-16 start without a calendar.
+var testPathStart = time.Date(2026, 10, 16, 0, 0, 0, 0, time.UTC)
+
+func TestPriceSimulatorStaysWithinDayRange(t *testing.T) {
+	sim := testPriceSimulator(t, 42)
+	bar := &PriceBar{Ticker: "AAPL", Date: "2026-10-16", Close: 100, High: 101, Low: 99}
+
+	assert.Equal(t, 100.0, sim.Price(bar, nil, testPathStart))
+	moved := false
+	for i := 1; i <= 500; i++ {
+		price := sim.Price(bar, nil, testPathStart.Add(time.Duration(i)*time.Minute))
+		assert.GreaterOrEqual(t, price, 99.0)
+		assert.LessOrEqual(t, price, 101.0)
+		moved = moved || price != 100
+	}
+	assert.True(t, moved, "price should move over time")
+}
+
+func TestPriceSimulatorAgreesAcrossReplicas(t *testing.T) {
+	bar := &PriceBar{Ticker: "AAPL", Date: "2026-10-16", Close: 100, High: 110, Low: 90}
+	// One replica has followed the path all day, another sees the ticker for
+	// the first time, a third restarted and is asked about an earlier time.
+	a, b, c := testPriceSimulator(t, 7), testPriceSimulator(t, 7), testPriceSimulator(t, 7)
+	for i := 1; i <= 10; i++ {
+		a.Price(bar, nil, testPathStart.Add(time.Duration(i)*10*time.Minute))
+	}
+	late, early := testPathStart.Add(100*time.Minute), testPathStart.Add(30*time.Minute)
+	assert.Equal(t, a.Price(bar, nil, late), b.Price(bar, nil, late))
+	c.Price(bar, nil, late)
+	assert.Equal(t, a.Price(bar, nil, early), c.Price(bar, nil, early))
+
+	// Without a seed paths are still the same on every replica.
+	assert.Equal(t, testPriceSimulator(t, 0).Price(bar, nil, late), testPriceSimulator(t, 0).Price(bar, nil, late))
+	assert.NotEqual(t, a.Price(bar, nil, late), testPriceSimulator(t, 8).Price(bar, nil, late))
+}
+
+func TestPriceSimulatorStartsAtSessionOpen(t *testing.T) {
+	cal, err := calendar.New(calendar.File{Exchanges: []calendar.ExchangeFile{
+		{Name: "XNYS", TimeZone: "America/New_York", Open: "09:30", Close: "16:00"},
+	}}, "")
+	require.NoError(t, err)
+	open := time.Date(2026, 10, 16, 13, 30, 0, 0, time.UTC) // 09:30 in New York
+	bar := &PriceBar{Ticker: "AAPL", Date: "2026-10-16", Close: 100, High: 110, Low: 90}
+	sim := testPriceSimulator(t, 7)
+
+	assert.Equal(t, 100.0, sim.Price(bar, cal.Default(), open.Add(-time.Hour)))
+	assert.Equal(t, 100.0, sim.Price(bar, cal.Default(), open))
+	// The path is the one that starts at midnight UTC without a calendar, moved to the open.
+	assert.Equal(t, testPriceSimulator(t, 7).Price(bar, nil, testPathStart.Add(time.Hour)), sim.Price(bar, cal.Default(), open.Add(time.Hour)))
+}
+
+func TestPriceSimulatorRestartsOnNewDate(t *testing.T) {
+	sim := testPriceSimulator(t, 7)
+	bar := &PriceBar{Ticker: "AAPL", Date: "2026-10-16", Close: 100, High: 110, Low: 90}
+	sim.Price(bar, nil, testPathStart.Add(time.Hour))
+
+	next := &PriceBar{Ticker: "AAPL", Date: "2026-10-19", Close: 105, High: 106, Low: 104}
+	assert.Equal(t, 105.0, sim.Price(next, nil, time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC)))
+	assert.Equal(t, 105.0, sim.Price(next, nil, testPathStart.Add(2*time.Hour)), "before the path starts")
+}
+
+func TestPriceSimulatorFlatRange(t *testing.T) {
+	sim := testPriceSimulator(t, 0)
+	bar := &PriceBar{Ticker: "AAPL", Date: "2026-10-16", Close: 100, High: 100, Low: 100}
+	assert.Equal(t, 100.0, sim.Price(bar, nil, testPathStart.Add(time.Hour)))
+}
+
+func TestNewPriceSimulatorRejectsInvalidConfig(t *testing.T) {
+	_, err := NewPriceSimulator(config.PriceSimulationConfig{Volatility: 0.01, Step: 0}, 0)
+	assert.Error(t, err)
+	_, err = NewPriceSimulator(config.PriceSimulationConfig{Volatility: -1, Step: 1000}, 0)
+	assert.Error(t, err)
+}
@@ -12,6 +12,18 @@ import (
 	"go.uber.org/zap"
 )
 
+// PriceBar is the daily price bar the Pricing Service returns for a ticker.
+type PriceBar struct {
+	ID     int     `json:"id"`
+	Ticker string  `json:"ticker"`
+	Date   string  `json:"date"`
+	Open   float64 `json:"open"`
+	Close  float64 `json:"close"`
+	High   float64 `json:"high"`
+	Low    float64 `json:"low"`
+	Volume int     `json:"volume"`
+}
+
 type PricingServiceClient struct {
 	cfg    config.ServiceConfig
 	logger *zap.Logger
@@ -21,36 +33,28 @@ func NewPricingServiceClient(cfg config.ServiceConfig, logger *zap.Logger) *Pric
 	return &PricingServiceClient{cfg: cfg, logger: logger}
 }
 
-func (c *PricingServiceClient) GetPrice(ctx context.Context, ticker string) (float64, error) {
+// GetPriceBar returns the current price bar for ticker.
+func (c *PricingServiceClient) GetPriceBar(ctx context.Context, ticker string) (*PriceBar, error) {
 	url := fmt.Sprintf("http://%s:%d/api/v1/price/%s", c.cfg.Host, c.cfg.Port, ticker)
-	c.logger.Debug("PricingServiceClient.GetPrice", zap.String("url", url), zap.String("ticker", ticker))
+	c.logger.Debug("PricingServiceClient.GetPriceBar", zap.String("url", url), zap.String("ticker", ticker))
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
 	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
 	defer resp.Body.Close()
 	if resp.StatusCode != http.StatusOK {
 		var bodyBytes []byte
 		bodyBytes, _ = io.ReadAll(resp.Body)
-		log.Printf("PricingServiceClient.GetPrice: non-200 response %d, body: %s", resp.StatusCode, string(bodyBytes))
-		return 0, fmt.Errorf("pricing service returned status %d", resp.StatusCode)
-	}
-	var data struct {
-		ID     int     `json:"id"`
-		Ticker string  `json:"ticker"`
-		Date   string  `json:"date"`
-		Open   float64 `json:"open"`
-		Close  float64 `json:"close"`
-		High   float64 `json:"high"`
-		Low    float64 `json:"low"`
-		Volume int     `json:"volume"`
+		log.Printf("PricingServiceClient.GetPriceBar: non-200 response %d, body: %s", resp.StatusCode, string(bodyBytes))
+		return nil, fmt.Errorf("pricing service returned status %d", resp.StatusCode)
 	}
-	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
-		return 0, err
+	var bar PriceBar
+	if err := json.NewDecoder(resp.Body).Decode(&bar); err != nil {
+		return nil, err
 	}
-	return data.Close, nil
+	return &bar, nil
 }
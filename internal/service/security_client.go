@@ -3,6 +3,7 @@ package service
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"sync"
@@ -11,6 +12,9 @@ import (
 	"github.com/kasbench/globeco-fix-engine/internal/config"
 )
 
+// ErrSecurityNotFound is returned when the Security Service does not know a security ID.
+var ErrSecurityNotFound = errors.New("security not found")
+
 type SecurityServiceClient struct {
 	cfg   config.ServiceConfig
 	cache map[string]cachedTicker
@@ -47,6 +51,9 @@ func (c *SecurityServiceClient) GetTickerBySecurityID(ctx context.Context, secur
 		return "", err
 	}
 	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return "", fmt.Errorf("%w: %s", ErrSecurityNotFound, securityID)
+	}
 	if resp.StatusCode != http.StatusOK {
 		return "", fmt.Errorf("security service returned status %d", resp.StatusCode)
 	}
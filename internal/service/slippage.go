@@ -0,0 +1,82 @@
+package service
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+
+	"github.com/kasbench/globeco-fix-engine/internal/config"
+	"github.com/kasbench/globeco-fix-engine/internal/domain"
+	"github.com/kasbench/globeco-fix-engine/internal/repository"
+	"github.com/shopspring/decimal"
+)
+
+// SlippageModel moves the fill price away from the market price: adverse
+// market impact proportional to the fill's share of daily volume, plus a
+// random part of the spread, with occasional price improvement for limit
+// orders. Costs are in basis points of the market price.
+type SlippageModel struct {
+	impact          float64 // bps of impact per 1% of daily volume filled
+	maxImpact       float64 // cap on impact, bps
+	spread          float64 // maximum spread cost, bps
+	improvement     float64 // maximum price improvement, bps
+	improvementProb float64 // probability a limit order fill is improved
+}
+
+// NewSlippageModel creates a SlippageModel from config. All-zero config
+// creates a model that fills at the market price.
+func NewSlippageModel(cfg config.SlippageConfig) (*SlippageModel, error) {
+	if cfg.Impact < 0 || cfg.MaxImpact < 0 || cfg.Spread < 0 || cfg.Improvement < 0 {
+		return nil, fmt.Errorf("invalid slippage: costs must not be negative")
+	}
+	if cfg.ImprovementProbability < 0 || cfg.ImprovementProbability > 1 {
+		return nil, fmt.Errorf("invalid slippage: improvement probability %v not in [0, 1]", cfg.ImprovementProbability)
+	}
+	return &SlippageModel{
+		impact:          cfg.Impact,
+		maxImpact:       cfg.MaxImpact,
+		spread:          cfg.Spread,
+		improvement:     cfg.Improvement,
+		improvementProb: cfg.ImprovementProbability,
+	}, nil
+}
+
+// FillPrice returns the price exec fills fillQty at when the market is at
+// price. BUY and COVER pay up, SELL and SHORT receive less, and a limit order
+// never fills through its limit. The price is rounded to the stored scale.
+func (m *SlippageModel) FillPrice(rng *rand.Rand, exec *repository.Execution, price, fillQty decimal.Decimal, bar *PriceBar) decimal.Decimal {
+	bps := m.spread * rng.Float64()
+	if bar.Volume > 0 {
+		bps += math.Min(m.impact*100*fillQty.InexactFloat64()/float64(bar.Volume), m.maxImpact)
+	}
+	if exec.LimitPrice.Valid && m.improvementProb > 0 && rng.Float64() < m.improvementProb {
+		bps = -m.improvement * rng.Float64()
+	}
+
+	if isBuy(exec.TradeType) {
+		price = domain.RoundScale(price.Mul(decimal.NewFromFloat(1 + bps/10000)))
+		if exec.LimitPrice.Valid {
+			price = decimal.Min(price, exec.LimitPrice.Decimal)
+		}
+	} else {
+		price = domain.RoundScale(price.Mul(decimal.NewFromFloat(1 - bps/10000)))
+		if exec.LimitPrice.Valid {
+			price = decimal.Max(price, exec.LimitPrice.Decimal)
+		}
+	}
+	return price
+}
+
+// isTradeType reports whether tradeType is one the engine can fill.
+func isTradeType(tradeType string) bool {
+	switch tradeType {
+	case "BUY", "SELL", "SHORT", "COVER":
+		return true
+	}
+	return false
+}
+
+// isBuy reports whether tradeType buys (BUY, COVER) rather than sells (SELL, SHORT).
+func isBuy(tradeType string) bool {
+	return tradeType == "BUY" || tradeType == "COVER"
+}
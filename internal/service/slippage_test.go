@@ -0,0 +1,69 @@
+package service
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/kasbench/globeco-fix-engine/internal/config"
+	"github.com/kasbench/globeco-fix-engine/internal/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSlippageModelDirection(t *testing.T) {
+	m, err := NewSlippageModel(config.SlippageConfig{Impact: 10, MaxImpact: 100, Spread: 5})
+	require.NoError(t, err)
+	bar := &PriceBar{Volume: 100000}
+	rng := rand.New(rand.NewSource(1))
+
+	for _, tradeType := range []string{"BUY", "COVER"} {
+		price := m.FillPrice(rng, &repository.Execution{TradeType: tradeType}, dec(100), dec(1000), bar).InexactFloat64()
+		assert.Greater(t, price, 100.0, tradeType)
+		assert.LessOrEqual(t, price, 100*(1+15.0/10000), tradeType)
+	}
+	for _, tradeType := range []string{"SELL", "SHORT"} {
+		price := m.FillPrice(rng, &repository.Execution{TradeType: tradeType}, dec(100), dec(1000), bar).InexactFloat64()
+		assert.Less(t, price, 100.0, tradeType)
+		assert.GreaterOrEqual(t, price, 100*(1-15.0/10000), tradeType)
+	}
+}
+
+func TestSlippageModelImpactScalesWithVolume(t *testing.T) {
+	m, err := NewSlippageModel(config.SlippageConfig{Impact: 10, MaxImpact: 50})
+	require.NoError(t, err)
+	exec := &repository.Execution{TradeType: "BUY"}
+	rng := rand.New(rand.NewSource(1))
+
+	// 1% of daily volume costs 10bps, 10% hits the 50bps cap, no volume costs nothing
+	assert.Equal(t, "100.1", m.FillPrice(rng, exec, dec(100), dec(1000), &PriceBar{Volume: 100000}).String())
+	assert.Equal(t, "100.5", m.FillPrice(rng, exec, dec(100), dec(10000), &PriceBar{Volume: 100000}).String())
+	assert.Equal(t, "100", m.FillPrice(rng, exec, dec(100), dec(1000), &PriceBar{}).String())
+}
+
+func TestSlippageModelLimitOrders(t *testing.T) {
+	m, err := NewSlippageModel(config.SlippageConfig{Impact: 10, MaxImpact: 100, Improvement: 2, ImprovementProbability: 1})
+	require.NoError(t, err)
+	rng := rand.New(rand.NewSource(1))
+	bar := &PriceBar{Volume: 100000}
+
+	// Improved limit fills buy below and sell above the market price
+	buy := &repository.Execution{TradeType: "BUY", LimitPrice: toNullDecimal(101)}
+	assert.True(t, m.FillPrice(rng, buy, dec(100), dec(1000), bar).LessThanOrEqual(dec(100)))
+	sell := &repository.Execution{TradeType: "SELL", LimitPrice: toNullDecimal(99)}
+	assert.True(t, m.FillPrice(rng, sell, dec(100), dec(1000), bar).GreaterThanOrEqual(dec(100)))
+
+	// Costs never push a fill through the limit
+	m, err = NewSlippageModel(config.SlippageConfig{Impact: 10, MaxImpact: 100})
+	require.NoError(t, err)
+	buy.LimitPrice = toNullDecimal(100.05)
+	assert.Equal(t, "100.05", m.FillPrice(rng, buy, dec(100), dec(10000), bar).String())
+	sell.LimitPrice = toNullDecimal(99.95)
+	assert.Equal(t, "99.95", m.FillPrice(rng, sell, dec(100), dec(10000), bar).String())
+}
+
+func TestNewSlippageModelRejectsInvalidConfig(t *testing.T) {
+	_, err := NewSlippageModel(config.SlippageConfig{Spread: -1})
+	assert.Error(t, err)
+	_, err = NewSlippageModel(config.SlippageConfig{ImprovementProbability: 1.5})
+	assert.Error(t, err)
+}
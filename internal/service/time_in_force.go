@@ -0,0 +1,143 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/kasbench/globeco-fix-engine/internal/config"
+	"github.com/kasbench/globeco-fix-engine/internal/domain"
+	"github.com/kasbench/globeco-fix-engine/internal/repository"
+	"go.uber.org/zap"
+)
+
+// ErrInvalidOrder is returned (wrapped with the reason) when an inbound order is malformed.
+var ErrInvalidOrder = errors.New("invalid order")
+
+// SessionClose is the daily close of the trading session, at which DAY orders expire.
+type SessionClose struct {
+	Hour     int
+	Minute   int
+	Location *time.Location
+}
+
+// defaultSessionClose is used when the service has no SessionClose configured.
+var defaultSessionClose = &SessionClose{Hour: 16, Location: time.UTC}
+
+// NewSessionClose parses the session close from config.
+func NewSessionClose(cfg config.TradingSessionConfig) (*SessionClose, error) {
+	loc, err := time.LoadLocation(cfg.TimeZone)
+	if err != nil {
+		return nil, fmt.Errorf("invalid session time zone %q: %w", cfg.TimeZone, err)
+	}
+	t, err := time.Parse("15:04", cfg.CloseTime)
+	if err != nil {
+		return nil, fmt.Errorf("invalid session close time %q: %w", cfg.CloseTime, err)
+	}
+	return &SessionClose{Hour: t.Hour(), Minute: t.Minute(), Location: loc}, nil
+}
+
+// Next returns the first session close strictly after t.
+func (c *SessionClose) Next(t time.Time) time.Time {
+	local := t.In(c.Location)
+	next := time.Date(local.Year(), local.Month(), local.Day(), c.Hour, c.Minute, 0, 0, c.Location)
+	if !next.After(local) {
+		next = time.Date(local.Year(), local.Month(), local.Day()+1, c.Hour, c.Minute, 0, 0, c.Location)
+	}
+	return next.UTC()
+}
+
+// resolveTimeInForce validates the order's time in force and works out when it
+// expires. Orders without a time in force are GTC.
+func (s *ExecutionService) resolveTimeInForce(postDTO *domain.ExecutionDTO, now time.Time) (string, *time.Time, error) {
+	tif := strings.ToUpper(postDTO.TimeInForce)
+	switch tif {
+	case "":
+		return domain.TimeInForceGTC, nil, nil
+	case domain.TimeInForceGTC, domain.TimeInForceIOC, domain.TimeInForceFOK:
+		return tif, nil, nil
+	case domain.TimeInForceDay:
+		if exchange := s.exchange(s.venue(postDTO.Destination)); exchange != nil {
+			expire := exchange.NextClose(now)
+			return tif, &expire, nil
+		}
+		sessionClose := s.SessionClose
+		if sessionClose == nil {
+			sessionClose = defaultSessionClose
+		}
+		expire := sessionClose.Next(now)
+		return tif, &expire, nil
+	case domain.TimeInForceGTD:
+		if postDTO.ExpireTimestamp == nil {
+			return "", nil, fmt.Errorf("%w: GTD requires expireTimestamp", ErrInvalidOrder)
+		}
+		expire := postDTO.ExpireTimestamp.Time()
+		return tif, &expire, nil
+	}
+	return "", nil, fmt.Errorf("%w: unsupported timeInForce %q", ErrInvalidOrder, postDTO.TimeInForce)
+}
+
+// applyTimeInForce cancels whatever is left of an IOC or FOK order after its
+// single fill attempt. It reports whether the remainder was cancelled.
+func (s *ExecutionService) applyTimeInForce(ctx context.Context, exec *repository.Execution) (bool, error) {
+	if !exec.IsOpen || (exec.TimeInForce != domain.TimeInForceIOC && exec.TimeInForce != domain.TimeInForceFOK) {
+		return false, nil
+	}
+	if err := s.transition(ctx, exec, domain.StatusCancelled); err != nil {
+		return false, err
+	}
+	exec.NextFillTimestamp = sqlNullTime(nil)
+	return true, nil
+}
+
+// StartExpirySweeper periodically closes DAY and GTD executions that have
+// passed their expiry with status EXPD and publishes their final state.
+func (s *ExecutionService) StartExpirySweeper(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.sweepExpired(ctx); err != nil {
+				log.Printf("error sweeping expired executions: %v", err)
+			}
+		}
+	}
+}
+
+// sweepExpired expires the executions due at the current time. The sweep is
+// one transaction, so expiries are only kept once they are published. An
+// execution the state machine refuses to expire is left as it is.
+func (s *ExecutionService) sweepExpired(ctx context.Context) error {
+	return s.Repo.WithinTx(ctx, func(ctx context.Context, repo repository.ExecutionRepository) error {
+		now := s.now()
+		execs, err := repo.PollDueForExpiry(ctx, now)
+		if err != nil {
+			return fmt.Errorf("polling expired executions: %w", err)
+		}
+		for _, exec := range execs {
+			if err := s.transition(ctx, exec, domain.StatusExpired); err != nil {
+				if errors.Is(err, domain.ErrInvalidTransition) {
+					continue
+				}
+				return err
+			}
+			exec.NextFillTimestamp = sqlNullTime(nil)
+			if err := repo.Update(ctx, exec); err != nil {
+				return fmt.Errorf("expiring execution %d: %w", exec.ID, err)
+			}
+			if err := s.publish(ctx, repo, exec, expireEvent(exec, now, s.averagePriceRounding())); err != nil {
+				return fmt.Errorf("publishing expiry: %w", err)
+			}
+			s.Logger.Debug("execution expired",
+				zap.Int("execution_service_id", exec.ExecutionServiceID),
+				zap.String("time_in_force", exec.TimeInForce))
+		}
+		return nil
+	})
+}
@@ -0,0 +1,101 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/kasbench/globeco-fix-engine/internal/config"
+	"github.com/kasbench/globeco-fix-engine/internal/domain"
+	"github.com/kasbench/globeco-fix-engine/internal/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestSessionClose_Next(t *testing.T) {
+	sessionClose, err := NewSessionClose(config.TradingSessionConfig{CloseTime: "16:00", TimeZone: "America/New_York"})
+	require.NoError(t, err)
+	ny := sessionClose.Location
+
+	// Before the close the order expires today, at or after it expires tomorrow.
+	assert.Equal(t, time.Date(2025, 3, 3, 16, 0, 0, 0, ny).UTC(), sessionClose.Next(time.Date(2025, 3, 3, 9, 30, 0, 0, ny)))
+	assert.Equal(t, time.Date(2025, 3, 4, 16, 0, 0, 0, ny).UTC(), sessionClose.Next(time.Date(2025, 3, 3, 16, 0, 0, 0, ny)))
+
+	_, err = NewSessionClose(config.TradingSessionConfig{CloseTime: "4pm", TimeZone: "UTC"})
+	assert.Error(t, err)
+}
+
+func TestResolveTimeInForce(t *testing.T) {
+	svc := &ExecutionService{SessionClose: &SessionClose{Hour: 16, Location: time.UTC}}
+	now := time.Date(2025, 3, 3, 12, 0, 0, 0, time.UTC)
+
+	tif, expire, err := svc.resolveTimeInForce(&domain.ExecutionDTO{}, now)
+	require.NoError(t, err)
+	assert.Equal(t, domain.TimeInForceGTC, tif)
+	assert.Nil(t, expire)
+
+	tif, expire, err = svc.resolveTimeInForce(&domain.ExecutionDTO{TimeInForce: "day"}, now)
+	require.NoError(t, err)
+	assert.Equal(t, domain.TimeInForceDay, tif)
+	assert.Equal(t, time.Date(2025, 3, 3, 16, 0, 0, 0, time.UTC), *expire)
+
+	gtd := domain.EpochTimeFromTime(now.Add(48 * time.Hour))
+	tif, expire, err = svc.resolveTimeInForce(&domain.ExecutionDTO{TimeInForce: "GTD", ExpireTimestamp: &gtd}, now)
+	require.NoError(t, err)
+	assert.Equal(t, domain.TimeInForceGTD, tif)
+	assert.WithinDuration(t, now.Add(48*time.Hour), *expire, time.Millisecond)
+
+	_, _, err = svc.resolveTimeInForce(&domain.ExecutionDTO{TimeInForce: "GTD"}, now)
+	assert.ErrorIs(t, err, ErrInvalidOrder)
+	_, _, err = svc.resolveTimeInForce(&domain.ExecutionDTO{TimeInForce: "GTX"}, now)
+	assert.ErrorIs(t, err, ErrInvalidOrder)
+}
+
+func TestApplyTimeInForce(t *testing.T) {
+	svc := &ExecutionService{Logger: zap.NewNop()}
+	ioc := &repository.Execution{IsOpen: true, ExecutionStatus: "PART", TimeInForce: domain.TimeInForceIOC}
+	cancelled, err := svc.applyTimeInForce(context.Background(), ioc)
+	require.NoError(t, err)
+	assert.True(t, cancelled)
+	assert.False(t, ioc.IsOpen)
+	assert.Equal(t, "CANC", ioc.ExecutionStatus)
+
+	filled := &repository.Execution{IsOpen: false, ExecutionStatus: "FULL", TimeInForce: domain.TimeInForceFOK}
+	cancelled, err = svc.applyTimeInForce(context.Background(), filled)
+	require.NoError(t, err)
+	assert.False(t, cancelled)
+	assert.Equal(t, "FULL", filled.ExecutionStatus)
+
+	gtc := &repository.Execution{IsOpen: true, ExecutionStatus: "WORK", TimeInForce: domain.TimeInForceGTC}
+	cancelled, err = svc.applyTimeInForce(context.Background(), gtc)
+	require.NoError(t, err)
+	assert.False(t, cancelled)
+	assert.True(t, gtc.IsOpen)
+}
+
+func TestSweepExpired(t *testing.T) {
+	now := time.Now().UTC()
+	expired := sql.NullTime{Time: now.Add(-time.Minute), Valid: true}
+	repo := &fakeRepo{execs: map[int]*repository.Execution{
+		1: {ID: 1, ExecutionServiceID: 1, IsOpen: true, ExecutionStatus: "WORK", TimeInForce: domain.TimeInForceGTD, ExpireTimestamp: expired,
+			NextFillTimestamp: sql.NullTime{Time: now, Valid: true}, Version: 1},
+		// Open but already FULL, which the state machine refuses to expire.
+		2: {ID: 2, ExecutionServiceID: 2, IsOpen: true, ExecutionStatus: "FULL", TimeInForce: domain.TimeInForceGTD, ExpireTimestamp: expired, Version: 1},
+		3: {ID: 3, ExecutionServiceID: 3, IsOpen: true, ExecutionStatus: "WORK", TimeInForce: domain.TimeInForceGTD,
+			ExpireTimestamp: sql.NullTime{Time: now.Add(time.Hour), Valid: true}, Version: 1},
+	}}
+	svc := &ExecutionService{Repo: repo, Logger: zap.NewNop()}
+
+	require.NoError(t, svc.sweepExpired(context.Background()))
+	assert.Equal(t, "EXPD", repo.execs[1].ExecutionStatus)
+	assert.False(t, repo.execs[1].IsOpen)
+	assert.False(t, repo.execs[1].NextFillTimestamp.Valid)
+	assert.Equal(t, 2, repo.execs[1].Version)
+	assert.Equal(t, "FULL", repo.execs[2].ExecutionStatus)
+	assert.Equal(t, 1, repo.execs[2].Version)
+	assert.True(t, repo.execs[3].IsOpen)
+	require.Len(t, repo.outbox, 1)
+	assert.Equal(t, []byte("1"), repo.outbox[0].MessageKey)
+}
@@ -0,0 +1,210 @@
+package service
+
+import (
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/kasbench/globeco-fix-engine/internal/calendar"
+	"github.com/kasbench/globeco-fix-engine/internal/config"
+	"github.com/kasbench/globeco-fix-engine/internal/domain"
+	"github.com/shopspring/decimal"
+	"go.uber.org/zap"
+)
+
+// maxScheduleSteps bounds the search for a time at which both a venue and its
+// exchange are trading, in case their hours never overlap.
+const maxScheduleSteps = 1000
+
+// Venue is the simulated behaviour of one destination: its fill model, the
+// latency it adds to each fill attempt, how often it rejects orders, its
+// trading hours, the exchange calendar it follows and its fees. Executions
+// for destinations without a configured venue use defaultVenue.
+type Venue struct {
+	Name              string
+	FillModel         FillModel          // nil uses the service's fill model
+	Exchange          *calendar.Exchange // nil follows the calendar's default exchange
+	minLatency        time.Duration
+	maxLatency        time.Duration
+	rejectProbability float64
+	hours             *tradingHours // nil trades around the clock
+	feePerShare       decimal.Decimal
+	feeBps            decimal.Decimal
+}
+
+// defaultVenue fills with the service's fill model, immediately, around the clock and for free.
+var defaultVenue = &Venue{}
+
+// tradingHours is a venue's daily trading window.
+type tradingHours struct {
+	open, close time.Duration // offsets from local midnight
+	location    *time.Location
+}
+
+// NewVenues creates the venues from config, keyed by destination. Venues
+// naming an exchange need it in cal.
+func NewVenues(cfgs []config.VenueConfig, cal *calendar.Calendar) (map[string]*Venue, error) {
+	venues := make(map[string]*Venue, len(cfgs))
+	for _, cfg := range cfgs {
+		if cfg.Name == "" {
+			return nil, fmt.Errorf("venue without a name")
+		}
+		if _, ok := venues[cfg.Name]; ok {
+			return nil, fmt.Errorf("duplicate venue %q", cfg.Name)
+		}
+		venue, err := newVenue(cfg, cal)
+		if err != nil {
+			return nil, fmt.Errorf("venue %q: %w", cfg.Name, err)
+		}
+		venues[cfg.Name] = venue
+	}
+	return venues, nil
+}
+
+func newVenue(cfg config.VenueConfig, cal *calendar.Calendar) (*Venue, error) {
+	if cfg.MinLatency < 0 || cfg.MaxLatency < cfg.MinLatency {
+		return nil, fmt.Errorf("invalid latency range %d-%dms", cfg.MinLatency, cfg.MaxLatency)
+	}
+	if cfg.RejectProbability < 0 || cfg.RejectProbability > 1 {
+		return nil, fmt.Errorf("invalid reject probability %v", cfg.RejectProbability)
+	}
+	if cfg.FeePerShare < 0 || cfg.FeeBps < 0 {
+		return nil, fmt.Errorf("invalid fees: %v per share, %vbps", cfg.FeePerShare, cfg.FeeBps)
+	}
+	venue := &Venue{
+		Name:              cfg.Name,
+		minLatency:        time.Duration(cfg.MinLatency) * time.Millisecond,
+		maxLatency:        time.Duration(cfg.MaxLatency) * time.Millisecond,
+		rejectProbability: cfg.RejectProbability,
+		feePerShare:       decimal.NewFromFloat(cfg.FeePerShare),
+		feeBps:            decimal.NewFromFloat(cfg.FeeBps),
+	}
+	if cfg.Fill != nil {
+		fillModel, err := NewFillModel(*cfg.Fill)
+		if err != nil {
+			return nil, err
+		}
+		venue.FillModel = fillModel
+	}
+	if cfg.Exchange != "" {
+		if cal == nil {
+			return nil, fmt.Errorf("exchange %q needs a trading calendar", cfg.Exchange)
+		}
+		exchange, ok := cal.Exchange(cfg.Exchange)
+		if !ok {
+			return nil, fmt.Errorf("exchange %q is not in the trading calendar", cfg.Exchange)
+		}
+		venue.Exchange = exchange
+	}
+	if cfg.OpenTime != "" || cfg.CloseTime != "" {
+		hours, err := newTradingHours(cfg.OpenTime, cfg.CloseTime, cfg.TimeZone)
+		if err != nil {
+			return nil, err
+		}
+		venue.hours = hours
+	}
+	return venue, nil
+}
+
+func newTradingHours(openTime, closeTime, timeZone string) (*tradingHours, error) {
+	loc, err := time.LoadLocation(timeZone)
+	if err != nil {
+		return nil, fmt.Errorf("invalid time zone %q: %w", timeZone, err)
+	}
+	open, err := time.Parse("15:04", openTime)
+	if err != nil {
+		return nil, fmt.Errorf("invalid open time %q: %w", openTime, err)
+	}
+	close, err := time.Parse("15:04", closeTime)
+	if err != nil {
+		return nil, fmt.Errorf("invalid close time %q: %w", closeTime, err)
+	}
+	hours := &tradingHours{
+		open:     time.Duration(open.Hour())*time.Hour + time.Duration(open.Minute())*time.Minute,
+		close:    time.Duration(close.Hour())*time.Hour + time.Duration(close.Minute())*time.Minute,
+		location: loc,
+	}
+	if hours.close <= hours.open {
+		return nil, fmt.Errorf("close time %s is not after open time %s", closeTime, openTime)
+	}
+	return hours, nil
+}
+
+// venue returns the venue executions for destination are simulated at.
+func (s *ExecutionService) venue(destination string) *Venue {
+	if venue, ok := s.Venues[destination]; ok {
+		return venue
+	}
+	return defaultVenue
+}
+
+// exchange returns the exchange calendar venue follows, nil if it trades around the clock.
+func (s *ExecutionService) exchange(venue *Venue) *calendar.Exchange {
+	if venue.Exchange != nil || s.Calendar == nil {
+		return venue.Exchange
+	}
+	return s.Calendar.Default()
+}
+
+// tradingTime returns the first time at or after t at which both venue and
+// its exchange are trading. If their hours never overlap it warns and gives
+// up after maxScheduleSteps, returning a time at which one of them is closed.
+func (s *ExecutionService) tradingTime(venue *Venue, t time.Time) time.Time {
+	exchange := s.exchange(venue)
+	for i := 0; i < maxScheduleSteps; i++ {
+		next := venue.NextOpen(t)
+		if exchange != nil {
+			next = exchange.NextOpen(next)
+		}
+		if next.Equal(t) {
+			return t
+		}
+		t = next
+	}
+	fields := []zap.Field{zap.String("venue", venue.Name), zap.Time("scheduled", t)}
+	if exchange != nil {
+		fields = append(fields, zap.String("exchange", exchange.Name))
+	}
+	s.Logger.Warn("venue and exchange trading hours never overlap, scheduling outside trading hours", fields...)
+	return t
+}
+
+// Latency draws the delay the venue adds to a fill attempt.
+func (v *Venue) Latency(rng *rand.Rand) time.Duration {
+	if v.maxLatency <= v.minLatency {
+		return v.minLatency
+	}
+	return v.minLatency + time.Duration(rng.Int63n(int64(v.maxLatency-v.minLatency)))
+}
+
+// Rejects draws whether the venue rejects an order routed to it.
+func (v *Venue) Rejects(rng *rand.Rand) bool {
+	return v.rejectProbability > 0 && rng.Float64() < v.rejectProbability
+}
+
+// Fee returns the venue's fee for filling quantity at price, rounded to the stored scale.
+func (v *Venue) Fee(quantity, price decimal.Decimal) decimal.Decimal {
+	value := quantity.Mul(price)
+	return domain.RoundScale(quantity.Mul(v.feePerShare).Add(value.Mul(v.feeBps).Shift(-4)))
+}
+
+// NextOpen returns t if the venue is trading at t, and otherwise when it next opens.
+func (v *Venue) NextOpen(t time.Time) time.Time {
+	if v.hours == nil {
+		return t
+	}
+	local := t.In(v.hours.location)
+	open := v.hours.on(local, 0, v.hours.open)
+	switch {
+	case local.Before(open):
+		return open.UTC()
+	case local.Before(v.hours.on(local, 0, v.hours.close)):
+		return t
+	}
+	return v.hours.on(local, 1, v.hours.open).UTC()
+}
+
+// on returns the time offset into the day days after local's.
+func (h *tradingHours) on(local time.Time, days int, offset time.Duration) time.Time {
+	return time.Date(local.Year(), local.Month(), local.Day()+days, 0, int(offset/time.Minute), 0, 0, h.location)
+}
@@ -0,0 +1,141 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/kasbench/globeco-fix-engine/internal/calendar"
+	"github.com/kasbench/globeco-fix-engine/internal/config"
+	"github.com/kasbench/globeco-fix-engine/internal/domain"
+	"github.com/kasbench/globeco-fix-engine/internal/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestNewVenues(t *testing.T) {
+	fill := testFillModelConfig(FillModelPoisson)
+	venues, err := NewVenues([]config.VenueConfig{
+		{Name: "LIT", MinLatency: 1, MaxLatency: 20, OpenTime: "09:30", CloseTime: "16:00", TimeZone: "America/New_York"},
+		{Name: "DARK", Fill: &fill, RejectProbability: 0.1},
+	}, nil)
+	require.NoError(t, err)
+	assert.Nil(t, venues["LIT"].FillModel)
+	assert.IsType(t, poissonFillModel{}, venues["DARK"].FillModel)
+
+	for _, cfg := range []config.VenueConfig{
+		{},
+		{Name: "X", MinLatency: 20, MaxLatency: 10},
+		{Name: "X", RejectProbability: 2},
+		{Name: "X", FeeBps: -1},
+		{Name: "X", OpenTime: "16:00", CloseTime: "09:30", TimeZone: "UTC"},
+		{Name: "X", OpenTime: "09:30", CloseTime: "16:00", TimeZone: "Nowhere/Special"},
+		{Name: "X", Fill: &config.FillModelConfig{Model: "bogus", MinDelay: 1, MaxDelay: 2}},
+		{Name: "X", Exchange: "XNYS"}, // no calendar
+	} {
+		_, err := NewVenues([]config.VenueConfig{cfg}, nil)
+		assert.Error(t, err, "%+v", cfg)
+	}
+	_, err = NewVenues([]config.VenueConfig{{Name: "LIT"}, {Name: "LIT"}}, nil)
+	assert.Error(t, err)
+}
+
+func TestVenueNextOpen(t *testing.T) {
+	venues, err := NewVenues([]config.VenueConfig{{Name: "LIT", OpenTime: "09:30", CloseTime: "16:00", TimeZone: "America/New_York"}}, nil)
+	require.NoError(t, err)
+	venue := venues["LIT"]
+	ny, _ := time.LoadLocation("America/New_York")
+	open := time.Date(2026, 10, 16, 9, 30, 0, 0, ny)
+
+	assert.Equal(t, open.UTC(), venue.NextOpen(time.Date(2026, 10, 16, 8, 0, 0, 0, ny)))
+	during := time.Date(2026, 10, 16, 12, 0, 0, 0, ny).UTC()
+	assert.Equal(t, during, venue.NextOpen(during))
+	assert.Equal(t, open.AddDate(0, 0, 1).UTC(), venue.NextOpen(time.Date(2026, 10, 16, 16, 0, 0, 0, ny)))
+
+	// Venues without trading hours are always open
+	assert.Equal(t, during, defaultVenue.NextOpen(during))
+}
+
+func TestVenueLatencyAndFees(t *testing.T) {
+	venues, err := NewVenues([]config.VenueConfig{{Name: "LIT", MinLatency: 10, MaxLatency: 20, FeePerShare: 0.003, FeeBps: 1}}, nil)
+	require.NoError(t, err)
+	venue := venues["LIT"]
+	rng := rand.New(rand.NewSource(1))
+	for i := 0; i < 100; i++ {
+		latency := venue.Latency(rng)
+		assert.GreaterOrEqual(t, latency, 10*time.Millisecond)
+		assert.Less(t, latency, 20*time.Millisecond)
+	}
+	// 100 shares at 50: 0.30 per share plus 1bp of 5000
+	assert.Equal(t, "0.8", venue.Fee(dec(100), dec(50)).String())
+	assert.Equal(t, time.Duration(0), defaultVenue.Latency(rng))
+	assert.Equal(t, "0", defaultVenue.Fee(dec(100), dec(50)).String())
+}
+
+func TestIngestOrderVenueReject(t *testing.T) {
+	repo := &fakeRepo{execs: map[int]*repository.Execution{}}
+	venues, err := NewVenues([]config.VenueConfig{{Name: "ML", RejectProbability: 1}}, nil)
+	require.NoError(t, err)
+	svc := &ExecutionService{Repo: repo, SecurityClient: newTestSecurityClient(t, "SEC1", "IBM"), Venues: venues, Logger: zap.NewNop()}
+	order := []byte(`{"id": 101, "tradeType": "BUY", "destination": "ML", "securityId": "SEC1", "quantity": 100, "version": 1}`)
+
+	exec, _, err := svc.ingestOrder(context.Background(), order, &domain.ExecutionDTO{})
+	require.NoError(t, err)
+	assert.False(t, exec.IsOpen)
+	assert.Equal(t, "REJ", exec.ExecutionStatus)
+	assert.False(t, exec.NextFillTimestamp.Valid)
+	require.Len(t, repo.outbox, 1)
+	var event domain.ExecutionEventDTO
+	require.NoError(t, json.Unmarshal(repo.outbox[0].Payload, &event))
+	assert.Equal(t, domain.EventTypeReject, event.EventType)
+	assert.Equal(t, "REJ", event.ExecutionStatus)
+}
+
+func TestTradingTime_WarnsWhenHoursNeverOverlap(t *testing.T) {
+	cal, err := calendar.New(calendar.File{Exchanges: []calendar.ExchangeFile{
+		{Name: "XNYS", TimeZone: "America/New_York", Open: "09:30", Close: "16:00"},
+	}}, "")
+	require.NoError(t, err)
+	venues, err := NewVenues([]config.VenueConfig{{Name: "NIGHT", OpenTime: "20:00", CloseTime: "21:00", TimeZone: "America/New_York"}}, cal)
+	require.NoError(t, err)
+	core, logs := observer.New(zap.WarnLevel)
+	svc := &ExecutionService{Calendar: cal, Venues: venues, Logger: zap.New(core)}
+
+	start := time.Date(2026, 11, 25, 14, 30, 0, 0, time.UTC)
+	assert.False(t, svc.tradingTime(svc.venue("NIGHT"), start).Before(start))
+	require.Equal(t, 1, logs.Len())
+	entry := logs.All()[0]
+	assert.Equal(t, "NIGHT", entry.ContextMap()["venue"])
+	assert.Equal(t, "XNYS", entry.ContextMap()["exchange"])
+}
+
+func TestTradingCalendarSchedulesFills(t *testing.T) {
+	open := time.Date(2026, 11, 25, 14, 30, 0, 0, time.UTC) // 09:30 in New York
+	cal, err := calendar.New(calendar.File{Exchanges: []calendar.ExchangeFile{
+		{Name: "XNYS", TimeZone: "America/New_York", Open: "09:30", Close: "16:00", Holidays: []string{"2026-11-26"}},
+		{Name: "XLON", TimeZone: "Europe/London", Open: "08:00", Close: "16:30"},
+	}}, "")
+	require.NoError(t, err)
+	venues, err := NewVenues([]config.VenueConfig{{Name: "LSE", Exchange: "XLON"}}, cal)
+	require.NoError(t, err)
+	svc := &ExecutionService{Calendar: cal, Venues: venues}
+
+	during := open.Add(time.Hour)
+	assert.Equal(t, during, svc.tradingTime(svc.venue("ML"), during))
+	afterClose := open.Add(7 * time.Hour)
+	assert.Equal(t, open.AddDate(0, 0, 2), svc.tradingTime(svc.venue("ML"), afterClose)) // skips Thanksgiving
+
+	// Destinations on London follow its calendar instead
+	assert.Equal(t, during, svc.tradingTime(svc.venue("LSE"), during))
+	assert.Equal(t, time.Date(2026, 11, 26, 8, 0, 0, 0, time.UTC), svc.tradingTime(svc.venue("LSE"), afterClose))
+
+	// DAY orders expire at the close of their exchange
+	tif, expire, err := svc.resolveTimeInForce(&domain.ExecutionDTO{TimeInForce: "DAY", Destination: "ML"}, during)
+	require.NoError(t, err)
+	assert.Equal(t, domain.TimeInForceDay, tif)
+	assert.Equal(t, open.Add(390*time.Minute), *expire)
+}
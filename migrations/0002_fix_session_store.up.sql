@@ -0,0 +1,17 @@
+-- Create FIX session sequence number table
+CREATE TABLE public.fix_session (
+	session_id varchar(100) NOT NULL,
+	next_sender_seq_num integer NOT NULL DEFAULT 1,
+	next_target_seq_num integer NOT NULL DEFAULT 1,
+	creation_time timestamptz NOT NULL DEFAULT NOW(),
+	CONSTRAINT fix_session_pk PRIMARY KEY (session_id)
+);
+
+-- Create FIX outbound message journal table
+CREATE TABLE public.fix_message (
+	session_id varchar(100) NOT NULL,
+	msg_seq_num integer NOT NULL,
+	message bytea NOT NULL,
+	CONSTRAINT fix_message_pk PRIMARY KEY (session_id, msg_seq_num),
+	CONSTRAINT fix_message_session_fk FOREIGN KEY (session_id) REFERENCES public.fix_session (session_id)
+);
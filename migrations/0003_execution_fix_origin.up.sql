@@ -0,0 +1,3 @@
+-- Record the FIX session and ClOrdID of orders received over FIX
+ALTER TABLE public.execution ADD COLUMN fix_session_id varchar(100) NULL;
+ALTER TABLE public.execution ADD COLUMN cl_ord_id varchar(64) NULL;
@@ -0,0 +1,7 @@
+-- Add time in force; existing orders keep living until filled
+ALTER TABLE public.execution ADD COLUMN time_in_force varchar(3) NOT NULL DEFAULT 'GTC';
+ALTER TABLE public.execution ADD COLUMN expire_timestamp timestamptz NULL;
+
+-- Create index for the expiry sweeper
+CREATE INDEX execution_expire_ndx ON public.execution
+USING btree (expire_timestamp) WHERE is_open;
@@ -0,0 +1,14 @@
+-- Create outbox table for messages written in the same transaction as the
+-- execution change they report, and relayed to Kafka afterwards
+CREATE TABLE public.outbox (
+	id bigserial NOT NULL,
+	message_key bytea NULL,
+	payload bytea NOT NULL,
+	created_timestamp timestamptz NOT NULL DEFAULT NOW(),
+	sent_timestamp timestamptz NULL,
+	CONSTRAINT outbox_pk PRIMARY KEY (id)
+);
+
+-- Create index for the relay's pending scan
+CREATE INDEX outbox_pending_ndx ON public.outbox
+USING btree (id) WHERE sent_timestamp IS NULL;
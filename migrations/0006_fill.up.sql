@@ -0,0 +1,16 @@
+-- Create fill table recording every individual fill of an execution
+CREATE TABLE public.fill (
+	id bigserial NOT NULL,
+	execution_id integer NOT NULL,
+	fill_sequence integer NOT NULL,
+	quantity decimal(18,8) NOT NULL,
+	price decimal(18,8) NOT NULL,
+	fill_timestamp timestamptz NOT NULL,
+	venue varchar(20) NOT NULL,
+	CONSTRAINT fill_pk PRIMARY KEY (id),
+	CONSTRAINT fill_execution_fk FOREIGN KEY (execution_id) REFERENCES public.execution (id)
+);
+
+-- Create unique index on the fills of an execution, in sequence
+CREATE UNIQUE INDEX fill_execution_sequence_ndx ON public.fill
+USING btree (execution_id, fill_sequence);
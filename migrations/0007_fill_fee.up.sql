@@ -0,0 +1,2 @@
+-- Record the venue fee charged for each fill
+ALTER TABLE public.fill ADD COLUMN fee decimal(18,8) NOT NULL DEFAULT 0;
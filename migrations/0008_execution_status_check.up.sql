@@ -0,0 +1,5 @@
+-- Restrict execution_status to the order state machine and derive is_open from it
+ALTER TABLE public.execution ADD CONSTRAINT execution_status_chk
+CHECK (execution_status IN ('NEW', 'WORK', 'PART', 'FULL', 'CANC', 'REJ', 'EXPD', 'PCAN', 'PREP'));
+ALTER TABLE public.execution ADD CONSTRAINT execution_is_open_chk
+CHECK (is_open = (execution_status IN ('NEW', 'WORK', 'PART', 'PCAN', 'PREP')));
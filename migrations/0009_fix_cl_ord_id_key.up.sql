@@ -0,0 +1,7 @@
+-- Key FIX orders on their session and ClOrdID instead of execution_service_id,
+-- which only Kafka orders carry
+DROP INDEX public.execution_service_id_ndx;
+CREATE UNIQUE INDEX execution_service_id_ndx ON public.execution
+USING btree (execution_service_id) WHERE fix_session_id IS NULL;
+CREATE UNIQUE INDEX execution_cl_ord_id_ndx ON public.execution
+USING btree (fix_session_id, cl_ord_id) WHERE fix_session_id IS NOT NULL;
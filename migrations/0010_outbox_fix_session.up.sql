@@ -0,0 +1,9 @@
+-- Queue ExecutionReports for FIX sessions in the outbox alongside fills topic
+-- messages, so they are only sent once the change they report has committed
+ALTER TABLE public.outbox ADD COLUMN fix_session_id varchar(100) NULL;
+
+DROP INDEX public.outbox_pending_ndx;
+CREATE INDEX outbox_pending_ndx ON public.outbox
+USING btree (id) WHERE sent_timestamp IS NULL AND fix_session_id IS NULL;
+CREATE INDEX outbox_fix_pending_ndx ON public.outbox
+USING btree (fix_session_id, id) WHERE sent_timestamp IS NULL AND fix_session_id IS NOT NULL;
@@ -0,0 +1,3 @@
+-- Create index for pruning sent messages past their retention
+CREATE INDEX outbox_sent_ndx ON public.outbox
+USING btree (sent_timestamp) WHERE sent_timestamp IS NOT NULL;